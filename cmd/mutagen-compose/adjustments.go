@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"text/template"
 
 	"github.com/spf13/cobra"
 
+	"gopkg.in/yaml.v2"
+
 	"github.com/docker/cli/cli"
 	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/templates"
 
 	commands "github.com/docker/compose/v2/cmd/compose"
 	"github.com/docker/compose/v2/cmd/formatter"
@@ -18,6 +22,7 @@ import (
 
 	"github.com/mutagen-io/mutagen/cmd"
 
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
 	versionpkg "github.com/mutagen-io/mutagen-compose/pkg/version"
 )
 
@@ -47,15 +52,32 @@ func fauxTopLevelCommandForHelpAndUsage() *cobra.Command {
 	// Adjust the version command like we do for the real command hierarchy.
 	adjustVersionCommand(root)
 
+	// Adjust the logs, down, stop, pull, create, and up commands like we do for
+	// the real command hierarchy. The liaison that we use here is unused (see below).
+	adjustLogsCommand(root, &mutagen.Liaison{})
+	adjustTeardownCommands(root, &mutagen.Liaison{})
+	adjustPullCommand(root, &mutagen.Liaison{})
+	adjustCreationCommands(root, &mutagen.Liaison{})
+
 	// Add the legal command like we do for the real command hierarchy.
 	root.AddCommand(legalCommand)
 
+	// Add the mutagen command like we do for the real command hierarchy. The
+	// liaison that we use here is never registered or used since this command
+	// hierarchy is only used for help and usage information.
+	root.AddCommand(mutagenCommand(&mutagen.Liaison{}))
+
 	// HACK: Set this command up as a Docker plugin root command in order to add
 	// the top-level Docker CLI flags and to set usage formatting. Normally
 	// there would be an intermediate command above this that would be the
 	// actual root.
 	cli.SetupPluginRootCommand(root)
 
+	// Register top-level Mutagen Compose flags. These are parsed and consumed
+	// by our top-level parser, but we include them here so that they appear in
+	// help and usage information. The storage that we use here is unused.
+	(&mutagen.Flags{}).Register(root.PersistentFlags())
+
 	// Extract the unified flag set.
 	flags := root.Flags()
 
@@ -95,9 +117,16 @@ func adjustUsageInformation(cmd *cobra.Command) {
 		}
 
 		// Otherwise, this is a help request for a Compose subcommand, so
-		// reparent the subcommand onto the faux top-level command to get a
-		// proper command name and then display its usage.
-		faux.AddCommand(c)
+		// reparent the subcommand's top-level ancestor (which is the
+		// subcommand itself for non-nested subcommands) onto the faux
+		// top-level command to get a proper command name and then display its
+		// usage. Reparenting the ancestor (rather than the subcommand itself)
+		// preserves the full command path for nested subcommands.
+		ancestor := c
+		for ancestor.HasParent() && ancestor.Parent() != cmd {
+			ancestor = ancestor.Parent()
+		}
+		faux.AddCommand(ancestor)
 		return c.Usage()
 	})
 }
@@ -117,16 +146,30 @@ func adjustUnknownCommandErrors(cmd *cobra.Command) {
 	// Extract the original entry point.
 	originalRunE := cmd.RunE
 
+	// Set the suggestion distance threshold. Cobra only sets its default when
+	// computing its own suggestions, which it doesn't do for Compose since the
+	// root command accepts arbitrary arguments.
+	if cmd.SuggestionsMinimumDistance <= 0 {
+		cmd.SuggestionsMinimumDistance = 2
+	}
+
 	// Override the entry point with one that changes the error message for
-	// unknown command errors.
+	// unknown command errors and appends suggestions for similarly named
+	// commands (using the same format as Cobra).
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		err := originalRunE(cmd, args)
 		if err != nil {
 			if statusErr, ok := err.(cli.StatusError); ok {
 				if strings.HasPrefix(statusErr.Status, unknownCommandErrorPrefix) {
+					status := replacementUnknownCommandErrorPrefix + statusErr.Status[len(unknownCommandErrorPrefix):]
+					if len(args) > 0 {
+						if suggestions := cmd.SuggestionsFor(args[0]); len(suggestions) > 0 {
+							status += "\n\nDid you mean this?\n\t" + strings.Join(suggestions, "\n\t")
+						}
+					}
 					err = cli.StatusError{
 						StatusCode: compose.CommandSyntaxFailure.ExitCode,
-						Status:     replacementUnknownCommandErrorPrefix + statusErr.Status[len(unknownCommandErrorPrefix):],
+						Status:     status,
 					}
 				}
 			}
@@ -135,6 +178,35 @@ func adjustUnknownCommandErrors(cmd *cobra.Command) {
 	}
 }
 
+// versionInformation is the full version information reported by the version
+// command. It is designed to be serialized as JSON or YAML.
+type versionInformation struct {
+	versionpkg.Versions `yaml:",inline"`
+	// DaemonVersion is the version of the running Mutagen daemon, if any.
+	DaemonVersion string `json:"daemonVersion,omitempty" yaml:"daemonVersion,omitempty"`
+	// SidecarImage is the default Mutagen sidecar image reference.
+	SidecarImage string `json:"sidecarImage" yaml:"sidecarImage"`
+	// SidecarVersionLabel is the version label applied to sidecar containers,
+	// formatted as key=value.
+	SidecarVersionLabel string `json:"sidecarVersionLabel" yaml:"sidecarVersionLabel"`
+	// Build is the build provenance information.
+	Build *versionpkg.BuildInformation `json:"build" yaml:"build"`
+}
+
+// formatYAML is the YAML output format for commands that support structured
+// output. Compose doesn't define a constant for it since its formatter doesn't
+// support YAML.
+const formatYAML = "yaml"
+
+// printYAML prints a value to standard output as YAML.
+func printYAML(value interface{}) error {
+	encoder := yaml.NewEncoder(os.Stdout)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("unable to encode YAML: %w", err)
+	}
+	return encoder.Close()
+}
+
 // adjustVersionCommand adjust the behavior of the version command to correspond
 // to Mutagen Compose.
 func adjustVersionCommand(cmd *cobra.Command) {
@@ -151,33 +223,198 @@ func adjustVersionCommand(cmd *cobra.Command) {
 	shortFlag := flags.Lookup("short")
 	shortFlag.Usage = "Show only the version numbers."
 
+	// Look up the format flag and replace its description to include the
+	// additional formats that we support.
+	flags.Lookup("format").Usage = "Format the output. Values: [pretty | json | yaml | TEMPLATE]."
+
 	// Override the command entry point.
 	version.RunE = func(cmd *cobra.Command, args []string) error {
 		// Look up the format flag.
 		formatFlag := flags.Lookup("format")
 
 		// Extract flag values.
-		format := formatFlag.Value.String()
+		rawFormat := formatFlag.Value.String()
+		format := strings.ToLower(rawFormat)
 		short := shortFlag.Value.String() == "true"
 
+		// Validate the format. Anything that looks like a Go template is parsed
+		// as one, in the same manner as Docker's version command. We reject
+		// other unknown formats in the same manner as Compose's own formatted
+		// output.
+		var tmpl *template.Template
+		switch format {
+		case formatter.PRETTY, "", formatter.JSON, formatYAML:
+		default:
+			if !strings.Contains(rawFormat, "{{") {
+				return fmt.Errorf("format value %q could not be parsed: %w", rawFormat, api.ErrParsingFailed)
+			}
+			var err error
+			if tmpl, err = templates.Parse(rawFormat); err != nil {
+				return fmt.Errorf("unable to parse format template: %w", err)
+			}
+		}
+
 		// Load version information.
-		versions, err := versionpkg.LoadVersions()
+		versions, build, err := versionpkg.LoadVersionsAndBuildInformation()
 		if err != nil {
 			return fmt.Errorf("unable to load version information: %w", err)
 		}
 
-		// Print accordingly. We don't perform any validation on format because
-		// the built-in version command doesn't either.
+		// Warn about unidentifiable dependency versions, which usually indicate
+		// a non-standard build. We print these to standard error so that they
+		// don't interfere with formatted output.
+		if versions.Compose == versionpkg.Unknown {
+			fmt.Fprintln(os.Stderr, "Warning: unable to identify Compose version (non-standard build?)")
+		}
+		if versions.Docker == versionpkg.Unknown {
+			fmt.Fprintln(os.Stderr, "Warning: unable to identify Docker version (non-standard build?)")
+		}
+
+		// Query the version of the Mutagen daemon, if it's running. We don't
+		// start the daemon just to report its version.
+		daemonVersion, _ := mutagen.RunningDaemonVersion(cmd.Context())
+
+		// Compute sidecar information.
+		labelKey, labelValue := mutagen.SidecarVersionLabel()
+		information := &versionInformation{
+			Versions:            *versions,
+			DaemonVersion:       daemonVersion,
+			SidecarImage:        mutagen.SidecarImage(),
+			SidecarVersionLabel: labelKey + "=" + labelValue,
+			Build:               build,
+		}
+
+		// Print accordingly. The format takes precedence over the short flag so
+		// that scripts requesting structured output always receive it, with the
+		// short form containing only version numbers.
+		if tmpl != nil {
+			if err := tmpl.Execute(os.Stdout, information); err != nil {
+				return fmt.Errorf("unable to execute format template: %w", err)
+			}
+			fmt.Println()
+			return nil
+		} else if format == formatter.JSON {
+			if short {
+				return json.NewEncoder(os.Stdout).Encode(versions)
+			}
+			return json.NewEncoder(os.Stdout).Encode(information)
+		} else if format == formatYAML {
+			if short {
+				return printYAML(versions)
+			}
+			return printYAML(information)
+		}
 		if short {
 			fmt.Printf("%s/%s/%s\n", versions.Mutagen, versions.Compose, versions.Docker)
 			return nil
 		}
-		if format == formatter.JSON {
-			return json.NewEncoder(os.Stdout).Encode(versions)
-		}
 		fmt.Println("Mutagen version", versions.Mutagen)
+		if daemonVersion != "" {
+			fmt.Println("Mutagen daemon version", daemonVersion)
+		} else {
+			fmt.Println("Mutagen daemon version", "(not running)")
+		}
 		fmt.Println("Compose version", versions.Compose)
 		fmt.Println("Docker version", versions.Docker)
+		fmt.Println("Sidecar image", information.SidecarImage)
+		fmt.Println("Sidecar version label", information.SidecarVersionLabel)
 		return nil
 	}
 }
+
+// adjustLogsCommand adjusts the logs command to support including logs from the
+// Mutagen Compose sidecar container when specific services are requested.
+func adjustLogsCommand(cmd *cobra.Command, liaison *mutagen.Liaison) {
+	// Look up the logs command.
+	logs, _, _ := cmd.Find([]string{"logs"})
+
+	// Register an additional flag.
+	var includeSidecar bool
+	logs.Flags().BoolVar(&includeSidecar, "mutagen", false, "Include output from the Mutagen Compose sidecar container.")
+
+	// Override the command entry point to forward the flag value.
+	originalRunE := logs.RunE
+	logs.RunE = func(cmd *cobra.Command, args []string) error {
+		liaison.SetSidecarLogsIncluded(includeSidecar)
+		return originalRunE(cmd, args)
+	}
+}
+
+// adjustPullCommand adjusts the pull command to support skipping the Mutagen
+// Compose sidecar image when it's already present locally.
+func adjustPullCommand(cmd *cobra.Command, liaison *mutagen.Liaison) {
+	// Look up the pull command.
+	pull, _, _ := cmd.Find([]string{"pull"})
+
+	// Register an additional flag.
+	var skipCachedSidecar bool
+	pull.Flags().BoolVar(&skipCachedSidecar, "skip-cached-sidecar", false, "Don't pull the Mutagen Compose sidecar image if it's already present.")
+
+	// Override the command entry point to forward the flag value.
+	originalRunE := pull.RunE
+	pull.RunE = func(cmd *cobra.Command, args []string) error {
+		liaison.SetCachedSidecarPullSkipped(skipCachedSidecar)
+		return originalRunE(cmd, args)
+	}
+}
+
+// adjustCreationCommands adjusts the create and up commands to support
+// overriding the pull policy for the Mutagen Compose sidecar image.
+func adjustCreationCommands(cmd *cobra.Command, liaison *mutagen.Liaison) {
+	for _, name := range []string{"create", "up"} {
+		// Look up the command.
+		command, _, _ := cmd.Find([]string{name})
+
+		// Register an additional flag.
+		var pullPolicy string
+		command.Flags().StringVar(&pullPolicy, "pull-sidecar", "", "Pull policy for the Mutagen Compose sidecar image (\"never\"|\"missing\"|\"always\").")
+
+		// Override the command entry point to forward the flag value.
+		originalRunE := command.RunE
+		command.RunE = func(cmd *cobra.Command, args []string) error {
+			liaison.SetSidecarPullPolicy(pullPolicy)
+			return originalRunE(cmd, args)
+		}
+	}
+}
+
+// adjustStartCommand adjusts the start command to register the project with
+// the liaison, since Compose's start operation only loads the project name. If
+// the project can't be loaded (e.g. because only a project name was specified),
+// then the start command proceeds without it.
+func adjustStartCommand(cmd *cobra.Command, liaison *mutagen.Liaison) {
+	// Look up the start command.
+	start, _, _ := cmd.Find([]string{"start"})
+
+	// Override the command entry point to register the project.
+	originalRunE := start.RunE
+	start.RunE = func(cmd *cobra.Command, args []string) error {
+		if options, err := loadProjectOptions(cmd); err == nil {
+			if project, err := options.toProject(); err == nil {
+				liaison.RegisterProject(project)
+			}
+		}
+		return originalRunE(cmd, args)
+	}
+}
+
+// adjustTeardownCommands adjusts the down and stop commands to support disabling
+// the final flush of Mutagen synchronization sessions.
+func adjustTeardownCommands(cmd *cobra.Command, liaison *mutagen.Liaison) {
+	for _, name := range []string{"down", "stop"} {
+		// Look up the command.
+		command, _, _ := cmd.Find([]string{name})
+
+		// Register an additional flag.
+		var noFlush bool
+		command.Flags().BoolVar(&noFlush, "no-flush", false, "Don't flush Mutagen synchronization sessions before stopping.")
+
+		// Override the command entry point to enable the final flush unless
+		// disabled.
+		originalRunE := command.RunE
+		command.RunE = func(cmd *cobra.Command, args []string) error {
+			liaison.SetFinalFlushEnabled(!noFlush)
+			return originalRunE(cmd, args)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/cli/cli/command"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+	"github.com/docker/compose/v2/pkg/api"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// TestAdjustUsageInformation tests that usage information for subcommands
+// (including nested subcommands) displays the full Mutagen Compose command
+// path.
+func TestAdjustUsageInformation(t *testing.T) {
+	testCases := []struct {
+		arguments []string
+		expected  string
+	}{
+		{[]string{"up"}, "mutagen-compose up"},
+		{[]string{"mutagen"}, "mutagen-compose mutagen"},
+		{[]string{"mutagen", "reload"}, "mutagen-compose mutagen reload"},
+		{[]string{"mutagen", "status"}, "mutagen-compose mutagen status"},
+	}
+	for _, testCase := range testCases {
+		// Create a command hierarchy like the one used by invoke.
+		dockerCli, err := command.NewDockerCli()
+		if err != nil {
+			t.Fatal("unable to create Docker CLI:", err)
+		}
+		root := commands.RootCommand(dockerCli, api.NewServiceProxy())
+		root.AddCommand(mutagenCommand(&mutagen.Liaison{}))
+		adjustUsageInformation(root)
+
+		// Locate the subcommand.
+		subcommand, _, err := root.Find(testCase.arguments)
+		if err != nil {
+			t.Errorf("%v: unable to find command: %v", testCase.arguments, err)
+			continue
+		}
+
+		// Display usage information.
+		output := &bytes.Buffer{}
+		subcommand.SetOut(output)
+		subcommand.SetErr(output)
+		if err := subcommand.Usage(); err != nil {
+			t.Errorf("%v: unable to display usage information: %v", testCase.arguments, err)
+			continue
+		}
+
+		// Verify the command path.
+		if !strings.Contains(output.String(), testCase.expected) {
+			t.Errorf("%v: usage information doesn't contain \"%s\":\n%s",
+				testCase.arguments, testCase.expected, output.String(),
+			)
+		}
+	}
+}
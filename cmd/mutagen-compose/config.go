@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+	"github.com/docker/compose/v2/cmd/formatter"
+	"github.com/docker/compose/v2/pkg/api"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// configCommand creates the mutagen config command.
+func configCommand(liaison *mutagen.Liaison) *cobra.Command {
+	// Create the command.
+	var format string
+	result := &cobra.Command{
+		Use:   "config",
+		Short: "Show the effective Mutagen session configuration for the project",
+		Args:  cmd.DisallowArguments,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, _ []string) error {
+			// Validate the output format.
+			format = strings.ToLower(format)
+			if format != formatter.JSON && format != formatYAML {
+				return fmt.Errorf("format value %q could not be parsed: %w", format, api.ErrParsingFailed)
+			}
+
+			// Load the project.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			project, err := options.toProject()
+			if err != nil {
+				return err
+			}
+
+			// Compute the effective configuration.
+			configuration, err := liaison.EffectiveConfiguration(project)
+			if err != nil {
+				return err
+			}
+
+			// Encode the configuration as JSON.
+			encoded, err := json.MarshalIndent(configuration, "", "    ")
+			if err != nil {
+				return fmt.Errorf("unable to encode configuration: %w", err)
+			}
+
+			// Print the configuration in the requested format. For YAML, we
+			// convert the JSON encoding to preserve its field names and
+			// enumeration encoding.
+			if format == formatter.JSON {
+				_, err = fmt.Fprintln(os.Stdout, string(encoded))
+				return err
+			}
+			var generic interface{}
+			if err := json.Unmarshal(encoded, &generic); err != nil {
+				return fmt.Errorf("unable to convert configuration: %w", err)
+			}
+			return printYAML(generic)
+		}),
+		SilenceUsage: true,
+	}
+
+	// Register flags.
+	flags := result.Flags()
+	flags.StringVar(&format, "format", formatYAML, "Format the output. Values: [yaml | json].")
+
+	// Done.
+	return result
+}
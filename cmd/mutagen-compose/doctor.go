@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// doctorCommand creates the mutagen doctor command.
+func doctorCommand(liaison *mutagen.Liaison) *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose Mutagen configuration and connectivity for the project",
+		Args:  cmd.DisallowArguments,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, _ []string) error {
+			// Load the project.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			project, err := options.toProject()
+			if err != nil {
+				return err
+			}
+
+			// Perform diagnostics.
+			return liaison.Doctor(ctx, project)
+		}),
+		SilenceUsage: true,
+	}
+}
@@ -19,6 +19,9 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/spf13/cobra"
 
 	dockercli "github.com/docker/cli/cli"
@@ -35,42 +38,75 @@ import (
 	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
 )
 
-// invokeCompose invokes Compose via the plugin infrastructure. It requires that
-// os.Args be set in a manner that emulates execution as a plugin.
-func invokeCompose(liaison *mutagen.Liaison) {
-	plugin.Run(func(dockerCli command.Cli) *cobra.Command {
-		liaison.RegisterDockerCLI(dockerCli)
-		liaisedCli := liaison.DockerCLI()
-		lazyInit := api.NewServiceProxy()
-		cmd := commands.RootCommand(liaisedCli, lazyInit)
-		originalPreRun := cmd.PersistentPreRunE
-		cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
-			if err := plugin.PersistentPreRunE(cmd, args); err != nil {
-				return err
+// invokeCompose invokes Compose via the plugin infrastructure and returns the
+// resulting exit code. It requires that os.Args be set in a manner that
+// emulates execution as a plugin. It mirrors plugin.Run, except that it doesn't
+// exit the process, which allows the caller to perform cleanup before exiting.
+func invokeCompose(liaison *mutagen.Liaison) int {
+	// Create the Docker CLI.
+	dockerCli, err := command.NewDockerCli()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Create the root command.
+	liaison.RegisterDockerCLI(dockerCli)
+	liaisedCli := liaison.DockerCLI()
+	lazyInit := api.NewServiceProxy()
+	cmd := commands.RootCommand(liaisedCli, lazyInit)
+	originalPreRun := cmd.PersistentPreRunE
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := plugin.PersistentPreRunE(cmd, args); err != nil {
+			return err
+		}
+		liaison.RegisterDockerFlags(cmd.Root().Flags())
+		liaison.RegisterComposeService(compose.NewComposeService(liaisedCli))
+		lazyInit.WithService(liaison.ComposeService())
+		if originalPreRun != nil {
+			return originalPreRun(cmd, args)
+		}
+		return nil
+	}
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		return dockercli.StatusError{
+			StatusCode: compose.CommandSyntaxFailure.ExitCode,
+			Status:     err.Error(),
+		}
+	})
+	adjustUsageInformation(cmd)
+	adjustUnknownCommandErrors(cmd)
+	adjustVersionCommand(cmd)
+	adjustLogsCommand(cmd, liaison)
+	adjustTeardownCommands(cmd, liaison)
+	adjustPullCommand(cmd, liaison)
+	adjustCreationCommands(cmd, liaison)
+	adjustStartCommand(cmd, liaison)
+	cmd.AddCommand(legalCommand)
+	cmd.AddCommand(mutagenCommand(liaison))
+
+	// Run the command.
+	metadata := manager.Metadata{
+		SchemaVersion: "0.1.0",
+		Vendor:        "Mutagen IO, Inc.",
+		Version:       mutageninfo.Version,
+	}
+	if err := plugin.RunPlugin(dockerCli, cmd, metadata); err != nil {
+		if statusErr, ok := err.(dockercli.StatusError); ok {
+			if statusErr.Status != "" {
+				fmt.Fprintln(dockerCli.Err(), statusErr.Status)
 			}
-			liaison.RegisterDockerFlags(cmd.Root().Flags())
-			liaison.RegisterComposeService(compose.NewComposeService(liaisedCli))
-			lazyInit.WithService(liaison.ComposeService())
-			if originalPreRun != nil {
-				return originalPreRun(cmd, args)
+			// Status errors should only be used for errors, so we never
+			// report success for them.
+			if statusErr.StatusCode == 0 {
+				return 1
 			}
-			return nil
+			return statusErr.StatusCode
 		}
-		cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
-			return dockercli.StatusError{
-				StatusCode: compose.CommandSyntaxFailure.ExitCode,
-				Status:     err.Error(),
-			}
-		})
-		adjustUsageInformation(cmd)
-		adjustUnknownCommandErrors(cmd)
-		adjustVersionCommand(cmd)
-		cmd.AddCommand(legalCommand)
-		return cmd
-	},
-		manager.Metadata{
-			SchemaVersion: "0.1.0",
-			Vendor:        "Mutagen IO, Inc.",
-			Version:       mutageninfo.Version,
-		})
+		fmt.Fprintln(dockerCli.Err(), err)
+		return 1
+	}
+
+	// Success.
+	return 0
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// logsCommand creates the mutagen logs command.
+func logsCommand(liaison *mutagen.Liaison) *cobra.Command {
+	// Create the command.
+	var follow bool
+	var tail, group string
+	result := &cobra.Command{
+		Use:   "logs",
+		Short: "View output from the Mutagen Compose sidecar container",
+		Args:  cmd.DisallowArguments,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, _ []string) error {
+			// Determine the project name.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			projectName, err := options.toProjectName()
+			if err != nil {
+				return err
+			}
+
+			// Display the logs.
+			return liaison.SidecarLogs(ctx, projectName, group, follow, tail)
+		}),
+		SilenceUsage: true,
+	}
+
+	// Register flags.
+	flags := result.Flags()
+	flags.BoolVarP(&follow, "follow", "f", false, "Follow log output")
+	flags.StringVar(&tail, "tail", "all", "Number of lines to show from the end of the logs")
+	flags.StringVar(&group, "group", "", "Show output from the sidecar container for the specified sidecar group")
+
+	// Done.
+	return result
+}
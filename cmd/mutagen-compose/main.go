@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
 	"github.com/mutagen-io/mutagen/cmd/external"
@@ -22,12 +23,14 @@ func main() {
 	// Create storage for top-level Docker and Compose flags.
 	dockerFlags := &docker.Flags{}
 	composeFlags := &compose.Flags{}
+	mutagenFlags := &mutagen.Flags{}
 
 	// Create top-level flag set for parsing.
 	var help bool
 	flags := pflag.NewFlagSet("mutagen-compose", pflag.ContinueOnError)
 	dockerFlags.Register(flags)
 	composeFlags.Register(flags)
+	mutagenFlags.Register(flags)
 	flags.BoolVarP(&help, "help", "h", false, "")
 
 	// Mark the shorthand help flag as deprecated to match the behavior of the
@@ -74,12 +77,20 @@ func main() {
 	}
 
 	// Compute the emulated arguments that we'll use for the plugin-based
-	// invocation of Compose.
+	// invocation of Compose. Top-level Mutagen Compose flags are consumed here
+	// and aren't forwarded. Cobra's hidden shell completion request commands
+	// are only handled by the plugin root command, so we move them ahead of
+	// the Compose command name.
 	emulatedArgs := []string{"docker"}
 	if help {
 		emulatedArgs = append(emulatedArgs, "--help")
 	}
 	emulatedArgs = append(emulatedArgs, dockerFlags.Reconstituted(flags)...)
+	if len(commandAndArguments) > 0 &&
+		(commandAndArguments[0] == cobra.ShellCompRequestCmd || commandAndArguments[0] == cobra.ShellCompNoDescRequestCmd) {
+		emulatedArgs = append(emulatedArgs, commandAndArguments[0])
+		commandAndArguments = commandAndArguments[1:]
+	}
 	emulatedArgs = append(emulatedArgs, "compose")
 	emulatedArgs = append(emulatedArgs, composeFlags.Reconstituted(flags)...)
 	emulatedArgs = append(emulatedArgs, commandAndArguments...)
@@ -87,7 +98,26 @@ func main() {
 
 	// Create the Mutagen liaison.
 	liaison := &mutagen.Liaison{}
+	if err := liaison.LoadUserConfiguration(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	liaison.ApplyFlags(mutagenFlags)
+	liaison.SetDaemonConnectionReuse(true)
 
 	// Invoke Compose.
-	invokeCompose(liaison)
+	exitCode := invokeCompose(liaison)
+
+	// Shut down the liaison. This happens regardless of the outcome of the
+	// invocation, so we only let shutdown failures affect the exit code if the
+	// invocation succeeded.
+	if err := liaison.Shutdown(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
+
+	// Exit with the appropriate code.
+	os.Exit(exitCode)
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// mutagenCommand creates the mutagen command, which hosts subcommands that
+// operate on the Mutagen-specific components of a project.
+func mutagenCommand(liaison *mutagen.Liaison) *cobra.Command {
+	// Create the command.
+	result := &cobra.Command{
+		Use:   "mutagen",
+		Short: "Manage Mutagen sessions for the project",
+		RunE: func(command *cobra.Command, _ []string) error {
+			return command.Help()
+		},
+		SilenceUsage: true,
+	}
+
+	// Register subcommands.
+	result.AddCommand(
+		configCommand(liaison),
+		doctorCommand(liaison),
+		logsCommand(liaison),
+		pauseCommand(liaison),
+		pruneCommand(liaison),
+		reconcileCommand(liaison),
+		reloadCommand(liaison),
+		resumeCommand(liaison),
+		statusCommand(liaison),
+		terminateCommand(liaison),
+	)
+
+	// Make subcommands fail if Mutagen support is disabled.
+	for _, subcommand := range result.Commands() {
+		run := subcommand.RunE
+		subcommand.RunE = func(command *cobra.Command, arguments []string) error {
+			if liaison.Disabled() {
+				return errors.New("Mutagen support is disabled")
+			}
+			return run(command, arguments)
+		}
+	}
+
+	// Done.
+	return result
+}
+
+// completeSessionNames provides shell completion for commands that accept
+// Mutagen session names as arguments. It suggests the names of sessions defined
+// in the project's Mutagen configuration.
+func completeSessionNames(command *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	// Load the project. We can't report errors during completion, so we just
+	// disable completion if the project can't be loaded.
+	options, err := loadProjectOptions(command)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	project, err := options.toProject()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// Extract session names.
+	names, err := mutagen.SessionNames(project)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// Done.
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// printAffectedSessions prints the sessions affected by an operation.
+func printAffectedSessions(action string, affected *mutagen.AffectedSessions) {
+	if len(affected.Forwarding) == 0 && len(affected.Synchronization) == 0 {
+		fmt.Println("No Mutagen sessions found")
+		return
+	}
+	for _, name := range affected.Forwarding {
+		fmt.Printf("%s forwarding session %s\n", action, name)
+	}
+	for _, name := range affected.Synchronization {
+		fmt.Printf("%s synchronization session %s\n", action, name)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// pauseCommand creates the mutagen pause command.
+func pauseCommand(liaison *mutagen.Liaison) *cobra.Command {
+	return &cobra.Command{
+		Use:               "pause [SESSION...]",
+		Short:             "Pause Mutagen sessions for the project without affecting containers",
+		ValidArgsFunction: completeSessionNames,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, names []string) error {
+			// Determine the project name.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			projectName, err := options.toProjectName()
+			if err != nil {
+				return err
+			}
+
+			// Perform the operation.
+			affected, err := liaison.Pause(ctx, projectName, names)
+			if err != nil {
+				return err
+			}
+
+			// Report the affected sessions.
+			printAffectedSessions("Paused", affected)
+			return nil
+		}),
+		SilenceUsage: true,
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/compose-spec/compose-go/cli"
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose/v2/pkg/api"
+)
+
+// projectOptions stores the values of the top-level Compose project flags.
+// Compose doesn't export its own project option handling, so we extract the
+// values directly from the flags registered on the Compose root command.
+type projectOptions struct {
+	// profiles are the values of the --profile flag(s).
+	profiles []string
+	// projectName is the value of the -p/--project-name flag.
+	projectName string
+	// files are the values of the -f/--file flag(s).
+	files []string
+	// envFile is the value of the --env-file flag.
+	envFile string
+	// projectDirectory is the value of the --project-directory flag.
+	projectDirectory string
+}
+
+// loadProjectOptions extracts project options from the Compose root command
+// in the specified command's hierarchy. Note that the Compose root command
+// isn't necessarily the root of the hierarchy, since it's hosted beneath a
+// Docker CLI plugin command when running.
+func loadProjectOptions(command *cobra.Command) (*projectOptions, error) {
+	// Locate the Compose root command flags.
+	var flags *pflag.FlagSet
+	for c := command; c != nil; c = c.Parent() {
+		if c.Flags().Lookup("project-directory") != nil {
+			flags = c.Flags()
+			break
+		}
+	}
+	if flags == nil {
+		return nil, errors.New("unable to locate project flags")
+	}
+
+	// Extract flag values.
+	result := &projectOptions{}
+	var err error
+	if result.profiles, err = flags.GetStringArray("profile"); err != nil {
+		return nil, fmt.Errorf("unable to extract profiles: %w", err)
+	} else if result.projectName, err = flags.GetString("project-name"); err != nil {
+		return nil, fmt.Errorf("unable to extract project name: %w", err)
+	} else if result.files, err = flags.GetStringArray("file"); err != nil {
+		return nil, fmt.Errorf("unable to extract configuration files: %w", err)
+	} else if result.envFile, err = flags.GetString("env-file"); err != nil {
+		return nil, fmt.Errorf("unable to extract environment file: %w", err)
+	} else if result.projectDirectory, err = flags.GetString("project-directory"); err != nil {
+		return nil, fmt.Errorf("unable to extract project directory: %w", err)
+	}
+
+	// Handle the deprecated working directory flag in the same manner as
+	// Compose.
+	if workdir, err := flags.GetString("workdir"); err == nil && workdir != "" && result.projectDirectory == "" {
+		result.projectDirectory = workdir
+	}
+
+	// Success.
+	return result, nil
+}
+
+// toProject loads the project using the same strategy as Compose's
+// projectOptions.toProject method.
+func (o *projectOptions) toProject() (*types.Project, error) {
+	// Create project loading options.
+	options, err := cli.NewProjectOptions(o.files,
+		cli.WithResolvedPaths(true),
+		cli.WithWorkingDirectory(o.projectDirectory),
+		cli.WithEnvFile(o.envFile),
+		cli.WithDotEnv,
+		cli.WithOsEnv,
+		cli.WithConfigFileEnv,
+		cli.WithDefaultConfigPath,
+		cli.WithName(o.projectName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create project options: %w", err)
+	}
+
+	// Load the project.
+	project, err := cli.ProjectFromOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set service labels in the same manner as Compose.
+	envFile := o.envFile
+	if envFile != "" && !filepath.IsAbs(envFile) {
+		envFile = filepath.Join(project.WorkingDir, o.envFile)
+	}
+	for i, service := range project.Services {
+		service.CustomLabels = map[string]string{
+			api.ProjectLabel:     project.Name,
+			api.ServiceLabel:     service.Name,
+			api.VersionLabel:     api.ComposeVersion,
+			api.WorkingDirLabel:  project.WorkingDir,
+			api.ConfigFilesLabel: strings.Join(project.ComposeFiles, ","),
+			api.OneoffLabel:      "False",
+		}
+		if envFile != "" {
+			service.CustomLabels[api.EnvironmentFileLabel] = envFile
+		}
+		project.Services[i] = service
+	}
+
+	// Apply profiles.
+	profiles := o.profiles
+	if p, ok := options.Environment["COMPOSE_PROFILES"]; ok {
+		profiles = append(profiles, strings.Split(p, ",")...)
+	}
+	project.ApplyProfiles(profiles)
+
+	// Remove unused resources in the same manner as Compose.
+	project.WithoutUnnecessaryResources()
+
+	// Done.
+	return project, nil
+}
+
+// toProjectName computes the project name using the same strategy as Compose's
+// projectOptions.toProjectName method.
+func (o *projectOptions) toProjectName() (string, error) {
+	if o.projectName != "" {
+		return o.projectName, nil
+	} else if name := os.Getenv("COMPOSE_PROJECT_NAME"); name != "" {
+		return name, nil
+	}
+	project, err := o.toProject()
+	if err != nil {
+		return "", err
+	}
+	return project.Name, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// pruneCommand creates the mutagen prune command.
+func pruneCommand(liaison *mutagen.Liaison) *cobra.Command {
+	// Create the command.
+	var dryRun bool
+	result := &cobra.Command{
+		Use:   "prune",
+		Short: "Terminate Mutagen sessions whose projects no longer exist",
+		Args:  cmd.DisallowArguments,
+		RunE: commands.AdaptCmd(func(ctx context.Context, _ *cobra.Command, _ []string) error {
+			// Perform pruning.
+			affected, err := liaison.Prune(ctx, dryRun)
+			if err != nil {
+				return err
+			}
+
+			// Report the result.
+			if dryRun {
+				printAffectedSessions("Would prune", affected)
+			} else {
+				printAffectedSessions("Pruned", affected)
+			}
+			return nil
+		}),
+		SilenceUsage: true,
+	}
+
+	// Register flags.
+	flags := result.Flags()
+	flags.BoolVar(&dryRun, "dry-run", false, "Only show the sessions that would be pruned")
+
+	// Done.
+	return result
+}
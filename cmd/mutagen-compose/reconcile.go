@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// reconcileCommand creates the mutagen reconcile command.
+func reconcileCommand(liaison *mutagen.Liaison) *cobra.Command {
+	// Create the command.
+	var force, verbose bool
+	result := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Reconcile Mutagen sessions for a running project",
+		Args:  cmd.DisallowArguments,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, _ []string) error {
+			// Load the project.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			project, err := options.toProject()
+			if err != nil {
+				return err
+			}
+
+			// Perform reconciliation.
+			result, err := liaison.Reconcile(ctx, project, force)
+			if err != nil {
+				return err
+			}
+
+			// Report the result.
+			printReconciliationResult(result, verbose)
+			return nil
+		}),
+		SilenceUsage: true,
+	}
+
+	// Register flags.
+	flags := result.Flags()
+	flags.BoolVar(&force, "force", false, "Recreate all sessions, even if they are up-to-date")
+	flags.BoolVarP(&verbose, "verbose", "v", false, "Show the Mutagen identifiers of affected sessions")
+
+	// Done.
+	return result
+}
+
+// printReconciliationResult prints a summary of a reconciliation result. If
+// verbose is true, then the session identifiers involved in each action are
+// also printed so that they can be used with the Mutagen CLI.
+func printReconciliationResult(result *mutagen.ReconciliationResult, verbose bool) {
+	fmt.Printf("Created %d forwarding and %d synchronization session(s)\n",
+		len(result.CreatedForwardingSessions), len(result.CreatedSynchronizationSessions),
+	)
+	fmt.Printf("Pruned %d forwarding and %d synchronization session(s)\n",
+		len(result.PrunedForwardingSessions), len(result.PrunedSynchronizationSessions),
+	)
+	if verbose {
+		printCreatedSessions("forwarding", result.CreatedForwardingSessions)
+		printCreatedSessions("synchronization", result.CreatedSynchronizationSessions)
+		printSessionIdentifiers("Resumed", "forwarding", result.ResumedForwardingSessions)
+		printSessionIdentifiers("Resumed", "synchronization", result.ResumedSynchronizationSessions)
+		printSessionIdentifiers("Pruned", "forwarding", result.PrunedForwardingSessions)
+		printSessionIdentifiers("Pruned", "synchronization", result.PrunedSynchronizationSessions)
+		for _, name := range result.DeferredForwardingSessions {
+			fmt.Printf("Deferred forwarding session %s until its destination service is running\n", name)
+		}
+	}
+}
+
+// printCreatedSessions prints the names and identifiers of created sessions in
+// name order.
+func printCreatedSessions(kind string, sessions map[string]string) {
+	names := make([]string, 0, len(sessions))
+	for name := range sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("Created %s session %s: %s\n", kind, name, sessions[name])
+	}
+}
+
+// printSessionIdentifiers prints the identifiers of sessions affected by a
+// reconciliation action.
+func printSessionIdentifiers(action, kind string, identifiers []string) {
+	for _, identifier := range identifiers {
+		fmt.Printf("%s %s session: %s\n", action, kind, identifier)
+	}
+}
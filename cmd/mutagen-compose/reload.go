@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/compose-spec/compose-go/types"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+const (
+	// reloadWatchPollInterval is the interval at which Compose files are
+	// checked for modifications in watch mode.
+	reloadWatchPollInterval = time.Second
+	// reloadWatchDebounceInterval is the period for which Compose files must
+	// remain unmodified before changes are applied in watch mode. It avoids
+	// reloading partially saved files.
+	reloadWatchDebounceInterval = 500 * time.Millisecond
+)
+
+// reloadCommand creates the mutagen reload command.
+func reloadCommand(liaison *mutagen.Liaison) *cobra.Command {
+	// Create the command.
+	var verbose, watch bool
+	result := &cobra.Command{
+		Use:   "reload",
+		Short: "Apply Mutagen configuration changes without recreating the sidecar",
+		Args:  cmd.DisallowArguments,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, _ []string) error {
+			// Load the project.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			project, err := options.toProject()
+			if err != nil {
+				return err
+			}
+
+			// Perform the reload.
+			result, err := liaison.Reload(ctx, project)
+			if err != nil {
+				return err
+			}
+
+			// Report the result.
+			printReconciliationResult(result, verbose)
+
+			// If requested, watch for changes.
+			if watch {
+				return watchAndReload(ctx, liaison, options, project, verbose)
+			}
+			return nil
+		}),
+		SilenceUsage: true,
+	}
+
+	// Register flags.
+	flags := result.Flags()
+	flags.BoolVarP(&verbose, "verbose", "v", false, "Show the Mutagen identifiers of affected sessions")
+	flags.BoolVarP(&watch, "watch", "w", false, "Watch Compose files and reload when the Mutagen configuration changes")
+
+	// Done.
+	return result
+}
+
+// composeFileModificationTimes returns the modification times of a project's
+// Compose files. Files that can't be accessed (e.g. because they're being
+// replaced by an editor) are recorded with a zero time.
+func composeFileModificationTimes(project *types.Project) map[string]time.Time {
+	result := make(map[string]time.Time, len(project.ComposeFiles))
+	for _, path := range project.ComposeFiles {
+		if info, err := os.Stat(path); err == nil {
+			result[path] = info.ModTime()
+		} else {
+			result[path] = time.Time{}
+		}
+	}
+	return result
+}
+
+// modificationTimesEqual returns whether or not two sets of modification times
+// are equal.
+func modificationTimesEqual(first, second map[string]time.Time) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for path, modified := range first {
+		if other, ok := second[path]; !ok || !other.Equal(modified) {
+			return false
+		}
+	}
+	return true
+}
+
+// watchAndReload polls the project's Compose files for modifications and
+// reloads Mutagen sessions whenever the Mutagen configuration changes. Changes
+// that don't affect the Mutagen configuration (as determined by its digest) are
+// ignored. Errors encountered while loading the project or reloading sessions
+// are reported but don't terminate watching, since they're often due to edits
+// that are still in progress. It runs until the context is cancelled.
+func watchAndReload(ctx context.Context, liaison *mutagen.Liaison, options *projectOptions, project *types.Project, verbose bool) error {
+	// Compute the initial configuration digest and modification times.
+	digest, err := mutagen.ConfigurationDigest(project)
+	if err != nil {
+		return fmt.Errorf("unable to compute Mutagen configuration digest: %w", err)
+	}
+	modificationTimes := composeFileModificationTimes(project)
+
+	// Poll for changes.
+	fmt.Println("Watching Compose files for Mutagen configuration changes")
+	ticker := time.NewTicker(reloadWatchPollInterval)
+	defer ticker.Stop()
+	for {
+		// Wait for the next polling interval.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// Check for modifications and wait for the files to settle.
+		current := composeFileModificationTimes(project)
+		if modificationTimesEqual(current, modificationTimes) {
+			continue
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(reloadWatchDebounceInterval):
+			}
+			settled := composeFileModificationTimes(project)
+			if modificationTimesEqual(settled, current) {
+				break
+			}
+			current = settled
+		}
+		modificationTimes = current
+
+		// Reload the project and determine whether or not the Mutagen
+		// configuration has changed.
+		updated, err := options.toProject()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Unable to load project:", err)
+			continue
+		}
+		updatedDigest, err := mutagen.ConfigurationDigest(updated)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid Mutagen configuration:", err)
+			continue
+		} else if updatedDigest == digest {
+			continue
+		}
+
+		// Reload sessions.
+		fmt.Println("Mutagen configuration changed, reloading sessions")
+		result, err := liaison.Reload(ctx, updated)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintln(os.Stderr, "Unable to reload sessions:", err)
+			continue
+		}
+		printReconciliationResult(result, verbose)
+
+		// Record the applied configuration. We also switch to the updated
+		// project so that any newly included Compose files are watched.
+		digest = updatedDigest
+		project = updated
+		modificationTimes = composeFileModificationTimes(project)
+	}
+}
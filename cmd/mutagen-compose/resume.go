@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// resumeCommand creates the mutagen resume command.
+func resumeCommand(liaison *mutagen.Liaison) *cobra.Command {
+	return &cobra.Command{
+		Use:               "resume [SESSION...]",
+		Short:             "Resume Mutagen sessions for the project without affecting containers",
+		ValidArgsFunction: completeSessionNames,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, names []string) error {
+			// Determine the project name.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			projectName, err := options.toProjectName()
+			if err != nil {
+				return err
+			}
+
+			// Perform the operation.
+			affected, err := liaison.Resume(ctx, projectName, names)
+			if err != nil {
+				return err
+			}
+
+			// Report the affected sessions.
+			printAffectedSessions("Resumed", affected)
+			return nil
+		}),
+		SilenceUsage: true,
+	}
+}
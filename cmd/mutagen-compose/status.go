@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// statusCommand creates the mutagen status command.
+func statusCommand(liaison *mutagen.Liaison) *cobra.Command {
+	// Create the command.
+	var conflictsOnly bool
+	result := &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of Mutagen sessions for the project",
+		Args:  cmd.DisallowArguments,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, _ []string) error {
+			// Determine the project name.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			projectName, err := options.toProjectName()
+			if err != nil {
+				return err
+			}
+
+			// Display the session status.
+			return liaison.Status(ctx, projectName, conflictsOnly)
+		}),
+		SilenceUsage: true,
+	}
+
+	// Register flags.
+	flags := result.Flags()
+	flags.BoolVar(&conflictsOnly, "conflicts-only", false, "Only show synchronization sessions with unresolved conflicts")
+
+	// Done.
+	return result
+}
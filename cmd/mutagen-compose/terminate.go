@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	commands "github.com/docker/compose/v2/cmd/compose"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen-compose/pkg/mutagen"
+)
+
+// terminateCommand creates the mutagen terminate command.
+func terminateCommand(liaison *mutagen.Liaison) *cobra.Command {
+	return &cobra.Command{
+		Use:   "terminate",
+		Short: "Terminate Mutagen sessions for the project without affecting containers",
+		Args:  cmd.DisallowArguments,
+		RunE: commands.AdaptCmd(func(ctx context.Context, command *cobra.Command, _ []string) error {
+			// Determine the project name.
+			options, err := loadProjectOptions(command)
+			if err != nil {
+				return err
+			}
+			projectName, err := options.toProjectName()
+			if err != nil {
+				return err
+			}
+
+			// Perform termination.
+			count, err := liaison.Terminate(ctx, projectName)
+			if err != nil {
+				return err
+			}
+
+			// Report the result.
+			if count == 1 {
+				fmt.Println("Terminated 1 Mutagen session")
+			} else {
+				fmt.Printf("Terminated %d Mutagen sessions\n", count)
+			}
+			return nil
+		}),
+		SilenceUsage: true,
+	}
+}
@@ -4,22 +4,68 @@ import (
 	"context"
 	"errors"
 	"fmt"
-
-	moby "github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/filters"
+	"sync"
 
 	"github.com/compose-spec/compose-go/types"
 
+	"github.com/docker/cli/cli"
+
 	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/progress"
+
+	"github.com/mutagen-io/mutagen/pkg/mutagen"
 )
 
-// appendServiceByCopy appends a service definition to a slice of service
+// appendServicesByCopy appends service definitions to a slice of service
 // definitions without any risk of overwriting additional capacity in the slice
 // that might be in use elsewhere.
-func appendServiceByCopy(services types.Services, service types.ServiceConfig) types.Services {
-	result := make(types.Services, 0, len(services)+1)
+func appendServicesByCopy(services types.Services, additional ...types.ServiceConfig) types.Services {
+	result := make(types.Services, 0, len(services)+len(additional))
 	result = append(result, services...)
-	result = append(result, service)
+	result = append(result, additional...)
+	return result
+}
+
+// projectWithServices returns a shallow copy of a project with its service
+// lists replaced. It's used to present modified service lists to the underlying
+// Compose implementation without mutating the caller's project, which may be
+// shared or reused.
+func projectWithServices(project *types.Project, services, disabledServices types.Services) *types.Project {
+	result := *project
+	result.Services = services
+	result.DisabledServices = disabledServices
+	return &result
+}
+
+// servicesMarkedForBuild returns the names of services that have been marked
+// for (re)building by an up or create operation's --build flag, which Compose
+// encodes by setting the pull policy of services with build definitions to
+// "build". The Mutagen Compose sidecar services are never included since they
+// specify images without build definitions.
+func servicesMarkedForBuild(services types.Services) []string {
+	var result []string
+	for _, service := range services {
+		if service.Build != nil && service.PullPolicy == types.PullPolicyBuild {
+			result = append(result, service.Name)
+		}
+	}
+	return result
+}
+
+// withoutBuildPullPolicy returns a copy of a service list with the build pull
+// policy cleared from the specified services. It's used to avoid rebuilding
+// images that have already been built.
+func withoutBuildPullPolicy(services types.Services, built []string) types.Services {
+	result := make(types.Services, 0, len(services))
+	for _, service := range services {
+		for _, name := range built {
+			if service.Name == name {
+				service.PullPolicy = types.PullPolicyMissing
+				break
+			}
+		}
+		result = append(result, service)
+	}
 	return result
 }
 
@@ -50,20 +96,29 @@ func (s *composeService) Pull(ctx context.Context, project *types.Project, optio
 		return fmt.Errorf("unable to process project: %w", err)
 	}
 
-	// Cache the nominal service list.
-	services := project.Services
+	// Determine which sidecar services need to be pulled.
+	sidecars, err := s.liaison.sidecarServicesToPull(ctx)
+	if err != nil {
+		return err
+	}
 
-	// Inject the Mutagen service into the project.
-	project.Services = appendServiceByCopy(project.Services, s.liaison.mutagenService)
+	// Inject the Mutagen services into the project. The sidecar image is
+	// pulled alongside other service images (and thus reported in the same
+	// progress output), with registry credentials resolved from the Docker CLI
+	// configuration (which honors DOCKER_CONFIG, the --config flag, and any
+	// configured credential helpers). Our Docker CLI wrapper passes that
+	// configuration through unmodified, so sidecar images on authenticated
+	// registries (such as a configured image mirror) are pulled using the
+	// user's credentials. The sidecar services are placed at the same position
+	// that's used by other operations.
+	services := appendServicesByCopy(project.Services, sidecars...)
+	if s.liaison.sidecarFirst {
+		services = appendServicesByCopy(sidecars, project.Services...)
+	}
+	pullProject := projectWithServices(project, services, project.DisabledServices)
 
 	// Invoke the underlying implementation.
-	result := s.service.Pull(ctx, project, options)
-
-	// Restore the service list.
-	project.Services = services
-
-	// Done.
-	return result
+	return s.service.Pull(ctx, pullProject, options)
 }
 
 // Create implements github.com/docker/compose/v2/pkg/api.Service.Create.
@@ -73,53 +128,71 @@ func (s *composeService) Create(ctx context.Context, project *types.Project, opt
 		return fmt.Errorf("unable to process project: %w", err)
 	}
 
-	// Cache the nominal service lists.
-	services := project.Services
-	disabledServices := project.DisabledServices
+	// Verify that any external volumes required by the Mutagen Compose sidecar
+	// service exist.
+	if err := s.liaison.ensureExternalVolumesExist(ctx, project); err != nil {
+		return err
+	}
 
 	// Create the Mutagen Compose sidecar service first. We do this for
 	// consistency with Up and for the flag-related reasons outlined there (the
 	// hidden start progress updates aren't an issue for Create).
-	project.Services = types.Services{s.liaison.mutagenService}
-	project.DisabledServices = nil
+	mutagenProject := projectWithServices(project, s.liaison.sidecarServices(), nil)
 	mutagenCreateOptions := api.CreateOptions{
-		Services:      []string{sidecarServiceName},
+		Services:      s.liaison.sidecarServiceNamesForProject(),
 		IgnoreOrphans: true,
 	}
-	if err := s.service.Create(ctx, project, mutagenCreateOptions); err != nil {
-		project.Services = services
-		project.DisabledServices = disabledServices
+	if err := s.service.Create(ctx, mutagenProject, mutagenCreateOptions); err != nil {
 		return fmt.Errorf("unable to create Mutagen Compose sidecar service: %w", err)
 	}
 
-	// Restore the service lists but keep the Mutagen service defined so that it
-	// doesn't appear as an orphan service.
-	project.Services = services
-	project.DisabledServices = appendServiceByCopy(disabledServices, s.liaison.mutagenService)
+	// Keep the Mutagen service defined so that it doesn't appear as an orphan
+	// service.
+	servicesProject := projectWithServices(project,
+		project.Services,
+		s.liaison.withSidecarServices(project.DisabledServices),
+	)
 
 	// Invoke the underlying implementation.
-	result := s.service.Create(ctx, project, options)
-
-	// Restore the service lists.
-	project.DisabledServices = disabledServices
-
-	// Done.
-	return result
+	return s.service.Create(ctx, servicesProject, options)
 }
 
 // Start implements github.com/docker/compose/v2/pkg/api.Service.Start.
 func (s *composeService) Start(ctx context.Context, projectName string, options api.StartOptions) error {
+	// Process Mutagen extensions for the registered project, if any. Compose's
+	// start API only provides a project name, so we rely on the start command
+	// to register the project in order to obtain session specifications.
+	if project := s.liaison.registeredProject; project != nil {
+		if err := s.liaison.processProject(project); err != nil {
+			return fmt.Errorf("unable to process project: %w", err)
+		}
+	}
+
 	// Start the Mutagen Compose sidecar service first. We do this for
 	// consistency with Up and for the flag-related reasons outlined there (the
 	// hidden start progress updates aren't an issue for Start).
+	sidecarServiceNames, err := s.liaison.sidecarServiceNames(ctx, projectName)
+	if err != nil {
+		return fmt.Errorf("unable to determine Mutagen Compose sidecar service names: %w", err)
+	}
 	mutagenStartOptions := api.StartOptions{
-		AttachTo: []string{sidecarServiceName},
+		AttachTo: sidecarServiceNames,
 	}
 	if err := s.service.Start(ctx, projectName, mutagenStartOptions); err != nil {
 		return fmt.Errorf("unable to start Mutagen Compose sidecar service: %w", err)
 	}
 
-	// Invoke the underlying implementation.
+	// Start sessions for any sidecar containers that Compose didn't start
+	// (i.e. those that were already running). This ensures that start always
+	// yields active sessions, e.g. after a create operation or if sessions
+	// were paused or terminated out-of-band.
+	if err := s.liaison.ensureSessionsStarted(ctx, projectName); err != nil {
+		return err
+	}
+
+	// Invoke the underlying implementation and wait for the creation of any
+	// health-gated forwarding sessions scheduled as services started.
+	defer s.liaison.waitForHealthGatedSessions()
 	return s.service.Start(ctx, projectName, options)
 }
 
@@ -140,9 +213,52 @@ func (s *composeService) Up(ctx context.Context, project *types.Project, options
 		return fmt.Errorf("unable to process project: %w", err)
 	}
 
-	// Cache the nominal service lists.
-	services := project.Services
-	disabledServices := project.DisabledServices
+	// Verify that any external volumes required by the Mutagen Compose sidecar
+	// service exist.
+	if err := s.liaison.ensureExternalVolumesExist(ctx, project); err != nil {
+		return err
+	}
+
+	// If services have been marked for building (i.e. --build was specified),
+	// then build them before bringing up the Mutagen Compose sidecar service.
+	// Compose would otherwise build them while creating the remaining
+	// services, i.e. after session reconciliation, which would leave sessions
+	// synchronizing while builds are still running. We use the same progress
+	// mode as other Compose output and then clear the build pull policy for
+	// the built services so that Compose doesn't rebuild them.
+	if built := servicesMarkedForBuild(project.Services); len(built) > 0 {
+		buildOptions := api.BuildOptions{
+			Progress: progress.Mode,
+			Services: built,
+		}
+		if err := s.service.Build(ctx, project, buildOptions); err != nil {
+			return err
+		}
+		project = projectWithServices(project,
+			withoutBuildPullPolicy(project.Services, built),
+			project.DisabledServices,
+		)
+	}
+
+	// If the up operation is waiting for services to become running/healthy,
+	// then have session reconciliation wait for sessions to reach a steady
+	// state.
+	s.liaison.waitForSessions = options.Start.Wait
+
+	// If any existing sidecar container was created by a different version of
+	// Mutagen, then force recreation of the sidecar containers so that the
+	// agent version matches. Compose would normally detect this via the
+	// sidecar's configuration hash, but we don't want to rely on that.
+	recreate := api.RecreateDiverged
+	if stale, err := s.liaison.staleSidecarContainers(ctx, project.Name); err != nil {
+		return fmt.Errorf("unable to check Mutagen Compose sidecar container versions: %w", err)
+	} else if len(stale) > 0 {
+		s.liaison.log().WithField("containers", stale).Infof(
+			"Recreating Mutagen Compose sidecar container(s) created by a different Mutagen version (current version: %s)",
+			mutagen.Version,
+		)
+		recreate = api.RecreateForce
+	}
 
 	// Bring up the Mutagen Compose sidecar service first. We do this for two
 	// reasons: First, we don't want user-specified up flags (which might be
@@ -165,52 +281,73 @@ func (s *composeService) Up(ctx context.Context, project *types.Project, options
 	// networks, for example, are always created when any service starts,
 	// regardless of whether or not it depends on them).
 	//
-	// To do this, we'll need to temporarily modify the service lists to include
-	// only the Mutagen service, because although the underlying create call
-	// will filter services if a list is specified in the create options, the
-	// underlying start call has no such option field. In this case, we'll tell
-	// the up operation to ignore orphans, since all other services at that
-	// point would be orphans.
-	//
-	// We also have to perform a stop operation on the Mutagen service before
-	// performing the up operation to ensure that session reconciliation occurs
-	// if the service is already running. Fortunately this operation has no
-	// effect or output if the Mutagen service doesn't yet exist, and no effect
-	// if the Mutagen service is already stopped.
-	project.Services = types.Services{s.liaison.mutagenService}
-	project.DisabledServices = nil
+	// To do this, we'll need to present service lists that include only the
+	// Mutagen service (using a copy of the project), because although the
+	// underlying create call will filter services if a list is specified in the
+	// create options, the underlying start call has no such option field. In
+	// this case, we'll tell the up operation to ignore orphans, since all other
+	// services at that point would be orphans.
+	mutagenProject := projectWithServices(project, s.liaison.sidecarServices(), nil)
 	mutagenStopOptions := api.StopOptions{
-		Services: []string{sidecarServiceName},
+		Services: s.liaison.sidecarServiceNamesForProject(),
 	}
 	mutagenUpOptions := api.UpOptions{
 		Create: api.CreateOptions{
-			Services:      []string{sidecarServiceName},
+			Services:      s.liaison.sidecarServiceNamesForProject(),
+			Recreate:      recreate,
 			IgnoreOrphans: true,
 		},
 		Start: api.StartOptions{
-			AttachTo: []string{sidecarServiceName},
+			AttachTo: s.liaison.sidecarServiceNamesForProject(),
 		},
 	}
+
+	// Stop the Mutagen service before performing the up operation to ensure
+	// that session reconciliation occurs if the service is already running.
+	// Fortunately this operation has no effect or output if the Mutagen service
+	// doesn't yet exist, and no effect if the Mutagen service is already
+	// stopped.
 	if err := s.service.Stop(ctx, project.Name, mutagenStopOptions); err != nil {
-		project.Services = services
-		project.DisabledServices = disabledServices
 		return fmt.Errorf("unable to stop Mutagen Compose sidecar service: %w", err)
-	} else if err = s.service.Up(ctx, project, mutagenUpOptions); err != nil {
-		project.Services = services
-		project.DisabledServices = disabledServices
-		return fmt.Errorf("unable to bring up Mutagen Compose sidecar service: %w", err)
 	}
 
-	// Restore the service lists but keep the Mutagen service defined so that it
-	// doesn't appear as an orphan service.
-	project.Services = services
-	project.DisabledServices = appendServiceByCopy(disabledServices, s.liaison.mutagenService)
-
-	// Invoke the underlying implementation.
-	result := s.service.Up(ctx, project, options)
+	// Perform the up operation. Sessions are reconciled when the sidecar
+	// containers are started (see dockerAPIClient.ContainerStart), with any
+	// sidecar containers that weren't started by Compose handled explicitly by
+	// ensureSessionsStarted. If the Mutagen service comes up but initial
+	// synchronization doesn't complete, then we still bring up the remaining
+	// services, but we report a dedicated exit status (see below) so that the
+	// condition can be detected.
+	var incompleteErr error
+	if err := s.service.Up(ctx, mutagenProject, mutagenUpOptions); err != nil {
+		if !s.liaison.initialSynchronizationIncomplete {
+			return fmt.Errorf("unable to bring up Mutagen Compose sidecar service: %w", err)
+		}
+		incompleteErr = err
+	} else if err = s.liaison.ensureSessionsStarted(ctx, project.Name); err != nil {
+		return err
+	}
 
-	// Restore the service lists.
-	project.DisabledServices = disabledServices
+	// Keep the Mutagen service defined so that it doesn't appear as an orphan
+	// service.
+	servicesProject := projectWithServices(project,
+		project.Services,
+		s.liaison.withSidecarServices(project.DisabledServices),
+	)
+
+	// Invoke the underlying implementation and wait for the creation of any
+	// health-gated forwarding sessions scheduled as services started.
+	result := s.service.Up(ctx, servicesProject, options)
+	s.liaison.waitForHealthGatedSessions()
+
+	// If the project came up without completing initial synchronization, then
+	// report the dedicated exit status.
+	if result == nil && incompleteErr != nil {
+		return cli.StatusError{
+			StatusCode: InitialSynchronizationIncompleteExitCode,
+			Status:     fmt.Sprintf("initial Mutagen synchronization incomplete: %v", incompleteErr),
+		}
+	}
 
 	// Done.
 	return result
@@ -223,48 +360,75 @@ func (s *composeService) Down(ctx context.Context, projectName string, options a
 		return fmt.Errorf("unable to process project: %w", err)
 	}
 
-	// Cache the nominal service list and inject the Mutagen service definition
-	// if the project is non-nil.
-	var services types.Services
+	// Inject the Mutagen service definition if the project is non-nil. Since
+	// options is passed by value, we can replace the project without affecting
+	// the caller.
 	if options.Project != nil {
-		services = options.Project.Services
-		options.Project.Services = appendServiceByCopy(options.Project.Services, s.liaison.mutagenService)
+		options.Project = projectWithServices(options.Project,
+			s.liaison.withSidecarServices(options.Project.Services),
+			options.Project.DisabledServices,
+		)
 	}
 
 	// Invoke the underlying implementation.
-	result := s.service.Down(ctx, projectName, options)
+	if err := s.service.Down(ctx, projectName, options); err != nil {
+		return err
+	}
 
-	// Restore the service list if the project is non-nil.
-	if options.Project != nil {
-		options.Project.Services = services
+	// If orphans are being removed, then also terminate any of the project's
+	// Mutagen sessions that have outlived their sidecar containers (e.g. due
+	// to an interrupted teardown). Sessions associated with the sidecar
+	// containers removed above will have already been terminated.
+	if options.RemoveOrphans {
+		pruned, err := s.liaison.pruneProjectSessions(ctx, projectName, options.Project)
+		if err != nil {
+			return fmt.Errorf("unable to prune orphaned Mutagen sessions: %w", err)
+		} else if count := len(pruned.Forwarding) + len(pruned.Synchronization); count > 0 {
+			s.liaison.log().Infof("Terminated %d orphaned Mutagen session(s)", count)
+		}
 	}
 
-	// Done.
-	return result
+	// Stop the project-scoped Mutagen daemon, if any. Failure to do so doesn't
+	// affect the teardown itself, so we only warn about it.
+	if err := s.liaison.stopProjectDaemon(ctx, projectName, options.Project); err != nil {
+		s.liaison.log().Warnf("unable to stop project-scoped Mutagen daemon: %v", err)
+	}
+
+	// Success.
+	return nil
 }
 
 // Logs implements github.com/docker/compose/v2/pkg/api.Service.Logs.
 func (s *composeService) Logs(ctx context.Context, projectName string, consumer api.LogConsumer, options api.LogOptions) error {
+	// If specific services have been requested and sidecar logs have been
+	// requested, then add the Mutagen Compose sidecar services to the list of
+	// services. If no services have been specified, then Compose will already
+	// include the sidecar containers since it selects containers by project.
+	if s.liaison.includeSidecarLogs && len(options.Services) > 0 {
+		sidecarServiceNames, err := s.liaison.sidecarServiceNames(ctx, projectName)
+		if err != nil {
+			return fmt.Errorf("unable to determine Mutagen Compose sidecar service names: %w", err)
+		}
+		services := make([]string, 0, len(options.Services)+len(sidecarServiceNames))
+		services = append(services, options.Services...)
+		options.Services = append(services, sidecarServiceNames...)
+	}
+
+	// Invoke the underlying implementation.
 	return s.service.Logs(ctx, projectName, consumer, options)
 }
 
 // Ps implements github.com/docker/compose/v2/pkg/api.Service.Ps.
 func (s *composeService) Ps(ctx context.Context, projectName string, options api.PsOptions) ([]api.ContainerSummary, error) {
-	// Perform a query to identify the Mutagen Compose sidecar container. We
-	// allow it to not exist, but we don't allow multiple matches.
-	containers, err := s.liaison.dockerCLI.Client().ContainerList(ctx, moby.ContainerListOptions{
-		Filters: filters.NewArgs(
-			filters.Arg("label", fmt.Sprintf("%s=%s", api.ProjectLabel, projectName)),
-			filters.Arg("label", fmt.Sprintf("%s=%s", sidecarRoleLabelKey, sidecarRoleLabelValue)),
-		),
-		All: true,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("unable to query Mutagen sidecar container: %w", err)
-	} else if len(containers) > 1 {
-		return nil, errors.New("multiple Mutagen sidecar containers identified")
-	} else if len(containers) == 1 {
-		if err := s.liaison.listSessions(ctx, containers[0].ID); err != nil {
+	// Identify the Mutagen Compose sidecar containers and list their sessions.
+	// We allow them to not exist.
+	sidecarIDs, err := s.liaison.resolveSidecarIDs(ctx, projectName, false)
+	var notFound *SidecarNotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		return nil, err
+	}
+	for _, sidecarID := range sidecarIDs {
+		if err := s.liaison.listSessions(ctx, sidecarID); err != nil {
 			return nil, err
 		}
 	}
@@ -291,6 +455,7 @@ func (s *composeService) Kill(ctx context.Context, projectName string, options a
 // RunOneOffContainer implements
 // github.com/docker/compose/v2/pkg/api.Service.RunOneOffContainer.
 func (s *composeService) RunOneOffContainer(ctx context.Context, project *types.Project, options api.RunOptions) (int, error) {
+	defer s.liaison.waitForHealthGatedSessions()
 	return s.service.RunOneOffContainer(ctx, project, options)
 }
 
@@ -326,7 +491,63 @@ func (s *composeService) Top(ctx context.Context, projectName string, services [
 
 // Events implements github.com/docker/compose/v2/pkg/api.Service.Events.
 func (s *composeService) Events(ctx context.Context, projectName string, options api.EventsOptions) error {
-	return s.service.Events(ctx, projectName, options)
+	// Determine whether or not Mutagen session events should be included. They
+	// are associated with the Mutagen Compose sidecar services.
+	sidecarServiceNames, err := s.liaison.sidecarServiceNames(ctx, projectName)
+	if err != nil {
+		return fmt.Errorf("unable to determine Mutagen Compose sidecar service names: %w", err)
+	}
+	includeMutagen := len(options.Services) == 0
+	for _, service := range options.Services {
+		for _, name := range sidecarServiceNames {
+			if service == name {
+				includeMutagen = true
+			}
+		}
+	}
+
+	// If Mutagen session events aren't being included, then just invoke the
+	// underlying implementation.
+	if !includeMutagen {
+		return s.service.Events(ctx, projectName, options)
+	}
+
+	// Create a cancellable context to regulate event watching.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Serialize access to the event consumer, since it will be invoked from
+	// multiple Goroutines.
+	var consumerLock sync.Mutex
+	consumer := options.Consumer
+	options.Consumer = func(event api.Event) error {
+		consumerLock.Lock()
+		defer consumerLock.Unlock()
+		return consumer(event)
+	}
+
+	// Start watching for Mutagen session events and Compose events.
+	mutagenErrors := make(chan error, 1)
+	go func() {
+		mutagenErrors <- s.liaison.watchSessionEvents(ctx, projectName, options.Consumer)
+	}()
+	composeErrors := make(chan error, 1)
+	go func() {
+		composeErrors <- s.service.Events(ctx, projectName, options)
+	}()
+
+	// Wait for termination. Mutagen session event watching only terminates
+	// without error if the context is cancelled, in which case Compose event
+	// watching will also terminate.
+	select {
+	case err := <-composeErrors:
+		return err
+	case err := <-mutagenErrors:
+		if err != nil {
+			return fmt.Errorf("unable to watch Mutagen session events: %w", err)
+		}
+		return <-composeErrors
+	}
 }
 
 // Port implements github.com/docker/compose/v2/pkg/api.Service.Port.
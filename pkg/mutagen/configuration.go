@@ -1,18 +1,311 @@
 package mutagen
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/compose-spec/compose-go/types"
+
 	"github.com/mutagen-io/mutagen/pkg/configuration/forwarding"
 	"github.com/mutagen-io/mutagen/pkg/configuration/synchronization"
 )
 
+const (
+	// defaultExtensionKey is the default name of the Compose extension field
+	// containing Mutagen configuration.
+	defaultExtensionKey = "x-mutagen"
+	// extensionKeyEnvironmentVariable is the environment variable that can be
+	// used to override the name of the Compose extension field containing
+	// Mutagen configuration.
+	extensionKeyEnvironmentVariable = "MUTAGEN_COMPOSE_EXTENSION_KEY"
+)
+
+// extensionKey determines the name of the Compose extension field containing
+// Mutagen configuration. It uses the value of the
+// MUTAGEN_COMPOSE_EXTENSION_KEY environment variable if set, otherwise falling
+// back to "x-mutagen". Since Compose only allows custom top-level fields with
+// an "x-" prefix, the key must carry that prefix.
+func extensionKey() (string, error) {
+	key := os.Getenv(extensionKeyEnvironmentVariable)
+	if key == "" {
+		return defaultExtensionKey, nil
+	} else if !strings.HasPrefix(key, "x-") {
+		return "", fmt.Errorf("invalid extension key (%s) specified by %s: must begin with \"x-\"",
+			key, extensionKeyEnvironmentVariable,
+		)
+	}
+	return key, nil
+}
+
+// decodeConfiguration extracts and decodes the Mutagen extension section from
+// the specified project. If no section is present, then an empty configuration
+// is returned.
+func decodeConfiguration(project *types.Project) (*configuration, error) {
+	// Determine the extension key.
+	key, err := extensionKey()
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform decoding, if necessary.
+	result := &configuration{}
+	if x, ok := project.Extensions[key]; ok {
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.TextUnmarshallerHookFunc(),
+				boolToIgnoreVCSModeHookFunc(),
+				integerToModeHookFunc(),
+				stringToShellCommandHookFunc(),
+				environmentHookFunc(),
+			),
+			ErrorUnused: true,
+			Result:      result,
+			MatchName: func(mapKey, fieldName string) bool {
+				return mapKey == fieldName
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to create configuration decoder: %w", err)
+		} else if err = decoder.Decode(x); err != nil {
+			return nil, fmt.Errorf("unable to decode %s section: %w", key, err)
+		}
+	}
+
+	// Success.
+	return result, nil
+}
+
+// SessionNames returns the sorted names of the Mutagen sessions defined by the
+// specified project. It only decodes the project's Mutagen configuration and
+// expands session templates and patterns (performing no further validation), so
+// it's suitable for use in contexts (such as shell completion) where the
+// project won't actually be processed.
+func SessionNames(project *types.Project) ([]string, error) {
+	// Decode the configuration.
+	xMutagen, err := decodeConfiguration(project)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand templated synchronization sessions and sessions that use volume
+	// patterns.
+	if err := expandSynchronizationCounts(xMutagen.Synchronization); err != nil {
+		return nil, err
+	} else if err := expandSynchronizationVolumeGlobs(xMutagen.Synchronization, project.Volumes); err != nil {
+		return nil, err
+	}
+
+	// Extract session names, ignoring default specifications.
+	var names []string
+	for name := range xMutagen.Forwarding {
+		if name != "defaults" {
+			names = append(names, name)
+		}
+	}
+	for name := range xMutagen.Synchronization {
+		if name != "defaults" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Success.
+	return names, nil
+}
+
+// ConfigurationDigest returns a digest of the Mutagen configuration for the
+// specified project, after expanding session templates and patterns. It can be
+// used to determine whether or not changes to a project's Compose files affect
+// its Mutagen sessions. Like SessionNames, it performs no further validation.
+func ConfigurationDigest(project *types.Project) (string, error) {
+	// Decode the configuration.
+	xMutagen, err := decodeConfiguration(project)
+	if err != nil {
+		return "", err
+	}
+
+	// Expand templated synchronization sessions and sessions that use volume
+	// patterns.
+	if err := expandSynchronizationCounts(xMutagen.Synchronization); err != nil {
+		return "", err
+	} else if err := expandSynchronizationVolumeGlobs(xMutagen.Synchronization, project.Volumes); err != nil {
+		return "", err
+	}
+
+	// Compute the digest. JSON encoding sorts map keys, so the encoding is
+	// deterministic.
+	encoded, err := json.Marshal(xMutagen)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode configuration: %w", err)
+	}
+	digest := sha256.Sum256(encoded)
+
+	// Success.
+	return hex.EncodeToString(digest[:]), nil
+}
+
 // sidecarConfiguration encodes sidecar service configuration.
 type sidecarConfiguration struct {
+	// Name is the name given to the sidecar service. It defaults to "mutagen"
+	// and can be overridden if it conflicts with a user-defined service.
+	Name string `mapstructure:"name"`
 	// Features controls the sidecar feature set.
 	Features string `mapstructure:"features"`
 	// Restart is the restart policy for the sidecar container.
 	Restart string `mapstructure:"restart"`
 	// ContainerName is the name given to the sidecar container.
 	ContainerName string `mapstructure:"container_name"`
+	// StopGracePeriod is the period to wait when stopping the sidecar container
+	// before forcibly killing it. It uses Go duration syntax (e.g. "1m30s").
+	StopGracePeriod string `mapstructure:"stop_grace_period"`
+	// StagingVolume is the name of a project volume to use for the Mutagen
+	// data directory (which includes the staging directory) inside the sidecar
+	// container, allowing staged files to survive sidecar container
+	// recreation without consuming space in the container's writable layer.
+	StagingVolume string `mapstructure:"stagingVolume"`
+	// PullPolicy is the pull policy for the sidecar image. It may be "never",
+	// "missing", or "always". It can be overridden on the command line.
+	PullPolicy string `mapstructure:"pull_policy"`
+	// Command overrides the command run by the sidecar container. It may be a
+	// string (split using shell quoting rules) or an array of strings. This is
+	// an advanced setting intended for debugging and specialized sidecar
+	// images: the sidecar must still run a Mutagen agent environment that's
+	// compatible with the default image, so it should rarely be needed. If
+	// unspecified, the image's command is used.
+	Command types.ShellCommand `mapstructure:"command"`
+	// Entrypoint overrides the entrypoint of the sidecar container, with the
+	// same format and caveats as Command. If unspecified, the image's
+	// entrypoint is used.
+	Entrypoint types.ShellCommand `mapstructure:"entrypoint"`
+	// Environment specifies additional environment variables for the sidecar
+	// container (e.g. GOMAXPROCS or Mutagen tuning variables). It may use
+	// either the map or list syntax supported by Compose, and variables without
+	// a value are taken from the environment of Mutagen Compose (if set).
+	// Variables that Mutagen Compose manages itself are reserved.
+	Environment types.MappingWithEquals `mapstructure:"environment"`
+	// LogLevel is the Mutagen log level (e.g. "debug" or "trace") to set for
+	// Mutagen processes in the sidecar container via the MUTAGEN_LOG_LEVEL
+	// environment variable. Note that Mutagen agents are launched in the
+	// sidecar container by the Mutagen daemon, which passes its own log level
+	// to them and collects their output in its own log, so agent verbosity
+	// also depends on the MUTAGEN_LOG_LEVEL setting used to start the daemon.
+	LogLevel string `mapstructure:"logLevel"`
+	// Placement specifies placement constraints and preferences for the sidecar
+	// service, which are applied to its deploy configuration. Since volumes are
+	// local to the node on which they're created, this can be used in
+	// multi-host (e.g. Swarm) contexts to ensure that the sidecar lands on the
+	// node hosting the volumes that it synchronizes. Note that placement is
+	// only honored by orchestrators that support it: a single Docker engine
+	// ignores it.
+	Placement *placementConfiguration `mapstructure:"placement"`
+	// Position controls where the sidecar services are placed relative to
+	// user-defined services when they're injected into the project's service
+	// list. It may be "first" or "last" (the default).
+	Position string `mapstructure:"position"`
+	// RegistryAuth specifies credentials to use when pulling the sidecar
+	// image, overriding those that would be selected from the Docker CLI
+	// configuration.
+	RegistryAuth *registryAuthConfiguration `mapstructure:"registryAuth"`
+}
+
+// placementConfiguration encodes sidecar placement configuration. It uses the
+// same format as the placement section of a Compose service's deploy
+// configuration.
+type placementConfiguration struct {
+	// Constraints are the placement constraints (e.g.
+	// "node.hostname == worker-1").
+	Constraints []string `mapstructure:"constraints"`
+	// Preferences are the placement preferences.
+	Preferences []placementPreferenceConfiguration `mapstructure:"preferences"`
+}
+
+// placementPreferenceConfiguration encodes a sidecar placement preference.
+type placementPreferenceConfiguration struct {
+	// Spread is the node or engine label across which to spread (e.g.
+	// "node.labels.zone").
+	Spread string `mapstructure:"spread"`
+}
+
+// placementConstraintMatcher matches valid placement constraints, which take the
+// form "<attribute> == <value>" or "<attribute> != <value>". The attribute,
+// operator, and value are captured as submatches.
+var placementConstraintMatcher = regexp.MustCompile(
+	`^\s*(node\.(?:id|hostname|role|platform\.os|platform\.arch|labels\.\S+)|engine\.labels\.\S+)\s*(==|!=)\s*(\S.*?)\s*$`,
+)
+
+// placementPreferenceMatcher matches valid placement preference labels.
+var placementPreferenceMatcher = regexp.MustCompile(`^(node|engine)\.labels\.\S+$`)
+
+// placement validates the placement configuration and converts it to the
+// Compose format.
+func (c *placementConfiguration) placement() (types.Placement, error) {
+	for _, constraint := range c.Constraints {
+		if !placementConstraintMatcher.MatchString(constraint) {
+			return types.Placement{}, fmt.Errorf("invalid placement constraint: %s", constraint)
+		}
+	}
+	var preferences []types.PlacementPreferences
+	for _, preference := range c.Preferences {
+		if !placementPreferenceMatcher.MatchString(preference.Spread) {
+			return types.Placement{}, fmt.Errorf("invalid placement preference: %s", preference.Spread)
+		}
+		preferences = append(preferences, types.PlacementPreferences{Spread: preference.Spread})
+	}
+	return types.Placement{Constraints: c.Constraints, Preferences: preferences}, nil
+}
+
+// registryAuthConfiguration encodes registry authentication configuration for
+// the sidecar image. Either a named credential or a username and password must
+// be specified.
+type registryAuthConfiguration struct {
+	// Credential is the registry name of a credential in the Docker CLI
+	// configuration (which may be provided by a credential helper) to use.
+	Credential string `mapstructure:"credential"`
+	// Username is the registry username.
+	Username string `mapstructure:"username"`
+	// Password is the registry password. Compose interpolation can be used to
+	// avoid storing it in the Compose configuration.
+	Password string `mapstructure:"password"`
+}
+
+// ensureValid ensures that the registry authentication configuration is valid.
+func (c *registryAuthConfiguration) ensureValid() error {
+	if c.Credential != "" {
+		if c.Username != "" || c.Password != "" {
+			return errors.New("named credential can't be combined with username or password")
+		}
+	} else if c.Username == "" {
+		return errors.New("either a named credential or username must be specified")
+	} else if c.Password == "" {
+		return errors.New("password must be specified with username")
+	}
+	return nil
+}
+
+// daemonConfiguration encodes Mutagen daemon configuration.
+type daemonConfiguration struct {
+	// Isolated indicates whether or not the project's sessions should be
+	// managed by a project-scoped Mutagen daemon. If unspecified, the
+	// user-level default is used. A project-scoped daemon is started on demand
+	// and stopped when the project is brought down.
+	Isolated *bool `mapstructure:"isolated"`
+}
+
+// reconciliationConfiguration encodes session reconciliation configuration.
+type reconciliationConfiguration struct {
+	// KeepOrphans indicates whether or not sessions associated with the
+	// project's sidecar that don't correspond to any defined session should be
+	// left untouched (rather than pruned) during reconciliation.
+	KeepOrphans bool `mapstructure:"keepOrphans"`
 }
 
 // forwardingConfiguration encodes a forwarding session specification.
@@ -21,6 +314,20 @@ type forwardingConfiguration struct {
 	Source string `mapstructure:"source"`
 	// Destination is the destination URL for the session.
 	Destination string `mapstructure:"destination"`
+	// SidecarGroup is the sidecar group for the session. Sessions in a
+	// non-empty group are hosted by a dedicated sidecar container for that
+	// group, isolating their resource usage from other sessions.
+	SidecarGroup string `mapstructure:"sidecarGroup"`
+	// WaitForHealthy indicates whether or not creation of the session should
+	// be deferred until the project service targeted by its (network)
+	// destination is healthy. Services without health checks are considered
+	// healthy once running.
+	WaitForHealthy bool `mapstructure:"waitForHealthy"`
+	// Labels are user-defined labels to apply to the session, which can be
+	// used with Mutagen label selectors. Labels in the default configuration
+	// are merged with (and overridden by) session labels. Keys with the
+	// reserved io.mutagen.compose. prefix aren't allowed.
+	Labels map[string]string `mapstructure:"labels"`
 	// Configuration is the configuration for the session.
 	Configuration forwarding.Configuration `mapstructure:",squash"`
 	// ConfigurationSource is the source-specific configuration for the session.
@@ -31,11 +338,54 @@ type forwardingConfiguration struct {
 }
 
 // synchronizationConfiguration encodes a synchronization session specification.
+// The embedded Mutagen configurations use the same keys as the global Mutagen
+// configuration file, so settings such as stageMode, scanMode, watch,
+// permissions, maxEntryCount, and maxStagingFileSize (which accepts either a
+// byte count or a human-readable size such as "100 MB") are decoded (using
+// their text unmarshalling methods), validated in processProject, and merged
+// with any defaults before being included in the session creation
+// specification. Note that Mutagen doesn't expose any compression settings:
+// synchronization endpoint streams are always compressed and forwarding streams
+// are passed through as-is, so there's nothing to surface here and compression
+// keys will be rejected as unknown.
 type synchronizationConfiguration struct {
 	// Alpha is the alpha URL for the session.
 	Alpha string `mapstructure:"alpha"`
 	// Beta is the beta URL for the session.
 	Beta string `mapstructure:"beta"`
+	// SidecarGroup is the sidecar group for the session. Sessions in a
+	// non-empty group are hosted by a dedicated sidecar container for that
+	// group, isolating their resource usage from other sessions.
+	SidecarGroup string `mapstructure:"sidecarGroup"`
+	// Count, if positive, turns the session into a template that's expanded
+	// into the specified number of sessions, named by appending the index
+	// (starting at 0) to the session name. Any ${index} placeholders in the
+	// alpha and beta URLs are replaced with the index for each session. Since
+	// Compose performs its own variable interpolation, the placeholder needs
+	// to be written as $${index} in Compose files.
+	Count int `mapstructure:"count"`
+	// FlushOnCreate indicates whether or not the session should be flushed
+	// (blocking until its initial synchronization cycle completes) after it's
+	// created during reconciliation. If false, the session is still created
+	// and started, but it synchronizes in the background. If unspecified, the
+	// value from the default configuration is used, falling back to true.
+	FlushOnCreate *bool `mapstructure:"flushOnCreate"`
+	// Labels are user-defined labels to apply to the session, which can be
+	// used with Mutagen label selectors. Labels in the default configuration
+	// are merged with (and overridden by) session labels. Keys with the
+	// reserved io.mutagen.compose. prefix aren't allowed.
+	Labels map[string]string `mapstructure:"labels"`
+	// ConflictResolution is the conflict resolution policy for the session.
+	// A value of "manual" leaves conflicts for manual resolution (mapping to
+	// the two-way-safe mode) and a value of "alpha" automatically resolves
+	// conflicts in favor of alpha (mapping to the two-way-resolved mode).
+	// Mutagen doesn't support automatic resolution in favor of beta, so
+	// sessions that need it should swap their alpha and beta URLs. One-way
+	// modes have their own fixed semantics (one-way-safe preserves conflicting
+	// beta contents and one-way-replica overwrites them), so a policy can't be
+	// combined with them. A policy in the default configuration applies to
+	// sessions that don't specify their own policy or mode.
+	ConflictResolution conflictResolutionPolicy `mapstructure:"conflictResolution"`
 	// Configuration is the configuration for the session.
 	Configuration synchronization.Configuration `mapstructure:",squash"`
 	// ConfigurationAlpha is the alpha-specific configuration for the session.
@@ -45,18 +395,24 @@ type synchronizationConfiguration struct {
 }
 
 // configuration encodes collections of Mutagen forwarding and synchronization
-// sessions found under an "x-mutagen" extension field.
+// sessions found under an "x-mutagen" (or alternatively named) extension field.
 type configuration struct {
 	// Sidecar represents the sidecar service configuration.
 	Sidecar sidecarConfiguration `mapstructure:"sidecar"`
+	// Daemon represents the Mutagen daemon configuration.
+	Daemon daemonConfiguration `mapstructure:"daemon"`
+	// Reconciliation represents the session reconciliation configuration.
+	Reconciliation reconciliationConfiguration `mapstructure:"reconciliation"`
 	// Forwarding represents the forwarding sessions to be created. If a
 	// "defaults" key is present, it is treated as a template upon which other
 	// configurations are layered, thus keeping syntactic compatibility with the
 	// global Mutagen configuration file.
 	Forwarding map[string]forwardingConfiguration `mapstructure:"forward"`
-	// Synchronization represents the forwarding sessions to be created. If a
-	// "defaults" key is present, it is treated as a template upon which other
-	// configurations are layered, thus keeping syntactic compatibility with the
-	// global Mutagen configuration file.
+	// Synchronization represents the synchronization sessions to be created.
+	// If a "defaults" key is present, it is treated as a template upon which
+	// other configurations are layered, thus keeping syntactic compatibility
+	// with the global Mutagen configuration file. All settings (including
+	// scanMode and watch settings) are inherited from the template unless
+	// overridden by an individual session.
 	Synchronization map[string]synchronizationConfiguration `mapstructure:"sync"`
 }
@@ -0,0 +1,174 @@
+package mutagen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mutagen-io/mutagen/pkg/synchronization"
+)
+
+// TestSynchronizationDefaultsStageMode tests that a staging mode specified in
+// the default synchronization configuration is merged into the creation
+// specifications of synchronization sessions and that invalid staging modes
+// are rejected. Staging modes are validated when decoded by
+// synchronization.StageMode.UnmarshalText (and again by the configuration
+// validation in processProject), so an invalid mode is reported as a decoding
+// error.
+func TestSynchronizationDefaultsStageMode(t *testing.T) {
+	testCases := []struct {
+		description     string
+		defaultMode     string
+		sessionMode     string
+		expected        synchronization.StageMode
+		expectedSuccess bool
+	}{
+		{"unspecified", "", "", synchronization.StageMode_StageModeDefault, true},
+		{"default", "neighboring", "", synchronization.StageMode_StageModeNeighboring, true},
+		{"session", "", "internal", synchronization.StageMode_StageModeInternal, true},
+		{"session override", "neighboring", "internal", synchronization.StageMode_StageModeInternal, true},
+		{"invalid default", "bogus", "", 0, false},
+		{"invalid session", "neighboring", "bogus", 0, false},
+	}
+	for _, testCase := range testCases {
+		// Create the synchronization configuration.
+		defaults := map[string]interface{}{}
+		if testCase.defaultMode != "" {
+			defaults["stageMode"] = testCase.defaultMode
+		}
+		session := map[string]interface{}{
+			"alpha": "/project",
+			"beta":  "volume://code",
+		}
+		if testCase.sessionMode != "" {
+			session["stageMode"] = testCase.sessionMode
+		}
+
+		// Process the project.
+		liaison := newTestLiaison()
+		project := newTestProject(map[string]interface{}{
+			"sync": map[string]interface{}{
+				"defaults": defaults,
+				"code":     session,
+			},
+		})
+		err := liaison.processProject(project)
+		if !testCase.expectedSuccess {
+			if err == nil {
+				t.Errorf("%s: processing succeeded unexpectedly", testCase.description)
+			} else if !strings.Contains(err.Error(), "unknown staging mode") {
+				t.Errorf("%s: unexpected error: %v", testCase.description, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unable to process project: %v", testCase.description, err)
+			continue
+		}
+
+		// Verify the staging mode.
+		specification := liaison.synchronization["code"]
+		if specification == nil {
+			t.Errorf("%s: synchronization session missing", testCase.description)
+		} else if mode := specification.Configuration.StageMode; mode != testCase.expected {
+			t.Errorf("%s: staging mode mismatch: %v != %v", testCase.description, mode, testCase.expected)
+		}
+	}
+}
+
+// TestSynchronizationLimits tests that entry count and staging file size limits
+// are decoded, merged with the default synchronization configuration, and
+// included in the creation specifications of synchronization sessions. Staging
+// file size limits may be specified either as byte counts or as human-readable
+// sizes.
+func TestSynchronizationLimits(t *testing.T) {
+	testCases := []struct {
+		description             string
+		defaults                map[string]interface{}
+		session                 map[string]interface{}
+		expectedEntryCount      uint64
+		expectedStagingFileSize uint64
+		expectedSuccess         bool
+	}{
+		{
+			description:             "session byte count",
+			session:                 map[string]interface{}{"maxEntryCount": 500, "maxStagingFileSize": 1048576},
+			expectedEntryCount:      500,
+			expectedStagingFileSize: 1048576,
+			expectedSuccess:         true,
+		},
+		{
+			description:             "session human-readable size",
+			session:                 map[string]interface{}{"maxStagingFileSize": "100 MB"},
+			expectedStagingFileSize: 100000000,
+			expectedSuccess:         true,
+		},
+		{
+			description:             "defaults",
+			defaults:                map[string]interface{}{"maxEntryCount": 1000, "maxStagingFileSize": "100 MB"},
+			expectedEntryCount:      1000,
+			expectedStagingFileSize: 100000000,
+			expectedSuccess:         true,
+		},
+		{
+			description:             "merged",
+			defaults:                map[string]interface{}{"maxEntryCount": 1000, "maxStagingFileSize": "100 MB"},
+			session:                 map[string]interface{}{"maxStagingFileSize": 1048576},
+			expectedEntryCount:      1000,
+			expectedStagingFileSize: 1048576,
+			expectedSuccess:         true,
+		},
+		{
+			description: "invalid size",
+			session:     map[string]interface{}{"maxStagingFileSize": "lots"},
+		},
+		{
+			description: "negative entry count",
+			session:     map[string]interface{}{"maxEntryCount": -1},
+		},
+	}
+	for _, testCase := range testCases {
+		// Create the synchronization configuration.
+		defaults := map[string]interface{}{}
+		for key, value := range testCase.defaults {
+			defaults[key] = value
+		}
+		session := map[string]interface{}{
+			"alpha": "/project",
+			"beta":  "volume://code",
+		}
+		for key, value := range testCase.session {
+			session[key] = value
+		}
+
+		// Process the project.
+		liaison := newTestLiaison()
+		project := newTestProject(map[string]interface{}{
+			"sync": map[string]interface{}{
+				"defaults": defaults,
+				"code":     session,
+			},
+		})
+		err := liaison.processProject(project)
+		if !testCase.expectedSuccess {
+			if err == nil {
+				t.Errorf("%s: processing succeeded unexpectedly", testCase.description)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unable to process project: %v", testCase.description, err)
+			continue
+		}
+
+		// Verify the limits.
+		specification := liaison.synchronization["code"]
+		if specification == nil {
+			t.Errorf("%s: synchronization session missing", testCase.description)
+			continue
+		}
+		if count := specification.Configuration.MaximumEntryCount; count != testCase.expectedEntryCount {
+			t.Errorf("%s: maximum entry count mismatch: %d != %d", testCase.description, count, testCase.expectedEntryCount)
+		}
+		if size := specification.Configuration.MaximumStagingFileSize; size != testCase.expectedStagingFileSize {
+			t.Errorf("%s: maximum staging file size mismatch: %d != %d", testCase.description, size, testCase.expectedStagingFileSize)
+		}
+	}
+}
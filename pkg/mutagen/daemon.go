@@ -0,0 +1,434 @@
+package mutagen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose/v2/pkg/api"
+
+	"github.com/mutagen-io/mutagen/cmd/mutagen/daemon"
+
+	"github.com/mutagen-io/mutagen/pkg/filesystem"
+	"github.com/mutagen-io/mutagen/pkg/grpcutil"
+	"github.com/mutagen-io/mutagen/pkg/mutagen"
+	daemonsvc "github.com/mutagen-io/mutagen/pkg/service/daemon"
+)
+
+const (
+	// dataDirectoryEnvironmentVariable is the environment variable used by
+	// Mutagen to override the location of its data directory.
+	dataDirectoryEnvironmentVariable = "MUTAGEN_DATA_DIRECTORY"
+	// isolatedDaemonDirectoryName is the name of the directory (inside the base
+	// Mutagen data directory) under which project-scoped data directories are
+	// stored.
+	isolatedDaemonDirectoryName = "compose"
+	// defaultDaemonTimeout is the default maximum amount of time to wait for a
+	// Mutagen daemon connection to be established.
+	defaultDaemonTimeout = 30 * time.Second
+)
+
+// initialDataDirectory and initialDataDirectorySet record the value of the
+// Mutagen data directory environment variable at startup, before it is
+// potentially overridden to target a project-scoped daemon.
+var initialDataDirectory, initialDataDirectorySet = os.LookupEnv(dataDirectoryEnvironmentVariable)
+
+// baseDataDirectory and baseDataDirectoryErr record the base Mutagen data
+// directory path (or the error encountered computing it). The path is computed
+// at startup, before the environment is potentially modified, using Mutagen's
+// own logic so that it accounts for MUTAGEN_DATA_DIRECTORY and for the separate
+// data directory used by development builds of Mutagen.
+var baseDataDirectory, baseDataDirectoryErr = filesystem.Mutagen(false)
+
+// daemonEnvironmentLock serializes modification of the Mutagen data directory
+// environment variable together with the daemon connection (and any daemon
+// startup) that depends on it. Without it, concurrent connections to different
+// daemons could target (or start a daemon using) the wrong data directory.
+var daemonEnvironmentLock sync.Mutex
+
+// isolatedDaemonDataDirectory computes the data directory path used for the
+// project-scoped Mutagen daemon of the specified project. Project-scoped data
+// directories are stored inside the base Mutagen data directory, which honors
+// any MUTAGEN_DATA_DIRECTORY setting present at startup as well as Mutagen's
+// development mode.
+func isolatedDaemonDataDirectory(projectName string) (string, error) {
+	// Validate the project name.
+	if projectName == "" {
+		return "", errors.New("empty project name")
+	}
+
+	// Verify that the base data directory was computed successfully.
+	if baseDataDirectoryErr != nil {
+		return "", fmt.Errorf("unable to compute Mutagen data directory: %w", baseDataDirectoryErr)
+	}
+
+	// Compute the project-scoped data directory.
+	return filepath.Join(baseDataDirectory, isolatedDaemonDirectoryName, projectName), nil
+}
+
+// connectToDaemon connects to the Mutagen daemon, autostarting it if necessary.
+// If isolated is true, then the connection targets (and, if necessary, starts)
+// the project-scoped daemon for the specified project, otherwise it targets the
+// daemon for the base Mutagen data directory.
+func connectToDaemon(isolated bool, projectName string) (*grpc.ClientConn, error) {
+	return connectToDaemonWithAutostart(isolated, projectName, true)
+}
+
+// connectToDaemonWithAutostart connects to the Mutagen daemon targeted in the
+// same manner as connectToDaemon, starting it only if autostart is true.
+// Because Mutagen determines its data directory from the environment, this
+// function modifies the process environment (which is inherited by any daemon
+// that it starts), so it holds daemonEnvironmentLock for the duration of the
+// connection.
+func connectToDaemonWithAutostart(isolated bool, projectName string, autostart bool) (*grpc.ClientConn, error) {
+	// Lock the environment and defer its release.
+	daemonEnvironmentLock.Lock()
+	defer daemonEnvironmentLock.Unlock()
+
+	// Configure the data directory.
+	if isolated {
+		dataDirectory, err := isolatedDaemonDataDirectory(projectName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute project-scoped data directory: %w", err)
+		} else if err := os.Setenv(dataDirectoryEnvironmentVariable, dataDirectory); err != nil {
+			return nil, fmt.Errorf("unable to set data directory: %w", err)
+		}
+	} else if initialDataDirectorySet {
+		if err := os.Setenv(dataDirectoryEnvironmentVariable, initialDataDirectory); err != nil {
+			return nil, fmt.Errorf("unable to set data directory: %w", err)
+		}
+	} else if err := os.Unsetenv(dataDirectoryEnvironmentVariable); err != nil {
+		return nil, fmt.Errorf("unable to unset data directory: %w", err)
+	}
+
+	// Perform the connection. Version compatibility is verified separately by
+	// the liaison so that it can be reported in detail and applied uniformly to
+	// registered connectors.
+	return daemon.Connect(autostart, false)
+}
+
+// formatDaemonVersion formats the version reported by a Mutagen daemon in the
+// same manner as mutagen.Version.
+func formatDaemonVersion(version *daemonsvc.VersionResponse) string {
+	if version.Tag != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", version.Major, version.Minor, version.Patch, version.Tag)
+	}
+	return fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+}
+
+// checkDaemonVersion queries the version of the Mutagen daemon on the specified
+// connection and verifies that it matches the embedded Mutagen version, using
+// the same criteria as Mutagen itself. It returns the daemon version. If the
+// versions don't match, then the daemon version is returned along with a
+// *DaemonVersionMismatchError.
+func checkDaemonVersion(ctx context.Context, connection *grpc.ClientConn) (string, error) {
+	// Query the daemon version.
+	version, err := daemonsvc.NewDaemonClient(connection).Version(ctx, &daemonsvc.VersionRequest{})
+	if err != nil {
+		return "", fmt.Errorf("unable to query daemon version: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	}
+	daemonVersion := formatDaemonVersion(version)
+
+	// Verify compatibility.
+	if version.Major != mutagen.VersionMajor ||
+		version.Minor != mutagen.VersionMinor ||
+		version.Patch != mutagen.VersionPatch ||
+		version.Tag != mutagen.VersionTag {
+		return daemonVersion, &DaemonVersionMismatchError{
+			DaemonVersion: daemonVersion,
+			Version:       mutagen.Version,
+		}
+	}
+
+	// Success.
+	return daemonVersion, nil
+}
+
+// withDaemonVersionCheck wraps a daemon connector so that connections to daemons
+// with incompatible versions are closed and rejected (with a
+// *DaemonVersionMismatchError) rather than failing later with confusing
+// protocol errors.
+func withDaemonVersionCheck(connector DaemonConnector) DaemonConnector {
+	return func(isolated bool, projectName string) (*grpc.ClientConn, error) {
+		connection, err := connector(isolated, projectName)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := checkDaemonVersion(context.Background(), connection); err != nil {
+			connection.Close()
+			return nil, err
+		}
+		return connection, nil
+	}
+}
+
+// RunningDaemonVersion returns the version of the shared Mutagen daemon if it's
+// running. Unlike other daemon connections, it won't start the daemon. If the
+// daemon isn't running (or can't be reached), then an error is returned.
+func RunningDaemonVersion(ctx context.Context) (string, error) {
+	// Connect to the shared daemon without starting it.
+	connection, err := connectToDaemonWithAutostart(false, "", false)
+	if err != nil {
+		return "", fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
+	}
+	defer connection.Close()
+
+	// Query the daemon version, ignoring compatibility. We apply the default
+	// connection timeout since the daemon may be unresponsive.
+	ctx, cancel := context.WithTimeout(ctx, defaultDaemonTimeout)
+	defer cancel()
+	version, err := daemonsvc.NewDaemonClient(connection).Version(ctx, &daemonsvc.VersionRequest{})
+	if err != nil {
+		return "", fmt.Errorf("unable to query daemon version: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	}
+	return formatDaemonVersion(version), nil
+}
+
+// DaemonConnector is the signature for functions that connect to a Mutagen
+// daemon. If isolated is true, then the connection should target the
+// project-scoped daemon for the specified project, otherwise it should target
+// the shared daemon. Connectors can be registered with a liaison to control how
+// it acquires daemon connections (e.g. to target an in-process daemon when
+// testing).
+type DaemonConnector func(isolated bool, projectName string) (*grpc.ClientConn, error)
+
+// daemonConnectionPool caches daemon connections for reuse across operations.
+// Its zero value is initialized and ready to use. It is safe for concurrent
+// usage since Compose may operate on multiple sidecar containers concurrently:
+// all pool operations (including the connector invocations that populate it)
+// are serialized by its lock. Note that this doesn't serialize connections made
+// outside of the pool, so the default connector also serializes its
+// environment modification (see connectToDaemonWithAutostart).
+type daemonConnectionPool struct {
+	// lock serializes access to the pool.
+	lock sync.Mutex
+	// connections maps daemon keys to connections. The shared daemon uses
+	// the empty key and project-scoped daemons are keyed by project name.
+	connections map[string]*grpc.ClientConn
+}
+
+// daemonConnectionKey computes the key used to cache connections to the
+// specified daemon. The shared daemon uses the empty key and project-scoped
+// daemons are keyed by project name.
+func daemonConnectionKey(isolated bool, projectName string) string {
+	if isolated {
+		return projectName
+	}
+	return ""
+}
+
+// connect returns a cached connection for the specified daemon, connecting to
+// the daemon using the specified connector if necessary.
+func (p *daemonConnectionPool) connect(connector DaemonConnector, isolated bool, projectName string) (*grpc.ClientConn, error) {
+	// Lock the pool and defer its release.
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Compute the daemon key and check for an existing connection.
+	key := daemonConnectionKey(isolated, projectName)
+	if connection, ok := p.connections[key]; ok {
+		return connection, nil
+	}
+
+	// Connect to the daemon and cache the connection.
+	connection, err := connector(isolated, projectName)
+	if err != nil {
+		return nil, err
+	}
+	if p.connections == nil {
+		p.connections = make(map[string]*grpc.ClientConn)
+	}
+	p.connections[key] = connection
+
+	// Success.
+	return connection, nil
+}
+
+// evict closes and removes any cached connection for the specified daemon.
+func (p *daemonConnectionPool) evict(isolated bool, projectName string) {
+	// Lock the pool and defer its release.
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Close and remove any existing connection.
+	key := daemonConnectionKey(isolated, projectName)
+	if connection, ok := p.connections[key]; ok {
+		connection.Close()
+		delete(p.connections, key)
+	}
+}
+
+// close closes all cached connections.
+func (p *daemonConnectionPool) close() error {
+	// Lock the pool and defer its release.
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Close connections, tracking the first error.
+	var result error
+	for key, connection := range p.connections {
+		if err := connection.Close(); err != nil && result == nil {
+			result = fmt.Errorf("unable to close daemon connection: %w", err)
+		}
+		delete(p.connections, key)
+	}
+	return result
+}
+
+// SetDaemonConnectionReuse sets whether or not the liaison should hold daemon
+// connections open for its lifetime and reuse them across operations, rather
+// than connecting for each operation. If enabled, then Shutdown must be invoked
+// to close the connections.
+func (l *Liaison) SetDaemonConnectionReuse(reuse bool) {
+	l.reuseDaemonConnections = reuse
+}
+
+// connectToDaemonForSidecar connects to the Mutagen daemon responsible for the
+// sessions associated with the specified sidecar container, which is determined
+// by the labels applied to the container. The resulting connection must be
+// released using releaseDaemonConnection (rather than closed directly) since it
+// may be held for reuse.
+func (l *Liaison) connectToDaemonForSidecar(ctx context.Context, sidecarID string) (*grpc.ClientConn, error) {
+	// Inspect the sidecar container.
+	metadata, err := l.dockerCLI.Client().ContainerInspect(ctx, sidecarID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to inspect Mutagen Compose sidecar container: %w", err)
+	} else if metadata.Config == nil {
+		return nil, errors.New("Mutagen Compose sidecar container has no configuration")
+	}
+
+	// Connect to the appropriate daemon.
+	isolated := metadata.Config.Labels[sidecarIsolatedDaemonLabelKey] == sidecarIsolatedDaemonLabelValue
+	return l.connectToDaemon(isolated, metadata.Config.Labels[api.ProjectLabel])
+}
+
+// RegisterDaemonConnector registers the function used to connect to Mutagen
+// daemons. If no connector is registered (or connector is nil), then the
+// default connector is used, which connects to (and, if necessary, starts) the
+// daemon for the appropriate Mutagen data directory.
+func (l *Liaison) RegisterDaemonConnector(connector DaemonConnector) {
+	l.daemonConnector = connector
+}
+
+// connectToDaemon connects to the specified Mutagen daemon using the registered
+// daemon connector, using a cached connection if connection reuse is enabled.
+// The daemon's version is verified to match the embedded Mutagen version. The
+// resulting connection must be released using releaseDaemonConnection.
+func (l *Liaison) connectToDaemon(isolated bool, projectName string) (*grpc.ClientConn, error) {
+	connector := l.daemonConnector
+	if connector == nil {
+		connector = connectToDaemon
+	}
+	connector = withDaemonVersionCheck(connector)
+	if l.daemonTimeout > 0 {
+		connector = withDaemonTimeout(connector, l.daemonTimeout)
+	}
+	if l.reuseDaemonConnections {
+		return l.daemonConnections.connect(connector, isolated, projectName)
+	}
+	return connector(isolated, projectName)
+}
+
+// withDaemonTimeout wraps a daemon connector so that it fails if a connection
+// isn't established within the specified timeout. Mutagen's connection logic
+// can block indefinitely if a daemon is listening but unresponsive, so the
+// underlying connector is run in a separate Goroutine, and any connection that
+// it establishes after the timeout has elapsed is closed.
+func withDaemonTimeout(connector DaemonConnector, timeout time.Duration) DaemonConnector {
+	return func(isolated bool, projectName string) (*grpc.ClientConn, error) {
+		// Start the connection operation.
+		type connectResult struct {
+			connection *grpc.ClientConn
+			err        error
+		}
+		results := make(chan connectResult, 1)
+		go func() {
+			connection, err := connector(isolated, projectName)
+			results <- connectResult{connection, err}
+		}()
+
+		// Wait for the connection operation to complete or time out.
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case result := <-results:
+			return result.connection, result.err
+		case <-timer.C:
+			go func() {
+				if result := <-results; result.connection != nil {
+					result.connection.Close()
+				}
+			}()
+			return nil, fmt.Errorf("Mutagen daemon did not respond within %v", timeout)
+		}
+	}
+}
+
+// projectDaemonIsolated determines whether or not the specified project's
+// sessions are managed by a project-scoped Mutagen daemon. This is determined
+// from the project's configuration if it's been processed (i.e. if project is
+// non-nil), otherwise from the user-level default.
+func (l *Liaison) projectDaemonIsolated(project *types.Project) bool {
+	if project != nil {
+		return l.isolatedDaemon
+	}
+	return l.userConfiguration != nil && l.userConfiguration.Daemon.Isolated
+}
+
+// stopProjectDaemon stops the project-scoped Mutagen daemon for the specified
+// project, if the project uses one and it's running. It's invoked once the
+// project has been torn down, at which point the daemon has nothing left to
+// manage. Any sessions that remain (e.g. those orphaned by an interrupted
+// teardown) are persisted by the daemon and will be loaded again if it's
+// restarted. If a daemon connector is registered, then it's responsible for
+// daemon lifecycles and no daemon is stopped.
+func (l *Liaison) stopProjectDaemon(ctx context.Context, projectName string, project *types.Project) error {
+	// If the project doesn't use a project-scoped daemon (or daemons are
+	// managed by a registered connector), then there's nothing to stop.
+	if !l.projectDaemonIsolated(project) || l.daemonConnector != nil {
+		return nil
+	}
+
+	// Release any cached connection to the daemon.
+	l.daemonConnections.evict(true, projectName)
+
+	// If the daemon has never been used, then there's nothing to stop.
+	if dataDirectory, err := isolatedDaemonDataDirectory(projectName); err != nil {
+		return fmt.Errorf("unable to compute project-scoped data directory: %w", err)
+	} else if _, err := os.Stat(dataDirectory); os.IsNotExist(err) {
+		return nil
+	}
+
+	// Connect to the daemon without starting it. If we can't connect, then
+	// the daemon isn't running.
+	connection, err := connectToDaemonWithAutostart(true, projectName, false)
+	if err != nil {
+		return nil
+	}
+	defer connection.Close()
+
+	// Terminate the daemon.
+	if _, err := daemonsvc.NewDaemonClient(connection).Terminate(ctx, &daemonsvc.TerminateRequest{}); err != nil {
+		return fmt.Errorf("unable to terminate daemon: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	}
+
+	// Success.
+	return nil
+}
+
+// releaseDaemonConnection releases a connection obtained from
+// connectToDaemonForSidecar or connectToDaemon, closing it unless it's held for
+// reuse.
+func (l *Liaison) releaseDaemonConnection(connection *grpc.ClientConn) {
+	if !l.reuseDaemonConnections {
+		connection.Close()
+	}
+}
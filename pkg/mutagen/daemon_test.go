@@ -0,0 +1,298 @@
+package mutagen
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/mutagen-io/mutagen/pkg/mutagen"
+	daemonsvc "github.com/mutagen-io/mutagen/pkg/service/daemon"
+)
+
+// testDaemonServer is an in-process Mutagen daemon service that reports a
+// fixed version.
+type testDaemonServer struct {
+	daemonsvc.UnimplementedDaemonServer
+	// version is the version to report.
+	version *daemonsvc.VersionResponse
+}
+
+// Version implements daemonsvc.DaemonServer.Version.
+func (s *testDaemonServer) Version(_ context.Context, _ *daemonsvc.VersionRequest) (*daemonsvc.VersionResponse, error) {
+	return s.version, nil
+}
+
+// testDaemonConnector is a daemon connector that connects to an in-process
+// Mutagen daemon service and records the connections that it establishes.
+type testDaemonConnector struct {
+	// listener is the in-process listener for the daemon service.
+	listener *bufconn.Listener
+	// block, if non-nil, is waited on before each connection is established.
+	block chan struct{}
+	// lock serializes access to connections.
+	lock sync.Mutex
+	// connections are the connections established by the connector.
+	connections []*grpc.ClientConn
+}
+
+// newTestDaemonConnector starts an in-process Mutagen daemon service reporting
+// the specified version and returns a connector targeting it. The service is
+// stopped when the test completes.
+func newTestDaemonConnector(t *testing.T, version *daemonsvc.VersionResponse) *testDaemonConnector {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	daemonsvc.RegisterDaemonServer(server, &testDaemonServer{version: version})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return &testDaemonConnector{listener: listener}
+}
+
+// connect implements DaemonConnector.
+func (c *testDaemonConnector) connect(_ bool, _ string) (*grpc.ClientConn, error) {
+	if c.block != nil {
+		<-c.block
+	}
+	connection, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return c.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.connections = append(c.connections, connection)
+	c.lock.Unlock()
+	return connection, nil
+}
+
+// established returns the connections established by the connector.
+func (c *testDaemonConnector) established() []*grpc.ClientConn {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*grpc.ClientConn(nil), c.connections...)
+}
+
+// testDaemonVersion returns a version response matching the embedded Mutagen
+// version.
+func testDaemonVersion() *daemonsvc.VersionResponse {
+	return &daemonsvc.VersionResponse{
+		Major: mutagen.VersionMajor,
+		Minor: mutagen.VersionMinor,
+		Patch: mutagen.VersionPatch,
+		Tag:   mutagen.VersionTag,
+	}
+}
+
+// waitForConnectionShutdown waits for the specified connection to be closed.
+func waitForConnectionShutdown(connection *grpc.ClientConn) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for state := connection.GetState(); state != connectivity.Shutdown; state = connection.GetState() {
+		if !connection.WaitForStateChange(ctx, state) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestConnectToDaemonPooling tests that connections established by a registered
+// daemon connector are cached per daemon and held open if connection reuse is
+// enabled and are established for each operation (and closed when released)
+// otherwise.
+func TestConnectToDaemonPooling(t *testing.T) {
+	for _, reuse := range []bool{false, true} {
+		// Create the liaison.
+		connector := newTestDaemonConnector(t, testDaemonVersion())
+		liaison := &Liaison{}
+		liaison.RegisterDaemonConnector(connector.connect)
+		liaison.SetDaemonConnectionReuse(reuse)
+
+		// Perform connections to the shared daemon and two project-scoped
+		// daemons, releasing each connection after use.
+		targets := []struct {
+			isolated    bool
+			projectName string
+		}{
+			{false, "first"},
+			{false, "second"},
+			{true, "first"},
+			{true, "first"},
+			{true, "second"},
+		}
+		var connections []*grpc.ClientConn
+		for _, target := range targets {
+			connection, err := liaison.connectToDaemon(target.isolated, target.projectName)
+			if err != nil {
+				t.Fatalf("reuse %t: unable to connect to daemon: %v", reuse, err)
+			}
+			connections = append(connections, connection)
+			liaison.releaseDaemonConnection(connection)
+		}
+
+		// Verify connection usage.
+		if reuse {
+			if count := len(connector.established()); count != 3 {
+				t.Errorf("reuse %t: unexpected connection count: %d != 3", reuse, count)
+			}
+			if connections[0] != connections[1] {
+				t.Errorf("reuse %t: shared daemon connection not reused", reuse)
+			}
+			if connections[2] != connections[3] {
+				t.Errorf("reuse %t: project-scoped daemon connection not reused", reuse)
+			}
+			if connections[0] == connections[2] || connections[2] == connections[4] {
+				t.Errorf("reuse %t: connection reused across daemons", reuse)
+			}
+			for _, connection := range connector.established() {
+				if connection.GetState() == connectivity.Shutdown {
+					t.Errorf("reuse %t: pooled connection closed on release", reuse)
+				}
+			}
+			if err := liaison.Shutdown(); err != nil {
+				t.Errorf("reuse %t: unable to close pooled connections: %v", reuse, err)
+			}
+			for _, connection := range connector.established() {
+				if connection.GetState() != connectivity.Shutdown {
+					t.Errorf("reuse %t: pooled connection not closed", reuse)
+				}
+			}
+		} else {
+			if count := len(connector.established()); count != len(targets) {
+				t.Errorf("reuse %t: unexpected connection count: %d != %d", reuse, count, len(targets))
+			}
+			for _, connection := range connector.established() {
+				if connection.GetState() != connectivity.Shutdown {
+					t.Errorf("reuse %t: connection not closed on release", reuse)
+				}
+			}
+		}
+	}
+}
+
+// TestConnectToDaemonVersionCheck tests that connections established by a
+// registered daemon connector are rejected (and closed) if the daemon's version
+// doesn't match the embedded Mutagen version and that rejected connections
+// aren't pooled.
+func TestConnectToDaemonVersionCheck(t *testing.T) {
+	// Create the liaison.
+	version := testDaemonVersion()
+	version.Patch++
+	connector := newTestDaemonConnector(t, version)
+	liaison := &Liaison{}
+	liaison.RegisterDaemonConnector(connector.connect)
+	liaison.SetDaemonConnectionReuse(true)
+
+	// Attempt to connect.
+	for i := 0; i < 2; i++ {
+		_, err := liaison.connectToDaemon(false, "")
+		var mismatch *DaemonVersionMismatchError
+		if !errors.As(err, &mismatch) {
+			t.Fatal("version mismatch not reported:", err)
+		} else if mismatch.DaemonVersion != formatDaemonVersion(version) {
+			t.Errorf("daemon version mismatch: %s != %s", mismatch.DaemonVersion, formatDaemonVersion(version))
+		} else if mismatch.Version != mutagen.Version {
+			t.Errorf("version mismatch: %s != %s", mismatch.Version, mutagen.Version)
+		}
+	}
+
+	// Verify that each attempt connected and that the connections were closed.
+	established := connector.established()
+	if len(established) != 2 {
+		t.Errorf("unexpected connection count: %d != 2", len(established))
+	}
+	for _, connection := range established {
+		if connection.GetState() != connectivity.Shutdown {
+			t.Error("incompatible connection not closed")
+		}
+	}
+}
+
+// TestConnectToDaemonTimeout tests that connection attempts by a registered
+// daemon connector fail if they don't complete within the daemon timeout and
+// that any connection established after the timeout is closed.
+func TestConnectToDaemonTimeout(t *testing.T) {
+	// Create the liaison.
+	connector := newTestDaemonConnector(t, testDaemonVersion())
+	connector.block = make(chan struct{})
+	liaison := &Liaison{}
+	liaison.RegisterDaemonConnector(connector.connect)
+	liaison.daemonTimeout = 10 * time.Millisecond
+
+	// Attempt to connect.
+	if _, err := liaison.connectToDaemon(false, ""); err == nil {
+		t.Fatal("connection succeeded unexpectedly")
+	} else if !strings.Contains(err.Error(), "did not respond within 10ms") {
+		t.Error("unexpected error:", err)
+	}
+
+	// Unblock the connector and verify that the late connection is closed.
+	close(connector.block)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(connector.established()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	established := connector.established()
+	if len(established) != 1 {
+		t.Fatalf("unexpected connection count: %d != 1", len(established))
+	} else if !waitForConnectionShutdown(established[0]) {
+		t.Error("late connection not closed")
+	}
+
+	// Verify that connections completing within the timeout succeed.
+	liaison.daemonTimeout = 5 * time.Second
+	connection, err := liaison.connectToDaemon(false, "")
+	if err != nil {
+		t.Fatal("unable to connect to daemon:", err)
+	}
+	liaison.releaseDaemonConnection(connection)
+}
+
+// TestDaemonConnectionPoolEvict tests that evicting a pooled connection closes
+// it and that subsequent connections to the same daemon are re-established
+// while connections to other daemons are unaffected.
+func TestDaemonConnectionPoolEvict(t *testing.T) {
+	// Create a pool and populate it.
+	connector := newTestDaemonConnector(t, testDaemonVersion())
+	pool := &daemonConnectionPool{}
+	defer pool.close()
+	shared, err := pool.connect(connector.connect, false, "project")
+	if err != nil {
+		t.Fatal("unable to connect to shared daemon:", err)
+	}
+	isolated, err := pool.connect(connector.connect, true, "project")
+	if err != nil {
+		t.Fatal("unable to connect to project-scoped daemon:", err)
+	}
+
+	// Evict the project-scoped daemon connection.
+	pool.evict(true, "project")
+	if isolated.GetState() != connectivity.Shutdown {
+		t.Error("evicted connection not closed")
+	}
+	if shared.GetState() == connectivity.Shutdown {
+		t.Error("shared daemon connection closed by eviction")
+	}
+
+	// Verify that the project-scoped daemon connection is re-established.
+	if connection, err := pool.connect(connector.connect, true, "project"); err != nil {
+		t.Fatal("unable to reconnect to project-scoped daemon:", err)
+	} else if connection == isolated {
+		t.Error("evicted connection reused")
+	}
+	if connection, err := pool.connect(connector.connect, false, "project"); err != nil {
+		t.Fatal("unable to reconnect to shared daemon:", err)
+	} else if connection != shared {
+		t.Error("shared daemon connection not reused")
+	}
+}
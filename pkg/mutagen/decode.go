@@ -1,10 +1,17 @@
 package mutagen
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 
+	"github.com/mattn/go-shellwords"
 	"github.com/mitchellh/mapstructure"
 
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/mutagen-io/mutagen/pkg/filesystem"
 	"github.com/mutagen-io/mutagen/pkg/synchronization/core"
 )
 
@@ -36,3 +43,122 @@ func boolToIgnoreVCSModeHookFunc() mapstructure.DecodeHookFuncType {
 		}
 	}
 }
+
+// stringToShellCommandHookFunc returns a mapstructure.DecodeHookFunc that will
+// convert string types into a types.ShellCommand by splitting them into words
+// using shell quoting rules, matching the handling of command and entrypoint
+// strings by Compose. Arrays are decoded as-is.
+func stringToShellCommandHookFunc() mapstructure.DecodeHookFuncType {
+	return func(valueType reflect.Type, storageType reflect.Type, data any) (any, error) {
+		// If the incoming type isn't a string, then we're done.
+		if valueType.Kind() != reflect.String {
+			return data, nil
+		}
+
+		// If the storage isn't a ShellCommand, then we're done.
+		if storageType != reflect.TypeOf(types.ShellCommand{}) {
+			return data, nil
+		}
+
+		// Otherwise, perform conversion.
+		words, err := shellwords.Parse(data.(string))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse command: %w", err)
+		}
+		return types.ShellCommand(words), nil
+	}
+}
+
+// environmentHookFunc returns a mapstructure.DecodeHookFunc that will convert
+// environment specifications into a types.MappingWithEquals, supporting both of
+// the syntaxes accepted by Compose: a map of variable names to values and a list
+// of "NAME=value" strings. Variables without a value (i.e. null map values or
+// list entries without an equals sign) are decoded with nil values.
+func environmentHookFunc() mapstructure.DecodeHookFuncType {
+	return func(valueType reflect.Type, storageType reflect.Type, data any) (any, error) {
+		// If the storage isn't a MappingWithEquals, then we're done.
+		if storageType != reflect.TypeOf(types.MappingWithEquals{}) {
+			return data, nil
+		}
+
+		// Perform conversion based on the incoming type.
+		result := make(types.MappingWithEquals)
+		switch valueType.Kind() {
+		case reflect.Slice:
+			for _, entry := range data.([]any) {
+				specification, ok := entry.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid environment entry (%v): must be a string", entry)
+				}
+				name, value, hasValue := strings.Cut(specification, "=")
+				if name == "" {
+					return nil, fmt.Errorf("invalid environment entry (%s): empty variable name", specification)
+				} else if hasValue {
+					result[name] = &value
+				} else {
+					result[name] = nil
+				}
+			}
+		case reflect.Map:
+			for name, value := range data.(map[string]any) {
+				switch value.(type) {
+				case nil:
+					result[name] = nil
+				case string, bool, int, int64, uint64, float64:
+					formatted := fmt.Sprint(value)
+					result[name] = &formatted
+				default:
+					return nil, fmt.Errorf("invalid value for environment variable (%s): must be a scalar", name)
+				}
+			}
+		default:
+			return data, nil
+		}
+
+		// Success.
+		return result, nil
+	}
+}
+
+// integerToModeHookFunc returns a mapstructure.DecodeHookFunc that will convert
+// integer types into a filesystem.Mode. This hook is necessary for the same
+// reason as boolToIgnoreVCSModeHookFunc: unquoted permission modes (e.g. 0644)
+// will already have been decoded into integers by the YAML decoding performed
+// by Compose, with leading zeros indicating octal notation. Without this hook,
+// such values would be stored without validation, so we verify that they lie
+// within the portable permission bits. String representations of modes will
+// still be handled by the filesystem.Mode.UnmarshalText method, which always
+// treats values as octal.
+func integerToModeHookFunc() mapstructure.DecodeHookFuncType {
+	return func(valueType reflect.Type, storageType reflect.Type, data any) (any, error) {
+		// If the storage isn't a Mode, then we're done.
+		if storageType != reflect.TypeOf(filesystem.Mode(0)) {
+			return data, nil
+		}
+
+		// Extract the integer value, if any.
+		var value uint64
+		switch valueType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			signed := reflect.ValueOf(data).Int()
+			if signed < 0 {
+				return nil, errors.New("negative permission mode")
+			}
+			value = uint64(signed)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value = reflect.ValueOf(data).Uint()
+		default:
+			return data, nil
+		}
+
+		// Verify that the mode only contains permission bits. The most likely
+		// cause of failure here is a mode specified without a leading zero,
+		// which YAML will treat as a decimal value.
+		if value&uint64(filesystem.ModePermissionsMask) != value {
+			return nil, fmt.Errorf("permission mode (%d) contains disallowed bits (use octal notation with a leading zero, e.g. 0644)", value)
+		}
+
+		// Perform conversion.
+		return filesystem.Mode(value), nil
+	}
+}
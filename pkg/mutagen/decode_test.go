@@ -0,0 +1,73 @@
+package mutagen
+
+import (
+	"testing"
+
+	"github.com/mutagen-io/mutagen/pkg/filesystem"
+)
+
+// TestIntegerToModeHookFunc tests that unquoted permission modes (which Compose
+// decodes into integers) are decoded through x-mutagen into the endpoint-
+// specific configurations of synchronization session creation specifications
+// and that modes with disallowed bits are rejected.
+func TestIntegerToModeHookFunc(t *testing.T) {
+	// Integer modes are specified as they'd be produced by YAML decoding, i.e.
+	// an unquoted 0644 yields the (octal) integer 0644 and an unquoted 420
+	// yields the (decimal) integer 420.
+	testCases := []struct {
+		description       string
+		fileMode          any
+		directoryMode     any
+		expectedFile      filesystem.Mode
+		expectedDirectory filesystem.Mode
+		expectedSuccess   bool
+	}{
+		{"octal", 0644, 0755, 0644, 0755, true},
+		{"decimal", 420, 493, 0644, 0755, true},
+		{"unsigned", uint64(0600), uint64(0700), 0600, 0700, true},
+		{"quoted octal", "0644", "755", 0644, 0755, true},
+		{"decimal without leading zero", 644, 0755, 0, 0, false},
+		{"setuid bit", 04755, 0755, 0, 0, false},
+		{"sticky bit", 0644, 01777, 0, 0, false},
+		{"file type bit", 0100644, 0755, 0, 0, false},
+		{"negative", -1, 0755, 0, 0, false},
+	}
+	for _, testCase := range testCases {
+		liaison := newTestLiaison()
+		project := newTestProject(map[string]any{
+			"sync": map[string]any{
+				"code": map[string]any{
+					"alpha": "/project",
+					"beta":  "volume://code",
+					"configurationAlpha": map[string]any{
+						"permissions": map[string]any{"defaultFileMode": testCase.fileMode},
+					},
+					"configurationBeta": map[string]any{
+						"permissions": map[string]any{"defaultDirectoryMode": testCase.directoryMode},
+					},
+				},
+			},
+		})
+		err := liaison.processProject(project)
+		if !testCase.expectedSuccess {
+			if err == nil {
+				t.Errorf("%s: processing succeeded unexpectedly", testCase.description)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unable to process project: %v", testCase.description, err)
+			continue
+		}
+		specification := liaison.synchronization["code"]
+		if specification == nil {
+			t.Errorf("%s: synchronization session missing", testCase.description)
+			continue
+		}
+		if mode := filesystem.Mode(specification.ConfigurationAlpha.DefaultFileMode); mode != testCase.expectedFile {
+			t.Errorf("%s: alpha file mode mismatch: %o != %o", testCase.description, mode, testCase.expectedFile)
+		}
+		if mode := filesystem.Mode(specification.ConfigurationBeta.DefaultDirectoryMode); mode != testCase.expectedDirectory {
+			t.Errorf("%s: beta directory mode mismatch: %o != %o", testCase.description, mode, testCase.expectedDirectory)
+		}
+	}
+}
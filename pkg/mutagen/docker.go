@@ -3,9 +3,14 @@ package mutagen
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 )
 
@@ -34,6 +39,41 @@ func (c *dockerAPIClient) isMutagenComposeSidecar(ctx context.Context, container
 	return metadata.Config.Labels[sidecarRoleLabelKey] == sidecarRoleLabelValue, nil
 }
 
+// ImagePull implements github.com/docker/docker/client.APIClient.ImagePull.
+func (c *dockerAPIClient) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	// If this is a Mutagen Compose sidecar image with specific registry
+	// authentication configured, then use that authentication instead of the
+	// authentication selected by Compose.
+	if auth, err := c.liaison.sidecarRegistryAuth(ref); err != nil {
+		return nil, fmt.Errorf("unable to compute sidecar registry authentication: %w", err)
+	} else if auth != "" {
+		options.RegistryAuth = auth
+	}
+
+	// Pull the image.
+	return c.APIClient.ImagePull(ctx, ref, options)
+}
+
+// ContainerCreate implements
+// github.com/docker/docker/client.APIClient.ContainerCreate.
+func (c *dockerAPIClient) ContainerCreate(
+	ctx context.Context,
+	config *container.Config,
+	hostConfig *container.HostConfig,
+	networkingConfig *network.NetworkingConfig,
+	platform *specs.Platform,
+	containerName string,
+) (container.ContainerCreateCreatedBody, error) {
+	// If this is a Mutagen Compose sidecar container, then verify that its
+	// image is suitable for the daemon's architecture.
+	if config != nil && config.Labels[sidecarRoleLabelKey] == sidecarRoleLabelValue {
+		c.liaison.warnOnSidecarPlatformMismatch(ctx, config.Image)
+	}
+
+	// Create the container.
+	return c.APIClient.ContainerCreate(ctx, config, hostConfig, networkingConfig, platform, containerName)
+}
+
 // ContainerStart implements
 // github.com/docker/docker/client.APIClient.ContainerStart.
 func (c *dockerAPIClient) ContainerStart(ctx context.Context, container string, options types.ContainerStartOptions) error {
@@ -43,13 +83,19 @@ func (c *dockerAPIClient) ContainerStart(ctx context.Context, container string,
 	}
 
 	// If this is a Mutagen compose sidecar container, then reconcile Mutagen
-	// sessions.
+	// sessions. We do this here (rather than after the corresponding Compose
+	// operation completes) so that reconciliation progress is reported as part
+	// of the sidecar container's start. Otherwise, schedule the creation of any
+	// forwarding sessions gated on the container's service, which mustn't block
+	// or fail the container's start.
 	if sidecar, err := c.isMutagenComposeSidecar(ctx, container); err != nil {
 		return fmt.Errorf("unable to determine if container is sidecar: %w", err)
 	} else if sidecar {
-		if err := c.liaison.reconcileSessions(ctx, container); err != nil {
-			return fmt.Errorf("unable to reconcile Mutagen sessions: %w", err)
+		if err := c.liaison.startSessions(ctx, container); err != nil {
+			return err
 		}
+	} else {
+		c.liaison.startHealthGatedSessions(ctx, container)
 	}
 
 	// Success.
@@ -64,7 +110,7 @@ func (c *dockerAPIClient) ContainerPause(ctx context.Context, container string)
 	if sidecar, err := c.isMutagenComposeSidecar(ctx, container); err != nil {
 		return fmt.Errorf("unable to determine if container is sidecar: %w", err)
 	} else if sidecar {
-		if err := c.liaison.pauseSessions(ctx, container); err != nil {
+		if err := c.liaison.pauseSessions(ctx, container, nil); err != nil {
 			return fmt.Errorf("unable to pause Mutagen sessions: %w", err)
 		}
 	}
@@ -86,7 +132,7 @@ func (c *dockerAPIClient) ContainerUnpause(ctx context.Context, container string
 	if sidecar, err := c.isMutagenComposeSidecar(ctx, container); err != nil {
 		return fmt.Errorf("unable to determine if container is sidecar: %w", err)
 	} else if sidecar {
-		if err := c.liaison.resumeSessions(ctx, container); err != nil {
+		if err := c.liaison.resumeSessions(ctx, container, nil); err != nil {
 			return fmt.Errorf("unable to resume Mutagen sessions: %w", err)
 		}
 	}
@@ -99,11 +145,26 @@ func (c *dockerAPIClient) ContainerUnpause(ctx context.Context, container string
 // github.com/docker/docker/client.APIClient.ContainerStop.
 func (c *dockerAPIClient) ContainerStop(ctx context.Context, container string, timeout *time.Duration) error {
 	// If this is a Mutagen compose sidecar container, then pause associated
-	// Mutagen sessions.
+	// Mutagen sessions. For teardown operations (such as down and stop), we
+	// first perform a final flush of synchronization sessions to avoid losing
+	// pending changes. We perform this operation here (rather than in the
+	// Compose service implementation) since this is the point just before the
+	// container is stopped. We can only flush if the container is running and
+	// not paused, since sessions can't synchronize otherwise. A failed flush
+	// shouldn't prevent teardown, so it only results in a warning.
 	if sidecar, err := c.isMutagenComposeSidecar(ctx, container); err != nil {
 		return fmt.Errorf("unable to determine if container is sidecar: %w", err)
 	} else if sidecar {
-		if err := c.liaison.pauseSessions(ctx, container); err != nil {
+		if c.liaison.finalFlushEnabled {
+			if metadata, err := c.APIClient.ContainerInspect(ctx, container); err != nil {
+				return fmt.Errorf("unable to inspect container: %w", err)
+			} else if metadata.State != nil && metadata.State.Running && !metadata.State.Paused {
+				if err := c.liaison.flushSessions(ctx, container); err != nil {
+					c.liaison.log().Warnf("unable to flush Mutagen sessions (use --no-flush to skip): %v", err)
+				}
+			}
+		}
+		if err := c.liaison.pauseSessions(ctx, container, nil); err != nil {
 			return fmt.Errorf("unable to pause Mutagen sessions: %w", err)
 		}
 	}
@@ -120,7 +181,7 @@ func (c *dockerAPIClient) ContainerRemove(ctx context.Context, container string,
 	if sidecar, err := c.isMutagenComposeSidecar(ctx, container); err != nil {
 		return fmt.Errorf("unable to determine if container is sidecar: %w", err)
 	} else if sidecar {
-		if err := c.liaison.terminateSessions(ctx, container); err != nil {
+		if _, err := c.liaison.terminateSessions(ctx, container); err != nil {
 			return fmt.Errorf("unable to terminate Mutagen sessions: %w", err)
 		}
 	}
@@ -0,0 +1,235 @@
+package mutagen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	moby "github.com/docker/docker/api/types"
+
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/mutagen-io/mutagen/pkg/url"
+	forwardingurl "github.com/mutagen-io/mutagen/pkg/url/forwarding"
+)
+
+const (
+	// probeTimeoutSeconds is the timeout (in seconds) used for connectivity
+	// probes performed from inside the sidecar container.
+	probeTimeoutSeconds = 3
+)
+
+// printCheck prints the result of a diagnostic check. If the check failed and
+// a hint is provided, then the hint is printed beneath the check result.
+func printCheck(passed bool, description, hint string) {
+	if passed {
+		fmt.Println("  [pass]", description)
+	} else {
+		fmt.Println("  [fail]", description)
+		if hint != "" {
+			fmt.Println("        ", hint)
+		}
+	}
+}
+
+// probeCommandForDestination computes the command to execute inside the
+// sidecar container to probe the reachability of a forwarding destination. The
+// destination must be a sidecar URL (reified or not) as generated by
+// parseNetworkURL or parseForwardingVolumeURL.
+func probeCommandForDestination(destination *url.URL) ([]string, error) {
+	// Parse the forwarding endpoint.
+	protocol, address, err := forwardingurl.Parse(destination.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid forwarding endpoint: %w", err)
+	}
+
+	// Handle Unix domain socket endpoints, which we can only check for
+	// existence without risking interference with the target process.
+	if isUnixForwardingProtocol(protocol) {
+		return []string{"test", "-S", address}, nil
+	} else if !isTCPForwardingProtocol(protocol) {
+		return nil, fmt.Errorf("unsupported forwarding protocol (%s)", protocol)
+	}
+
+	// Handle TCP-based endpoints using a zero-I/O connection attempt.
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid forwarding address: %w", err)
+	}
+	return []string{"nc", "-z", "-w", strconv.Itoa(probeTimeoutSeconds), host, port}, nil
+}
+
+// execInSidecar executes a command inside the specified sidecar container,
+// waits for it to complete, and returns its exit code. Output is discarded.
+func (l *Liaison) execInSidecar(ctx context.Context, sidecarID string, command []string) (int, error) {
+	// Create the execution.
+	client := l.dockerCLI.Client()
+	execution, err := client.ContainerExecCreate(ctx, sidecarID, moby.ExecConfig{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          command,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to create execution: %w", err)
+	}
+
+	// Start the execution and wait for its output streams to close.
+	response, err := client.ContainerExecAttach(ctx, execution.ID, moby.ExecStartCheck{})
+	if err != nil {
+		return 0, fmt.Errorf("unable to start execution: %w", err)
+	}
+	_, err = io.Copy(io.Discard, response.Reader)
+	response.Close()
+	if err != nil {
+		return 0, fmt.Errorf("unable to wait for execution completion: %w", err)
+	}
+
+	// Extract the exit code.
+	metadata, err := client.ContainerExecInspect(ctx, execution.ID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to inspect execution: %w", err)
+	} else if metadata.Running {
+		return 0, errors.New("execution still running")
+	}
+	return metadata.ExitCode, nil
+}
+
+// Doctor performs diagnostic checks for the specified project and prints the
+// results as a checklist. It checks Docker daemon reachability, Mutagen daemon
+// connectivity, sidecar image availability, and the presence and health of the
+// Mutagen Compose sidecar container. If the sidecar container is running, then
+// it also lists the project's Mutagen sessions and, for each forwarding session,
+// probes the reachability of the destination endpoint from inside the sidecar
+// container (using the same network attachments that the forwarding session
+// uses). The project must be non-nil. Failing checks are reported (with hints)
+// but do not result in an error being returned.
+func (l *Liaison) Doctor(ctx context.Context, project *types.Project) error {
+	// Check Docker daemon reachability. Project processing requires access to
+	// the Docker daemon, so there's no point in proceeding without it.
+	fmt.Println("Environment")
+	if _, err := l.dockerCLI.Client().Info(ctx); err != nil {
+		printCheck(false, "Docker daemon reachable", fmt.Sprintf("Verify your Docker context or host settings (%v)", err))
+		return nil
+	}
+	printCheck(true, "Docker daemon reachable", "")
+
+	// Process Mutagen extensions for the project.
+	if err := l.processProject(project); err != nil {
+		printCheck(false, "Mutagen configuration valid", err.Error())
+		return nil
+	}
+	printCheck(true, "Mutagen configuration valid", "")
+
+	// Check Mutagen daemon connectivity and version compatibility.
+	var daemonReachable bool
+	var versionMismatch *DaemonVersionMismatchError
+	if daemonConnection, err := l.connectToDaemon(l.isolatedDaemon, project.Name); errors.As(err, &versionMismatch) {
+		printCheck(true, "Mutagen daemon reachable", "")
+		printCheck(false,
+			fmt.Sprintf("Mutagen daemon version (%s) compatible", versionMismatch.DaemonVersion),
+			fmt.Sprintf("Stop the Mutagen daemon so that version %s can be started", versionMismatch.Version),
+		)
+	} else if err != nil {
+		printCheck(false, "Mutagen daemon reachable", fmt.Sprintf("Ensure that a matching Mutagen version is installed (%v)", err))
+	} else {
+		daemonVersion, err := checkDaemonVersion(ctx, daemonConnection)
+		l.releaseDaemonConnection(daemonConnection)
+		printCheck(true, "Mutagen daemon reachable", "")
+		if err != nil {
+			printCheck(false, "Mutagen daemon version compatible", err.Error())
+		} else {
+			daemonReachable = true
+			printCheck(true, fmt.Sprintf("Mutagen daemon version (%s) compatible", daemonVersion), "")
+		}
+	}
+
+	// Check sidecar image availability.
+	image := l.mutagenService.Image
+	if _, _, err := l.dockerCLI.Client().ImageInspectWithRaw(ctx, image); err != nil {
+		printCheck(false, fmt.Sprintf("Sidecar image (%s) available", image), "The image will be pulled automatically by \"mutagen-compose up\"")
+	} else {
+		printCheck(true, fmt.Sprintf("Sidecar image (%s) available", image), "")
+	}
+
+	// Check the presence and health of the sidecar containers. Each sidecar
+	// group is hosted by its own container.
+	sidecars, err := l.sidecarContainers(ctx, project.Name)
+	if err != nil {
+		printCheck(false, "Mutagen Compose sidecar container exists", err.Error())
+		return nil
+	}
+	sidecarIDs := make(map[string]string, len(sidecars))
+	for _, sidecar := range sidecars {
+		sidecarIDs[sidecar.Labels[sidecarGroupLabelKey]] = sidecar.ID
+	}
+	allRunning := true
+	for _, service := range l.sidecarServices() {
+		subject := "Mutagen Compose sidecar container"
+		group := service.Labels[sidecarGroupLabelKey]
+		if group != "" {
+			subject = fmt.Sprintf("Mutagen Compose sidecar container for group (%s)", group)
+		}
+		sidecarID, ok := sidecarIDs[group]
+		if !ok {
+			printCheck(false, subject+" exists", "Run \"mutagen-compose up\" to create the project")
+			allRunning = false
+			continue
+		}
+		printCheck(true, subject+" exists", "")
+		sidecarMetadata, err := l.dockerCLI.Client().ContainerInspect(ctx, sidecarID)
+		if err != nil {
+			return fmt.Errorf("unable to inspect Mutagen Compose sidecar container: %w", err)
+		} else if sidecarMetadata.State == nil || !sidecarMetadata.State.Running {
+			printCheck(false, subject+" running", "Run \"mutagen-compose up\" to start the project")
+			allRunning = false
+		} else if sidecarMetadata.State.Health != nil && sidecarMetadata.State.Health.Status != moby.Healthy {
+			printCheck(false, subject+" healthy", "Check the sidecar container logs")
+		} else {
+			printCheck(true, subject+" running", "")
+		}
+	}
+	if !allRunning {
+		return nil
+	}
+
+	// List sessions and their statuses.
+	if daemonReachable {
+		fmt.Println()
+		for _, sidecar := range sidecars {
+			if err := l.listSessions(ctx, sidecar.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Check forwarding destination reachability, probing destinations in a
+	// stable order.
+	if len(l.forwarding) == 0 {
+		return nil
+	}
+	fmt.Println()
+	fmt.Println("Forwarding destination reachability")
+	for _, name := range sortedNames(l.forwarding) {
+		destination := l.forwarding[name].Destination
+		description := fmt.Sprintf("%s: destination %s reachable", name, destination.Path)
+		command, err := probeCommandForDestination(destination)
+		if err != nil {
+			printCheck(false, description, err.Error())
+			continue
+		}
+		exitCode, err := l.execInSidecar(ctx, sidecarIDs[l.forwardingGroups[name]], command)
+		if err != nil {
+			printCheck(false, description, fmt.Sprintf("Unable to perform probe: %v", err))
+		} else if exitCode != 0 {
+			printCheck(false, description, "Ensure that the target service is running and listening on the specified endpoint")
+		} else {
+			printCheck(true, description, "")
+		}
+	}
+
+	// Success.
+	return nil
+}
@@ -0,0 +1,73 @@
+package mutagen
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/compose-spec/compose-go/types"
+
+	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
+	synchronizationsvc "github.com/mutagen-io/mutagen/pkg/service/synchronization"
+)
+
+// EffectiveConfiguration is the fully resolved Mutagen configuration for a
+// project, i.e. the session creation specifications that Mutagen Compose will
+// use after merging defaults and expanding templates and patterns.
+type EffectiveConfiguration struct {
+	// Forwarding are the forwarding session creation specifications, keyed by
+	// session name.
+	Forwarding map[string]*forwardingsvc.CreationSpecification
+	// Synchronization are the synchronization session creation
+	// specifications, keyed by session name.
+	Synchronization map[string]*synchronizationsvc.CreationSpecification
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON. Specifications are encoded
+// using the Protocol Buffers JSON mapping so that enumeration values are
+// encoded by name.
+func (c *EffectiveConfiguration) MarshalJSON() ([]byte, error) {
+	// Encode forwarding specifications.
+	forwarding := make(map[string]json.RawMessage, len(c.Forwarding))
+	for name, specification := range c.Forwarding {
+		encoded, err := protojson.Marshal(specification)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode forwarding session (%s) specification: %w", name, err)
+		}
+		forwarding[name] = encoded
+	}
+
+	// Encode synchronization specifications.
+	synchronization := make(map[string]json.RawMessage, len(c.Synchronization))
+	for name, specification := range c.Synchronization {
+		encoded, err := protojson.Marshal(specification)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode synchronization session (%s) specification: %w", name, err)
+		}
+		synchronization[name] = encoded
+	}
+
+	// Encode the combined result.
+	return json.Marshal(struct {
+		Forwarding      map[string]json.RawMessage `json:"forwarding"`
+		Synchronization map[string]json.RawMessage `json:"synchronization"`
+	}{forwarding, synchronization})
+}
+
+// EffectiveConfiguration processes the Mutagen configuration for the specified
+// project and returns the resulting session creation specifications. Since
+// processing depends on the Docker daemon's platform (e.g. for mount paths in
+// the sidecar container), the daemon must be reachable.
+func (l *Liaison) EffectiveConfiguration(project *types.Project) (*EffectiveConfiguration, error) {
+	// Process Mutagen extensions for the project.
+	if err := l.processProject(project); err != nil {
+		return nil, fmt.Errorf("unable to process project: %w", err)
+	}
+
+	// Done.
+	return &EffectiveConfiguration{
+		Forwarding:      l.forwarding,
+		Synchronization: l.synchronization,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package mutagen
+
+import (
+	"fmt"
+)
+
+// UndefinedNetworkError indicates that a Mutagen session references a network
+// that isn't defined by the project. It's returned (possibly wrapped) by
+// operations that process the project.
+type UndefinedNetworkError struct {
+	// Network is the name of the undefined network.
+	Network string
+}
+
+// Error implements error.Error.
+func (e *UndefinedNetworkError) Error() string {
+	return fmt.Sprintf("undefined network (%s) referenced by forwarding session", e.Network)
+}
+
+// UndefinedVolumeError indicates that a Mutagen session references a volume
+// that isn't defined by the project. It's returned (possibly wrapped) by
+// operations that process the project.
+type UndefinedVolumeError struct {
+	// Volume is the name of the undefined volume.
+	Volume string
+}
+
+// Error implements error.Error.
+func (e *UndefinedVolumeError) Error() string {
+	return fmt.Sprintf("undefined volume (%s) referenced by Mutagen session", e.Volume)
+}
+
+// SidecarNotFoundError indicates that no Mutagen Compose sidecar container
+// exists for a project (or for a sidecar group within a project). It's
+// returned (possibly wrapped) by operations that require a sidecar container,
+// allowing callers to distinguish absence from other failures.
+type SidecarNotFoundError struct {
+	// Project is the name of the project.
+	Project string
+	// Group is the name of the sidecar group, if any.
+	Group string
+}
+
+// Error implements error.Error.
+func (e *SidecarNotFoundError) Error() string {
+	if e.Group != "" {
+		return fmt.Sprintf("no Mutagen Compose sidecar container found for group (%s) in project (%s)", e.Group, e.Project)
+	}
+	return fmt.Sprintf("no Mutagen Compose sidecar container found for project (%s)", e.Project)
+}
+
+// DaemonVersionMismatchError indicates that a Mutagen daemon's version doesn't
+// match the Mutagen version embedded in Mutagen Compose. Mutagen's daemon API
+// and agent protocols aren't guaranteed to be compatible across versions, so
+// such a daemon isn't used. It's returned (possibly wrapped) by operations that
+// connect to the Mutagen daemon.
+type DaemonVersionMismatchError struct {
+	// DaemonVersion is the version of the Mutagen daemon.
+	DaemonVersion string
+	// Version is the embedded Mutagen version.
+	Version string
+}
+
+// Error implements error.Error.
+func (e *DaemonVersionMismatchError) Error() string {
+	return fmt.Sprintf("Mutagen daemon version (%s) doesn't match Mutagen Compose's Mutagen version (%s) (daemon restart recommended)", e.DaemonVersion, e.Version)
+}
@@ -0,0 +1,276 @@
+package mutagen
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+
+	moby "github.com/docker/docker/api/types"
+
+	"github.com/docker/compose/v2/pkg/api"
+
+	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
+	synchronizationsvc "github.com/mutagen-io/mutagen/pkg/service/synchronization"
+)
+
+const (
+	// sessionEventPollingInterval is the interval at which Mutagen session
+	// states are polled when generating session events.
+	sessionEventPollingInterval = time.Second
+
+	// sessionEventKindForwarding is the session kind attribute value used for
+	// forwarding session events.
+	sessionEventKindForwarding = "forwarding"
+	// sessionEventKindSynchronization is the session kind attribute value used
+	// for synchronization session events.
+	sessionEventKindSynchronization = "synchronization"
+
+	// sessionEventStatusCreated is the event status used when a session is
+	// first observed.
+	sessionEventStatusCreated = "session-created"
+	// sessionEventStatusConnected is the event status used when all of a
+	// session's endpoints become connected.
+	sessionEventStatusConnected = "session-connected"
+	// sessionEventStatusDisconnected is the event status used when any of a
+	// session's endpoints becomes disconnected.
+	sessionEventStatusDisconnected = "session-disconnected"
+	// sessionEventStatusConflicted is the event status used when a
+	// synchronization session starts reporting conflicts.
+	sessionEventStatusConflicted = "session-conflicted"
+	// sessionEventStatusFlushed is the event status used when a synchronization
+	// session completes a synchronization cycle.
+	sessionEventStatusFlushed = "session-flushed"
+	// sessionEventStatusTerminated is the event status used when a session is
+	// no longer observed.
+	sessionEventStatusTerminated = "session-terminated"
+)
+
+// sessionEventState is the subset of Mutagen session state that's tracked to
+// generate session events.
+type sessionEventState struct {
+	// sidecarID is the identifier of the sidecar container hosting the session.
+	sidecarID string
+	// service is the name of the sidecar service hosting the session.
+	service string
+	// kind is the session kind.
+	kind string
+	// name is the session name.
+	name string
+	// connected indicates whether or not all session endpoints are connected.
+	connected bool
+	// conflicted indicates whether or not the session has conflicts.
+	conflicted bool
+	// successfulCycles is the number of successful synchronization cycles.
+	successfulCycles uint64
+}
+
+// sessionEventStatuses computes the event statuses corresponding to a session
+// state transition. If previous is nil, then the session is treated as newly
+// created. If current is nil, then the session is treated as terminated.
+func sessionEventStatuses(previous, current *sessionEventState) []string {
+	// Handle creation and termination.
+	if current == nil {
+		return []string{sessionEventStatusTerminated}
+	}
+	var statuses []string
+	if previous == nil {
+		statuses = append(statuses, sessionEventStatusCreated)
+		previous = &sessionEventState{}
+	}
+
+	// Handle transitions.
+	if current.connected && !previous.connected {
+		statuses = append(statuses, sessionEventStatusConnected)
+	} else if !current.connected && previous.connected {
+		statuses = append(statuses, sessionEventStatusDisconnected)
+	}
+	if current.conflicted && !previous.conflicted {
+		statuses = append(statuses, sessionEventStatusConflicted)
+	}
+	if current.successfulCycles > previous.successfulCycles {
+		statuses = append(statuses, sessionEventStatusFlushed)
+	}
+
+	// Done.
+	return statuses
+}
+
+// querySessionEventStates queries the states of the Mutagen sessions associated
+// with the specified sidecar container, recording them (keyed by session
+// identifier) in the specified map.
+func querySessionEventStates(ctx context.Context, daemonConnection *grpc.ClientConn, sidecar moby.Container, result map[string]*sessionEventState) error {
+	sidecarID := sidecar.ID
+	service := sidecar.Labels[api.ServiceLabel]
+
+	// Create the session selection criteria.
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		return err
+	}
+
+	// Query forwarding sessions.
+	forwardingService := forwardingsvc.NewForwardingClient(daemonConnection)
+	forwardingListResponse, err := forwardingService.List(ctx, &forwardingsvc.ListRequest{Selection: projectSelection})
+	if err != nil {
+		return fmt.Errorf("forwarding session listing failed: %w", err)
+	} else if err = forwardingListResponse.EnsureValid(); err != nil {
+		return fmt.Errorf("invalid forwarding session listing response received: %w", err)
+	}
+
+	// Query synchronization sessions.
+	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
+	synchronizationListResponse, err := synchronizationService.List(ctx, &synchronizationsvc.ListRequest{Selection: projectSelection})
+	if err != nil {
+		return fmt.Errorf("synchronization session listing failed: %w", err)
+	} else if err = synchronizationListResponse.EnsureValid(); err != nil {
+		return fmt.Errorf("invalid synchronization session listing response received: %w", err)
+	}
+
+	// Extract states.
+	for _, state := range forwardingListResponse.SessionStates {
+		result[state.Session.Identifier] = &sessionEventState{
+			sidecarID: sidecarID,
+			service:   service,
+			kind:      sessionEventKindForwarding,
+			name:      state.Session.Name,
+			connected: state.SourceConnected && state.DestinationConnected,
+		}
+	}
+	for _, state := range synchronizationListResponse.SessionStates {
+		result[state.Session.Identifier] = &sessionEventState{
+			sidecarID:        sidecarID,
+			service:          service,
+			kind:             sessionEventKindSynchronization,
+			name:             state.Session.Name,
+			connected:        state.AlphaConnected && state.BetaConnected,
+			conflicted:       len(state.Conflicts) > 0 || state.ExcludedConflicts > 0,
+			successfulCycles: state.SuccessfulSynchronizationCycles,
+		}
+	}
+
+	// Success.
+	return nil
+}
+
+// watchSessionEvents polls the states of the project's Mutagen sessions and
+// invokes the specified consumer with events corresponding to session state
+// transitions. Events are attributed to the sidecar service and container
+// hosting each session. It tracks the project's sidecar containers across
+// recreations and tolerates their absence. It runs until the context is
+// cancelled (in which case it returns nil) or the consumer returns an error.
+func (l *Liaison) watchSessionEvents(ctx context.Context, projectName string, consumer func(api.Event) error) error {
+	// Set up daemon connection tracking and defer closure of any connection.
+	// All of a project's sidecar containers share the same daemon, so we track
+	// the connection using the first sidecar container.
+	var connectionSidecarID string
+	var daemonConnection *grpc.ClientConn
+	defer func() {
+		if daemonConnection != nil {
+			l.releaseDaemonConnection(daemonConnection)
+		}
+	}()
+
+	// Create a ticker to regulate polling.
+	ticker := time.NewTicker(sessionEventPollingInterval)
+	defer ticker.Stop()
+
+	// Poll in a loop.
+	var previous map[string]*sessionEventState
+	for {
+		// Identify the current sidecar containers. If the first sidecar
+		// container has changed, then close any existing daemon connection,
+		// since it may target a different daemon.
+		sidecars, err := l.sidecarContainers(ctx, projectName)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("unable to identify Mutagen Compose sidecar containers: %w", err)
+		}
+		var currentSidecarID string
+		if len(sidecars) > 0 {
+			currentSidecarID = sidecars[0].ID
+		}
+		if currentSidecarID != connectionSidecarID {
+			if daemonConnection != nil {
+				l.releaseDaemonConnection(daemonConnection)
+				daemonConnection = nil
+			}
+			connectionSidecarID = currentSidecarID
+		}
+
+		// Query session states. If no sidecar containers exist, then no
+		// sessions exist. If the daemon can't be reached or queried, then we
+		// skip this polling cycle rather than generating spurious events.
+		current := make(map[string]*sessionEventState)
+		queried := true
+		if len(sidecars) > 0 {
+			if daemonConnection == nil {
+				if c, err := l.connectToDaemonForSidecar(ctx, connectionSidecarID); err == nil {
+					daemonConnection = c
+				}
+			}
+			if daemonConnection == nil {
+				queried = false
+			} else {
+				for _, sidecar := range sidecars {
+					if err := querySessionEventStates(ctx, daemonConnection, sidecar, current); err != nil {
+						l.releaseDaemonConnection(daemonConnection)
+						daemonConnection = nil
+						queried = false
+						break
+					}
+				}
+			}
+		}
+
+		// Generate events for state transitions. We don't generate events for
+		// the initial set of sessions since those transitions didn't occur
+		// while watching.
+		if queried {
+			if previous != nil {
+				now := time.Now()
+				emit := func(identifier string, state *sessionEventState, statuses []string) error {
+					for _, status := range statuses {
+						if err := consumer(api.Event{
+							Timestamp: now,
+							Service:   state.service,
+							Container: state.sidecarID,
+							Status:    status,
+							Attributes: map[string]string{
+								"session":    state.name,
+								"identifier": identifier,
+								"kind":       state.kind,
+							},
+						}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}
+				for identifier, state := range current {
+					if err := emit(identifier, state, sessionEventStatuses(previous[identifier], state)); err != nil {
+						return err
+					}
+				}
+				for identifier, state := range previous {
+					if _, ok := current[identifier]; !ok {
+						if err := emit(identifier, state, sessionEventStatuses(state, nil)); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			previous = current
+		}
+
+		// Wait for the next polling cycle.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package mutagen
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// disableEnvironmentVariable is the environment variable that can be used to
+// disable Mutagen support (equivalent to specifying the --no-mutagen flag).
+const disableEnvironmentVariable = "MUTAGEN_COMPOSE_DISABLE"
+
+// daemonTimeoutEnvironmentVariable is the environment variable that can be used
+// to specify the Mutagen daemon connection timeout (equivalent to specifying
+// the --mutagen-daemon-timeout flag). It accepts either a duration (e.g. "10s")
+// or a number of seconds.
+const daemonTimeoutEnvironmentVariable = "MUTAGEN_COMPOSE_DAEMON_TIMEOUT"
+
+// Flags stores top-level Mutagen Compose flags. Unlike top-level Docker and
+// Compose flags, these flags are consumed by Mutagen Compose itself and aren't
+// reconstituted for the underlying Compose invocation. They are registered both
+// with the top-level parser and with the command hierarchy used for help and
+// usage information, so they need only be declared here.
+type Flags struct {
+	// quiet indicates the presence of the --mutagen-quiet flag.
+	quiet bool
+	// disabled indicates the presence of the --no-mutagen flag.
+	disabled bool
+	// daemonTimeout stores the value of the --mutagen-daemon-timeout flag.
+	daemonTimeout time.Duration
+}
+
+// Register registers the flags into the specified flag set.
+func (f *Flags) Register(flags *pflag.FlagSet) {
+	flags.BoolVar(&f.quiet, "mutagen-quiet", false, "Suppress Mutagen session status output (errors are still shown)")
+	flags.BoolVar(&f.disabled, "no-mutagen", false, "Disable Mutagen support and behave like Compose (also "+disableEnvironmentVariable+"=1)")
+	flags.DurationVar(&f.daemonTimeout, "mutagen-daemon-timeout", 0, "Timeout for connecting to the Mutagen daemon (default 30s, also "+daemonTimeoutEnvironmentVariable+")")
+}
+
+// ApplyFlags configures the liaison using the specified top-level flags. Flags
+// can only enable behavior, so any user-level defaults remain in effect for
+// flags that weren't specified. Mutagen support is also disabled if the
+// MUTAGEN_COMPOSE_DISABLE environment variable is set to a true value. Both
+// settings are read only once, here, and gate all of the liaison's hooks. The
+// Mutagen daemon connection timeout is taken from the --mutagen-daemon-timeout
+// flag, falling back to the MUTAGEN_COMPOSE_DAEMON_TIMEOUT environment variable
+// and then to a default of 30 seconds. Non-positive values are ignored, and a
+// warning is logged if the environment variable is invalid or non-positive.
+func (l *Liaison) ApplyFlags(flags *Flags) {
+	if flags.quiet {
+		l.quiet = true
+	}
+	if flags.disabled {
+		l.disabled = true
+	} else if disabled, err := strconv.ParseBool(os.Getenv(disableEnvironmentVariable)); err == nil && disabled {
+		l.disabled = true
+	}
+	l.daemonTimeout = defaultDaemonTimeout
+	if flags.daemonTimeout > 0 {
+		l.daemonTimeout = flags.daemonTimeout
+	} else if value := os.Getenv(daemonTimeoutEnvironmentVariable); value != "" {
+		if timeout, err := parseDaemonTimeout(value); err != nil {
+			l.log().Warnf("ignoring invalid %s value (%s): %v", daemonTimeoutEnvironmentVariable, value, err)
+		} else if timeout <= 0 {
+			l.log().Warnf("ignoring non-positive %s value (%s)", daemonTimeoutEnvironmentVariable, value)
+		} else {
+			l.daemonTimeout = timeout
+		}
+	}
+}
+
+// parseDaemonTimeout parses a Mutagen daemon connection timeout specification,
+// which may be either a duration or a number of seconds.
+func parseDaemonTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// Disabled returns whether or not Mutagen support is disabled. If disabled, then
+// the liaison's Docker CLI and Compose service are the underlying
+// implementations, so project processing, sidecar injection, and daemon
+// connections are all bypassed.
+func (l *Liaison) Disabled() bool {
+	return l.disabled
+}
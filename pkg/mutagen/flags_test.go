@@ -0,0 +1,54 @@
+package mutagen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+// TestApplyFlagsDaemonTimeout tests the daemon connection timeout computed by
+// ApplyFlags and that invalid environment variable values are reported.
+func TestApplyFlagsDaemonTimeout(t *testing.T) {
+	testCases := []struct {
+		description     string
+		flag            time.Duration
+		environment     string
+		expected        time.Duration
+		expectedWarning bool
+	}{
+		{"default", 0, "", defaultDaemonTimeout, false},
+		{"flag", 5 * time.Second, "", 5 * time.Second, false},
+		{"flag overrides environment", 5 * time.Second, "10s", 5 * time.Second, false},
+		{"environment duration", 0, "1m", time.Minute, false},
+		{"environment seconds", 0, "10", 10 * time.Second, false},
+		{"invalid environment", 0, "30 seconds", defaultDaemonTimeout, true},
+		{"zero environment", 0, "0", defaultDaemonTimeout, true},
+		{"negative environment", 0, "-5s", defaultDaemonTimeout, true},
+	}
+	for _, testCase := range testCases {
+		// Configure the environment.
+		t.Setenv(daemonTimeoutEnvironmentVariable, testCase.environment)
+
+		// Apply the flags.
+		logger, hook := logtest.NewNullLogger()
+		liaison := &Liaison{}
+		liaison.SetLogger(logger)
+		liaison.ApplyFlags(&Flags{daemonTimeout: testCase.flag})
+
+		// Verify the results.
+		if liaison.daemonTimeout != testCase.expected {
+			t.Errorf("%s: timeout mismatch: %v != %v", testCase.description, liaison.daemonTimeout, testCase.expected)
+		}
+		warned := false
+		for _, entry := range hook.AllEntries() {
+			if entry.Level == logrus.WarnLevel {
+				warned = true
+			}
+		}
+		if warned != testCase.expectedWarning {
+			t.Errorf("%s: warning mismatch: %t != %t", testCase.description, warned, testCase.expectedWarning)
+		}
+	}
+}
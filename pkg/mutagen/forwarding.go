@@ -4,10 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"path"
+	"strconv"
 	"strings"
 
+	"github.com/compose-spec/compose-go/types"
+
 	"github.com/mutagen-io/mutagen/pkg/forwarding"
 	"github.com/mutagen-io/mutagen/pkg/grpcutil"
+	"github.com/mutagen-io/mutagen/pkg/mutagen"
 	"github.com/mutagen-io/mutagen/pkg/selection"
 	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
 	"github.com/mutagen-io/mutagen/pkg/url"
@@ -36,6 +42,70 @@ func isTCPForwardingProtocol(protocol string) bool {
 	}
 }
 
+// isUnixForwardingProtocol checks if a forwarding protocol is Unix domain
+// socket based.
+func isUnixForwardingProtocol(protocol string) bool {
+	return protocol == "unix"
+}
+
+// validateTCPForwardingAddress validates a TCP-based forwarding endpoint
+// address for the specified TCP-based protocol. The address must be of the form
+// host:port, where host may be empty (indicating all interfaces for listeners)
+// and port must be a valid port number. IPv6 literals must be enclosed in
+// brackets (e.g. [::1]:8080) and may include a zone (e.g. [fe80::1%eth0]:8080)
+// for link-local addresses. If the host is an IP literal, then its address
+// family must be compatible with the protocol (e.g. tcp4 can't be used with an
+// IPv6 literal). The host is not modified in any way, so any bind interface
+// specified for a listener (e.g. 127.0.0.1) is preserved exactly as written.
+func validateTCPForwardingAddress(protocol, address string) error {
+	// Split the address into host and port components. We provide a more
+	// helpful error message for the common case of an unbracketed IPv6
+	// literal, which would otherwise result in a "too many colons" error.
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		if strings.Count(address, ":") > 1 && !strings.HasPrefix(address, "[") {
+			return errors.New("IPv6 literals must be enclosed in brackets")
+		}
+		return fmt.Errorf("invalid address: %w", err)
+	}
+
+	// Validate the port.
+	if port == "" {
+		return errors.New("empty port")
+	} else if value, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port (%s)", port)
+	} else if value == 0 {
+		return errors.New("port must be non-zero")
+	}
+
+	// If the host is an IP literal, then ensure that its address family is
+	// compatible with the protocol. Zones are only valid for IPv6 literals.
+	ipHost := host
+	var zoned bool
+	if zoneIndex := strings.IndexByte(host, '%'); zoneIndex >= 0 {
+		ipHost = host[:zoneIndex]
+		zoned = true
+		if zoneIndex == len(host)-1 {
+			return errors.New("empty IPv6 zone")
+		}
+	}
+	if ip := net.ParseIP(ipHost); ip != nil {
+		isIPv4 := ip.To4() != nil && !strings.Contains(ipHost, ":")
+		if isIPv4 && zoned {
+			return errors.New("zones are only supported for IPv6 addresses")
+		} else if isIPv4 && protocol == "tcp6" {
+			return fmt.Errorf("IPv4 address (%s) incompatible with tcp6 protocol", host)
+		} else if !isIPv4 && protocol == "tcp4" {
+			return fmt.Errorf("IPv6 address (%s) incompatible with tcp4 protocol", host)
+		}
+	} else if zoned {
+		return fmt.Errorf("invalid IPv6 address (%s)", host)
+	}
+
+	// Success.
+	return nil
+}
+
 // parseNetworkURL parses a Docker Compose network pseudo-URL, enforces that its
 // forwarding endpoint protocol is TCP-based, and converts it to a sidecar
 // forwarding URL. This URL will only have kind, protocol, and path information
@@ -49,6 +119,8 @@ func parseNetworkURL(raw string) (*url.URL, string, error) {
 	raw = raw[len(networkURLPrefix):]
 
 	// Find the first colon, which will indicate the end of the network name.
+	// Network names can't contain colons, so any colons in the endpoint (such
+	// as those in a bracketed IPv6 literal) will be left intact.
 	var network, endpoint string
 	if colonIndex := strings.IndexByte(raw, ':'); colonIndex < 0 {
 		return nil, "", errors.New("unable to find forwarding endpoint specification")
@@ -61,10 +133,12 @@ func parseNetworkURL(raw string) (*url.URL, string, error) {
 
 	// Parse the forwarding endpoint URL to ensure that it's valid and supported
 	// for use with Docker Compose.
-	if protocol, _, err := forwardingurl.Parse(endpoint); err != nil {
+	if protocol, address, err := forwardingurl.Parse(endpoint); err != nil {
 		return nil, "", fmt.Errorf("invalid forwarding endpoint URL: %w", err)
 	} else if !isTCPForwardingProtocol(protocol) {
 		return nil, "", fmt.Errorf("non-TCP-based forwarding endpoint (%s) unsupported", endpoint)
+	} else if err = validateTCPForwardingAddress(protocol, address); err != nil {
+		return nil, "", fmt.Errorf("invalid forwarding endpoint address (%s): %w", address, err)
 	}
 
 	// Create a sidecar forwarding URL.
@@ -75,17 +149,186 @@ func parseNetworkURL(raw string) (*url.URL, string, error) {
 	}, network, nil
 }
 
+// networkHostResolvable determines whether or not a host name will be
+// resolvable (via Docker's embedded DNS server) on the specified project
+// network based on the services attached to that network. A host is considered
+// resolvable if it matches the name, container name, or a network alias of an
+// attached service, or if it matches the name of one of a service's replica
+// containers. Services disabled by profiles are included since they may be
+// enabled independently of the sidecar. Comparisons are case-insensitive, as
+// they are for DNS.
+func networkHostResolvable(project *types.Project, network, host string) bool {
+	return networkHostService(project, network, host) != ""
+}
+
+// networkHostService identifies the project service that a host name will
+// resolve to on the specified project network, using the same matching rules
+// as networkHostResolvable. It returns an empty string if the host doesn't
+// match any service.
+func networkHostService(project *types.Project, network, host string) string {
+	host = strings.ToLower(host)
+	services := make(types.Services, 0, len(project.Services)+len(project.DisabledServices))
+	services = append(services, project.Services...)
+	services = append(services, project.DisabledServices...)
+	for _, service := range services {
+		// Determine the service's configuration for the network. Services
+		// without any explicit network configuration (and without a custom
+		// network mode) are attached to the default network.
+		config, attached := service.Networks[network]
+		if len(service.Networks) == 0 && service.NetworkMode == "" {
+			attached = network == "default"
+		}
+		if !attached {
+			continue
+		}
+
+		// Check the service and container names.
+		if host == strings.ToLower(service.Name) || host == strings.ToLower(service.ContainerName) {
+			return service.Name
+		}
+
+		// Check for a replica container name of the form
+		// <project>-<service>-<index>.
+		replicaPrefix := strings.ToLower(project.Name + "-" + service.Name + "-")
+		if strings.HasPrefix(host, replicaPrefix) {
+			if _, err := strconv.ParseUint(host[len(replicaPrefix):], 10, 32); err == nil {
+				return service.Name
+			}
+		}
+
+		// Check network aliases.
+		if config != nil {
+			for _, alias := range config.Aliases {
+				if host == strings.ToLower(alias) {
+					return service.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// validateNetworkDestinationHost verifies that the host targeted by a network
+// forwarding endpoint will be resolvable on the specified project network. IP
+// literals, fully qualified (i.e. dotted) names, and localhost aren't
+// validated, since they may legitimately target resources outside the project.
+// Hosts on external networks aren't validated either, since those networks may
+// be shared with containers from other projects.
+func validateNetworkDestinationHost(project *types.Project, network, endpoint string) error {
+	// Extract the host from the endpoint. The endpoint will have already been
+	// validated by parseNetworkURL.
+	_, address, err := forwardingurl.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid forwarding endpoint URL: %w", err)
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid forwarding endpoint address: %w", err)
+	}
+
+	// Determine whether or not the host should be validated.
+	if host == "" || strings.ContainsAny(host, ".:%") || strings.EqualFold(host, "localhost") {
+		return nil
+	} else if config, ok := project.Networks[network]; !ok || config.External.External {
+		return nil
+	}
+
+	// Verify that the host will be resolvable.
+	if !networkHostResolvable(project, network, host) {
+		return fmt.Errorf("host (%s) doesn't match any service or alias on network (%s)", host, network)
+	}
+
+	// Success.
+	return nil
+}
+
+// networkDestinationService identifies the project service targeted by a
+// network forwarding endpoint on the specified project network. It returns an
+// error if the endpoint's host doesn't match a project service.
+func networkDestinationService(project *types.Project, network, endpoint string) (string, error) {
+	// Extract the host from the endpoint. The endpoint will have already been
+	// validated by parseNetworkURL.
+	_, address, err := forwardingurl.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid forwarding endpoint URL: %w", err)
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid forwarding endpoint address: %w", err)
+	}
+
+	// Identify the service.
+	if service := networkHostService(project, network, host); service != "" {
+		return service, nil
+	}
+	return "", fmt.Errorf("host (%s) doesn't match any service on network (%s)", host, network)
+}
+
+// parseForwardingVolumeURL parses a Docker Compose volume pseudo-URL used as a
+// forwarding destination, enforces that its forwarding endpoint protocol is
+// Unix domain socket based, and converts it to a sidecar forwarding URL. The
+// URL must be of the form volume://<volume>:unix:<path>, where the socket path
+// is interpreted relative to the root of the volume. As with parseNetworkURL,
+// the resulting URL will only have kind, protocol, and path information set.
+// This function also returns the volume dependency for the URL. This function
+// must only be called on URLs that have been classified as volume URLs by
+// isVolumeURL, otherwise it may panic.
+func parseForwardingVolumeURL(raw, platform string) (*url.URL, string, error) {
+	// Strip off the prefix
+	raw = raw[len(volumeURLPrefix):]
+
+	// Find the first colon, which will indicate the end of the volume name.
+	var volume, endpoint string
+	if colonIndex := strings.IndexByte(raw, ':'); colonIndex < 0 {
+		return nil, "", errors.New("unable to find forwarding endpoint specification")
+	} else if colonIndex == 0 {
+		return nil, "", errors.New("empty volume name")
+	} else {
+		volume = raw[:colonIndex]
+		endpoint = raw[colonIndex+1:]
+	}
+
+	// Parse the forwarding endpoint URL to ensure that it's valid and supported
+	// for use with Docker Compose.
+	protocol, address, err := forwardingurl.Parse(endpoint)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid forwarding endpoint URL: %w", err)
+	} else if !isUnixForwardingProtocol(protocol) {
+		return nil, "", fmt.Errorf("non-Unix-domain-socket forwarding endpoint (%s) unsupported for volumes", endpoint)
+	} else if platform != "linux" {
+		return nil, "", errors.New("Unix domain socket forwarding only supported for Linux containers")
+	}
+
+	// Compute the socket path within the Mutagen container.
+	socket := path.Clean("/" + address)
+	if socket == "/" {
+		return nil, "", errors.New("empty socket path")
+	}
+	socket = mountPathForVolumeInMutagenContainer(platform, volume) + socket
+
+	// Create a sidecar forwarding URL.
+	return &url.URL{
+		Kind:     url.Kind_Forwarding,
+		Protocol: sidecarURLProtocol,
+		Path:     protocol + ":" + socket,
+	}, volume, nil
+}
+
 // forwardingSessionCurrent determines whether or not an existing forwarding
-// session is equivalent to the specification for its creation.
+// session is equivalent to the specification for its creation. Sessions created
+// by a different version of Mutagen (as recorded by their version label) are
+// never considered current.
 func forwardingSessionCurrent(
 	session *forwarding.Session,
 	specification *forwardingsvc.CreationSpecification,
 ) bool {
-	return session.Source.Equal(specification.Source) &&
+	return session.Labels[sessionVersionLabelKey] == mutagen.Version &&
+		session.Source.Equal(specification.Source) &&
 		session.Destination.Equal(specification.Destination) &&
 		session.Configuration.Equal(specification.Configuration) &&
 		session.ConfigurationSource.Equal(specification.ConfigurationSource) &&
-		session.ConfigurationDestination.Equal(specification.ConfigurationDestination)
+		session.ConfigurationDestination.Equal(specification.ConfigurationDestination) &&
+		sessionLabelsEqual(session.Labels, specification.Labels)
 }
 
 // forwardingCreateWithSpecification creates a forwarding session using the
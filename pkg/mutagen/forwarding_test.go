@@ -0,0 +1,80 @@
+package mutagen
+
+import (
+	"testing"
+)
+
+// TestValidateTCPForwardingAddress tests validateTCPForwardingAddress.
+func TestValidateTCPForwardingAddress(t *testing.T) {
+	testCases := []struct {
+		protocol string
+		address  string
+		expected bool
+	}{
+		{"tcp", "127.0.0.1:8080", true},
+		{"tcp", ":8080", true},
+		{"tcp", "localhost:8080", true},
+		{"tcp", "[::1]:8080", true},
+		{"tcp6", "[::1]:8080", true},
+		{"tcp4", "[::1]:8080", false},
+		{"tcp", "[fe80::1%eth0]:8080", true},
+		{"tcp6", "[fe80::1%eth0]:8080", true},
+		{"tcp4", "[fe80::1%eth0]:8080", false},
+		{"tcp", "[fe80::1%]:8080", false},
+		{"tcp", "127.0.0.1%eth0:8080", false},
+		{"tcp", "localhost%eth0:8080", false},
+		{"tcp6", "127.0.0.1:8080", false},
+		{"tcp", "::1:8080", false},
+		{"tcp", "[::1]", false},
+		{"tcp", "[::1]:", false},
+		{"tcp", "[::1]:0", false},
+		{"tcp", "[::1]:65536", false},
+	}
+	for _, testCase := range testCases {
+		err := validateTCPForwardingAddress(testCase.protocol, testCase.address)
+		if valid := err == nil; valid != testCase.expected {
+			t.Errorf("%s:%s: validity mismatch: %t != %t (error: %v)",
+				testCase.protocol, testCase.address, valid, testCase.expected, err,
+			)
+		}
+	}
+}
+
+// TestParseNetworkURL tests parseNetworkURL.
+func TestParseNetworkURL(t *testing.T) {
+	testCases := []struct {
+		raw             string
+		expectedPath    string
+		expectedNetwork string
+		expectedSuccess bool
+	}{
+		{"network://default:tcp:database:5432", "tcp:database:5432", "default", true},
+		{"network://default:tcp:[::1]:8080", "tcp:[::1]:8080", "default", true},
+		{"network://backend:tcp6:[::1]:8080", "tcp6:[::1]:8080", "backend", true},
+		{"network://default:tcp:[fe80::1%eth0]:8080", "tcp:[fe80::1%eth0]:8080", "default", true},
+		{"network://default:tcp6:[fe80::1%eth0]:8080", "tcp6:[fe80::1%eth0]:8080", "default", true},
+		{"network://default:tcp4:[::1]:8080", "", "", false},
+		{"network://default:tcp:::1:8080", "", "", false},
+		{"network://default:unix:/socket", "", "", false},
+		{"network://:tcp:[::1]:8080", "", "", false},
+		{"network://default", "", "", false},
+	}
+	for _, testCase := range testCases {
+		target, network, err := parseNetworkURL(testCase.raw)
+		if !testCase.expectedSuccess {
+			if err == nil {
+				t.Errorf("%s: parsing succeeded unexpectedly", testCase.raw)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unable to parse: %v", testCase.raw, err)
+			continue
+		}
+		if target.Path != testCase.expectedPath {
+			t.Errorf("%s: path mismatch: %s != %s", testCase.raw, target.Path, testCase.expectedPath)
+		}
+		if network != testCase.expectedNetwork {
+			t.Errorf("%s: network mismatch: %s != %s", testCase.raw, network, testCase.expectedNetwork)
+		}
+	}
+}
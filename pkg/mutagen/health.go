@@ -0,0 +1,359 @@
+package mutagen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	moby "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+
+	"github.com/docker/compose/v2/pkg/api"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mutagen-io/mutagen/pkg/selection"
+	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
+	promptingsvc "github.com/mutagen-io/mutagen/pkg/service/prompting"
+)
+
+// serviceHealthTimeout is the maximum amount of time to wait for a service to
+// become healthy before creating forwarding sessions gated on its health.
+const serviceHealthTimeout = 2 * time.Minute
+
+// healthGates tracks forwarding sessions whose creation is gated on the health
+// of their destination services.
+type healthGates struct {
+	// services maps the names of gated forwarding sessions to the names of
+	// their destination services.
+	services map[string]string
+	// lock serializes access to scheduled.
+	lock sync.Mutex
+	// scheduled is the set of destination services for which gated session
+	// creation is currently pending.
+	scheduled map[string]bool
+	// pending tracks outstanding gated session creation operations.
+	pending sync.WaitGroup
+}
+
+// serviceHealth queries the health of the running containers of the specified
+// project service. Containers without health checks are considered healthy.
+// It returns false for running if the service has no running containers. If
+// any of the service's containers are reported as unhealthy, then an error is
+// returned.
+func (l *Liaison) serviceHealth(ctx context.Context, projectName, service string) (running, healthy bool, err error) {
+	// Query the service's running containers.
+	containers, err := l.dockerCLI.Client().ContainerList(ctx, moby.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", fmt.Sprintf("%s=%s", api.ProjectLabel, projectName)),
+			filters.Arg("label", fmt.Sprintf("%s=%s", api.ServiceLabel, service)),
+			filters.Arg("label", fmt.Sprintf("%s=%s", api.OneoffLabel, "False")),
+		),
+	})
+	if err != nil {
+		return false, false, fmt.Errorf("unable to query service containers: %w", err)
+	} else if len(containers) == 0 {
+		return false, false, nil
+	}
+
+	// Check the health of each container.
+	healthy = true
+	for _, container := range containers {
+		metadata, err := l.dockerCLI.Client().ContainerInspect(ctx, container.ID)
+		if err != nil {
+			return false, false, fmt.Errorf("unable to inspect service container: %w", err)
+		} else if metadata.State == nil || metadata.State.Health == nil {
+			continue
+		} else if metadata.State.Health.Status == moby.Unhealthy {
+			return false, false, fmt.Errorf("service (%s) is unhealthy", service)
+		} else if metadata.State.Health.Status != moby.Healthy {
+			healthy = false
+		}
+	}
+	return true, healthy, nil
+}
+
+// waitForServiceHealthy waits for the running containers of the specified
+// project service to become healthy (as determined by serviceHealth). If the
+// service has no running containers, then it returns false without waiting.
+// If the service's containers fail to become healthy before
+// serviceHealthTimeout elapses, or if any of them are reported as unhealthy,
+// then an error is returned.
+func (l *Liaison) waitForServiceHealthy(ctx context.Context, projectName, service string) (bool, error) {
+	// Create a timeout context and defer its cancellation.
+	ctx, cancel := context.WithTimeout(ctx, serviceHealthTimeout)
+	defer cancel()
+
+	// Create a ticker to regulate polling.
+	ticker := time.NewTicker(sessionWaitPollingInterval)
+	defer ticker.Stop()
+
+	// Poll until all of the service's containers are healthy.
+	for {
+		// Check the service's health.
+		if running, healthy, err := l.serviceHealth(ctx, projectName, service); err != nil {
+			return false, err
+		} else if !running {
+			return false, nil
+		} else if healthy {
+			return true, nil
+		}
+
+		// Wait for the next polling cycle.
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return false, fmt.Errorf("service (%s) failed to become healthy", service)
+			}
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// deferredForwardingSessions determines which of the specified forwarding
+// sessions should have their creation deferred because they're gated on the
+// health of a destination service that isn't yet healthy. It doesn't wait for
+// any service to become healthy; instead, for gated sessions whose destination
+// service is running, it schedules their creation once the service becomes
+// healthy (see scheduleHealthGatedSessions).
+func (l *Liaison) deferredForwardingSessions(ctx context.Context, projectName string, names []string) map[string]bool {
+	deferred := make(map[string]bool)
+	healthy := make(map[string]bool)
+	for _, name := range names {
+		service, gated := l.forwardingHealthGates.services[name]
+		if !gated || healthy[service] {
+			continue
+		}
+		// An unhealthy service is treated as running so that the failure is
+		// reported by the scheduled operation.
+		running, serviceHealthy, err := l.serviceHealth(ctx, projectName, service)
+		if err != nil {
+			running = true
+		} else if serviceHealthy {
+			healthy[service] = true
+			continue
+		}
+		deferred[name] = true
+		if running {
+			l.scheduleHealthGatedSessions(projectName, service)
+		}
+	}
+	return deferred
+}
+
+// startHealthGatedSessions schedules the creation of forwarding sessions gated
+// on the health of the service associated with the specified container, which
+// has just been started. It doesn't block or fail the container's start; any
+// failure is logged as a warning. It's a no-op if the container isn't
+// associated with such a service.
+func (l *Liaison) startHealthGatedSessions(ctx context.Context, container string) {
+	// If no sessions are gated, then there's nothing to do.
+	if len(l.forwardingHealthGates.services) == 0 {
+		return
+	}
+
+	// Identify the container's project and service.
+	metadata, err := l.dockerCLI.Client().ContainerInspect(ctx, container)
+	if err != nil {
+		l.log().Warnf("unable to inspect container for health-gated forwarding sessions: %v", err)
+		return
+	} else if metadata.Config == nil {
+		return
+	}
+
+	// Schedule creation of the gated sessions.
+	l.scheduleHealthGatedSessions(
+		metadata.Config.Labels[api.ProjectLabel],
+		metadata.Config.Labels[api.ServiceLabel],
+	)
+}
+
+// scheduleHealthGatedSessions asynchronously waits for the specified project
+// service to become healthy and then creates the forwarding sessions gated on
+// its health. Any failure is logged as a warning. It's a no-op if no sessions
+// are gated on the service or if their creation is already scheduled. Callers
+// can wait for scheduled operations to complete using
+// waitForHealthGatedSessions.
+func (l *Liaison) scheduleHealthGatedSessions(projectName, service string) {
+	// Identify the sessions gated on the service.
+	var names []string
+	for name, target := range l.forwardingHealthGates.services {
+		if target == service {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	// Register the operation, unless one is already pending for the service.
+	gates := &l.forwardingHealthGates
+	gates.lock.Lock()
+	if gates.scheduled[service] {
+		gates.lock.Unlock()
+		return
+	}
+	if gates.scheduled == nil {
+		gates.scheduled = make(map[string]bool)
+	}
+	gates.scheduled[service] = true
+	gates.pending.Add(1)
+	gates.lock.Unlock()
+
+	// Perform creation in the background.
+	go func() {
+		defer func() {
+			gates.lock.Lock()
+			delete(gates.scheduled, service)
+			gates.lock.Unlock()
+			gates.pending.Done()
+		}()
+		if err := l.createHealthGatedSessions(projectName, service, names); err != nil {
+			l.log().Warnf("unable to create forwarding sessions gated on service \"%s\": %v", service, err)
+		}
+	}()
+}
+
+// waitForHealthGatedSessions waits for any scheduled health-gated forwarding
+// session creation operations to complete.
+func (l *Liaison) waitForHealthGatedSessions() {
+	l.forwardingHealthGates.pending.Wait()
+}
+
+// createHealthGatedSessions waits for the specified project service to become
+// healthy and then creates any of the named forwarding sessions that don't
+// already exist. Unlike reconciliation, it doesn't prune or flush any sessions.
+// If the service stops running before it becomes healthy, then creation is
+// skipped, since it will be rescheduled when the service is started again.
+func (l *Liaison) createHealthGatedSessions(projectName, service string, names []string) error {
+	// Wait for the service to become healthy.
+	ctx := context.Background()
+	if running, err := l.waitForServiceHealthy(ctx, projectName, service); err != nil {
+		return err
+	} else if !running {
+		return nil
+	}
+
+	// Group the sessions by the sidecar group hosting them.
+	groups := make(map[string][]string)
+	for _, name := range names {
+		group := l.forwardingGroups[name]
+		groups[group] = append(groups[group], name)
+	}
+
+	// Create the sessions for each sidecar container that's running.
+	for _, group := range sortedNames(groups) {
+		sidecarID, err := l.resolveSidecarID(ctx, projectName, group)
+		var notFound *SidecarNotFoundError
+		if errors.As(err, &notFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if metadata, err := l.dockerCLI.Client().ContainerInspect(ctx, sidecarID); err != nil {
+			return fmt.Errorf("unable to inspect Mutagen Compose sidecar container: %w", err)
+		} else if metadata.State == nil || !metadata.State.Running || metadata.State.Paused {
+			continue
+		}
+		if err := l.createSidecarForwardingSessions(ctx, sidecarID, projectName, groups[group]); err != nil {
+			return err
+		}
+	}
+
+	// Success.
+	return nil
+}
+
+// createSidecarForwardingSessions creates any of the named forwarding sessions
+// that don't already exist for the specified sidecar container.
+func (l *Liaison) createSidecarForwardingSessions(ctx context.Context, sidecarID, projectName string, names []string) error {
+	// Connect to the Mutagen daemon and defer closure of the connection.
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
+	if err != nil {
+		return fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
+	}
+	defer l.releaseDaemonConnection(daemonConnection)
+
+	// Initiate message-only prompting and defer its termination. There's no
+	// Compose operation to report progress through, so messages are discarded.
+	status := newStatusUpdater(ctx, "Mutagen", true)
+	promptingCtx, promptingCancel := context.WithCancel(ctx)
+	prompter, promptingErrors, err := promptingsvc.Host(
+		promptingCtx, promptingsvc.NewPromptingClient(daemonConnection),
+		status, false,
+	)
+	defer func() {
+		promptingCancel()
+		<-promptingErrors
+	}()
+	if err != nil {
+		return fmt.Errorf("unable to initiate Mutagen prompting: %w", err)
+	}
+
+	// Create the session selection criteria.
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		return err
+	}
+
+	// Create the session specifications. We copy the project's specifications
+	// since they're shared with reconciliation.
+	specifications := make(map[string]*forwardingsvc.CreationSpecification, len(names))
+	for _, name := range names {
+		specification := proto.Clone(l.forwarding[name]).(*forwardingsvc.CreationSpecification)
+		reifySidecarURLIfNecessary(specification.Source, l.dockerFlags, l.dockerCLI, sidecarID)
+		reifySidecarURLIfNecessary(specification.Destination, l.dockerFlags, l.dockerCLI, sidecarID)
+		specification.Labels = sidecarSessionLabels(specification.Labels, sidecarID, projectName)
+		specifications[name] = specification
+	}
+
+	// Create the sessions.
+	return createMissingForwardingSessions(
+		ctx, l.sidecarLogger(sidecarID, "health"),
+		newDaemonForwardingSessionService(daemonConnection),
+		prompter, projectSelection, specifications,
+	)
+}
+
+// createMissingForwardingSessions creates the specified forwarding sessions
+// using the specified session service, skipping any whose name matches an
+// existing session in projectSelection. Sessions are created in name order.
+func createMissingForwardingSessions(
+	ctx context.Context,
+	logger logrus.FieldLogger,
+	forwardingService forwardingSessionService,
+	prompter string,
+	projectSelection *selection.Selection,
+	specifications map[string]*forwardingsvc.CreationSpecification,
+) error {
+	// Identify existing sessions.
+	states, err := forwardingService.List(ctx, projectSelection)
+	if err != nil {
+		return fmt.Errorf("forwarding session listing failed: %w", err)
+	}
+	existing := make(map[string]bool, len(states))
+	for _, state := range states {
+		existing[state.Session.Name] = true
+	}
+
+	// Create missing sessions.
+	for _, name := range sortedNames(specifications) {
+		if existing[name] {
+			continue
+		}
+		if _, err := forwardingService.Create(ctx, prompter, specifications[name]); err != nil {
+			return fmt.Errorf("unable to create forwarding session (%s): %w", name, err)
+		}
+		logger.WithField("session", name).Info("Created health-gated forwarding session")
+	}
+
+	// Success.
+	return nil
+}
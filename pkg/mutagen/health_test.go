@@ -0,0 +1,52 @@
+package mutagen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mutagen-io/mutagen/pkg/forwarding"
+	"github.com/mutagen-io/mutagen/pkg/selection"
+	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
+)
+
+// TestCreateMissingForwardingSessions tests that health-gated session creation
+// creates only the missing sessions, in name order, without pruning or
+// resuming any existing sessions.
+func TestCreateMissingForwardingSessions(t *testing.T) {
+	// Create a fake session service with an existing session.
+	forwardingService := &fakeForwardingSessionService{
+		sessions: []*forwarding.Session{
+			testForwardingSession("f1", 0, testForwardingSpecification("cache", "tcp:localhost:6379")),
+		},
+	}
+
+	// Create the sessions.
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	err := createMissingForwardingSessions(
+		context.Background(), logger, forwardingService, "",
+		&selection.Selection{LabelSelector: sessionSidecarLabelKey + " == test"},
+		map[string]*forwardingsvc.CreationSpecification{
+			"web":      testForwardingSpecification("web", "tcp:localhost:8080"),
+			"cache":    testForwardingSpecification("cache", "tcp:localhost:6379"),
+			"database": testForwardingSpecification("database", "tcp:localhost:5432"),
+		},
+	)
+	if err != nil {
+		t.Fatal("session creation failed:", err)
+	}
+
+	// Verify the results.
+	expected := []string{"database", "web"}
+	if !stringsEqualOrdered(forwardingService.created, expected) {
+		t.Errorf("created sessions mismatch: %v != %v", forwardingService.created, expected)
+	}
+	if len(forwardingService.terminated) > 0 {
+		t.Error("sessions terminated unexpectedly:", forwardingService.terminated)
+	}
+	if forwardingService.resumed {
+		t.Error("sessions resumed unexpectedly")
+	}
+}
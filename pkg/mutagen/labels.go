@@ -1,15 +1,137 @@
 package mutagen
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mutagen-io/mutagen/pkg/mutagen"
+	"github.com/mutagen-io/mutagen/pkg/selection"
+)
+
 const (
+	// reservedSessionLabelPrefix is the prefix for Mutagen session label keys
+	// reserved for use by Mutagen Compose.
+	reservedSessionLabelPrefix = "io.mutagen.compose."
 	// sessionSidecarLabelKey is the name of the label applied to Mutagen
 	// sessions to identify their associated Mutagen Compose sidecar container.
-	sessionSidecarLabelKey = "io.mutagen.compose.sidecar"
+	sessionSidecarLabelKey = reservedSessionLabelPrefix + "sidecar"
+	// sessionProjectLabelKey is the name of the label applied to Mutagen
+	// sessions to identify their associated Compose project. Unlike the
+	// sidecar label, it remains meaningful after the sidecar container has
+	// been removed, which allows orphaned sessions to be attributed to their
+	// project.
+	sessionProjectLabelKey = reservedSessionLabelPrefix + "project"
+	// sessionVersionLabelKey is the name of the label applied to Mutagen
+	// sessions to record the version of Mutagen that created them. Sessions
+	// created by a different version of Mutagen are considered stale and are
+	// recreated during reconciliation.
+	sessionVersionLabelKey = reservedSessionLabelPrefix + "mutagen-version"
 )
 
+// ensureSessionNameValid verifies that a session name is valid for use as a
+// Mutagen session name. Mutagen allows arbitrary Unicode letters and numbers in
+// session names, but names that appear in label values or label selector
+// expressions must also be valid label values (which are restricted to a
+// limited ASCII character set and length), so we additionally require that.
+func ensureSessionNameValid(name string) error {
+	if err := selection.EnsureNameValid(name); err != nil {
+		return err
+	} else if err := selection.EnsureLabelValueValid(name); err != nil {
+		return fmt.Errorf("name isn't safe for use in label selectors: %w", err)
+	}
+	return nil
+}
+
+// ensureSessionLabelsValid verifies that user-defined session labels are valid
+// Mutagen labels and don't use reserved keys.
+func ensureSessionLabelsValid(labels map[string]string) error {
+	for key, value := range labels {
+		if strings.HasPrefix(key, reservedSessionLabelPrefix) {
+			return fmt.Errorf("label key (%s) uses reserved prefix (%s)", key, reservedSessionLabelPrefix)
+		} else if err := selection.EnsureLabelKeyValid(key); err != nil {
+			return fmt.Errorf("invalid label key (%s): %w", key, err)
+		} else if err := selection.EnsureLabelValueValid(value); err != nil {
+			return fmt.Errorf("invalid value for label (%s): %w", key, err)
+		}
+	}
+	return nil
+}
+
+// mergeSessionLabels merges user-defined session labels on top of default
+// labels. It returns nil if the result would be empty.
+func mergeSessionLabels(defaults, labels map[string]string) map[string]string {
+	if len(defaults) == 0 && len(labels) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(defaults)+len(labels))
+	for key, value := range defaults {
+		result[key] = value
+	}
+	for key, value := range labels {
+		result[key] = value
+	}
+	return result
+}
+
+// sidecarSessionLabels computes the labels to apply to a session hosted by the
+// specified sidecar container, combining the session's user-defined labels with
+// the reserved sidecar, project, and version labels. The project label is
+// omitted if the project name isn't a valid label value.
+func sidecarSessionLabels(labels map[string]string, sidecarID, projectName string) map[string]string {
+	result := make(map[string]string, len(labels)+3)
+	for key, value := range labels {
+		result[key] = value
+	}
+	result[sessionSidecarLabelKey] = chopSidecarIdentifier(sidecarID)
+	result[sessionVersionLabelKey] = mutagen.Version
+	if projectName != "" && selection.EnsureLabelValueValid(projectName) == nil {
+		result[sessionProjectLabelKey] = projectName
+	}
+	return result
+}
+
+// sessionLabelsEqual determines whether or not two sets of session labels are
+// equal.
+func sessionLabelsEqual(first, second map[string]string) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for key, value := range first {
+		if other, ok := second[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
 // chopSidecarIdentifier chops off the 128-bit prefix of a 256-bit sidecar
 // container identifier (encoded as a hex string) to make it fit into Mutagen
 // session label values (which are limited to 63 characters). The first 128 bits
-// of entropy should be more than sufficient to avoid collisions.
+// of entropy should be more than sufficient to avoid collisions. Identifiers
+// that are already short enough are returned as-is.
 func chopSidecarIdentifier(sidecarID string) string {
+	if len(sidecarID) <= 32 {
+		return sidecarID
+	}
 	return sidecarID[:32]
 }
+
+// sidecarSessionSelection creates selection criteria that match the sessions
+// hosted by the specified sidecar container. This should be used wherever such
+// a selection is required, rather than constructing label selectors manually.
+// Mutagen's label selector syntax doesn't support quoting or escaping values,
+// so the label value is validated instead, ensuring that an unexpected
+// identifier results in an error rather than a selector that silently matches
+// the wrong sessions.
+func sidecarSessionSelection(sidecarID string) (*selection.Selection, error) {
+	value := chopSidecarIdentifier(sidecarID)
+	if value == "" {
+		return nil, errors.New("empty sidecar container identifier")
+	} else if err := selection.EnsureLabelValueValid(value); err != nil {
+		return nil, fmt.Errorf("invalid sidecar container identifier (%s): %w", sidecarID, err)
+	}
+	return &selection.Selection{
+		LabelSelector: fmt.Sprintf("%s == %s", sessionSidecarLabelKey, value),
+	}, nil
+}
@@ -0,0 +1,71 @@
+package mutagen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mutagen-io/mutagen/pkg/selection"
+)
+
+// TestSidecarSessionSelection tests sidecarSessionSelection.
+func TestSidecarSessionSelection(t *testing.T) {
+	fullID := strings.Repeat("0123456789abcdef", 4)
+	testCases := []struct {
+		description     string
+		sidecarID       string
+		expectedValue   string
+		expectedSuccess bool
+	}{
+		{"empty identifier", "", "", false},
+		{"full identifier", fullID, fullID[:32], true},
+		{"33-character identifier", fullID[:33], fullID[:32], true},
+		{"32-character identifier", fullID[:32], fullID[:32], true},
+		{"short identifier", "sidecar", "sidecar", true},
+		{"single-character identifier", "a", "a", true},
+		{"invalid characters beyond chopped prefix", fullID[:32] + " != x", fullID[:32], true},
+		{"space", "sidecar id", "", false},
+		{"selector injection", "sidecar,other", "", false},
+		{"operator", "sidecar!=other", "", false},
+		{"leading dash", "-sidecar", "", false},
+		{"trailing dot", "sidecar.", "", false},
+		{"non-ASCII characters", "sidécar", "", false},
+		{"invalid 32-character identifier", strings.Repeat("!", 32), "", false},
+	}
+	for _, testCase := range testCases {
+		// Create the selection.
+		result, err := sidecarSessionSelection(testCase.sidecarID)
+		if !testCase.expectedSuccess {
+			if err == nil {
+				t.Errorf("%s: selection creation succeeded unexpectedly", testCase.description)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unable to create selection: %v", testCase.description, err)
+			continue
+		}
+
+		// Verify the selection.
+		expectedSelector := sessionSidecarLabelKey + " == " + testCase.expectedValue
+		if result.LabelSelector != expectedSelector {
+			t.Errorf("%s: selector mismatch: %s != %s", testCase.description, result.LabelSelector, expectedSelector)
+		}
+		if err := result.EnsureValid(); err != nil {
+			t.Errorf("%s: invalid selection: %v", testCase.description, err)
+			continue
+		}
+
+		// Verify that the selection matches sessions labeled for the sidecar
+		// container and no others.
+		selector, err := selection.ParseLabelSelector(result.LabelSelector)
+		if err != nil {
+			t.Errorf("%s: unable to parse selector: %v", testCase.description, err)
+			continue
+		}
+		if !selector.Matches(sidecarSessionLabels(nil, testCase.sidecarID, "test")) {
+			t.Errorf("%s: selector doesn't match sidecar session labels", testCase.description)
+		}
+		if selector.Matches(sidecarSessionLabels(nil, "other", "test")) {
+			t.Errorf("%s: selector matches other sidecar session labels", testCase.description)
+		}
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -16,15 +17,16 @@ import (
 	"github.com/compose-spec/compose-go/types"
 
 	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/compose/v2/pkg/progress"
 
-	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
 
-	"github.com/mutagen-io/mutagen/cmd/mutagen/daemon"
 	"github.com/mutagen-io/mutagen/cmd/mutagen/forward"
 	"github.com/mutagen-io/mutagen/cmd/mutagen/sync"
 
 	"github.com/mutagen-io/mutagen/pkg/forwarding"
 	"github.com/mutagen-io/mutagen/pkg/grpcutil"
+	"github.com/mutagen-io/mutagen/pkg/logging"
 	"github.com/mutagen-io/mutagen/pkg/mutagen"
 	"github.com/mutagen-io/mutagen/pkg/selection"
 	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
@@ -51,6 +53,12 @@ type Liaison struct {
 	// processedProject indicates whether or not a project has already been
 	// processed.
 	processedProject bool
+	// registeredProject is the project registered for operations whose Compose
+	// API doesn't provide a project. It may be nil.
+	registeredProject *types.Project
+	// reconciledSidecars records the identifiers of sidecar containers for
+	// which session reconciliation (or resumption) has been attempted.
+	reconciledSidecars map[string]bool
 	// mutagenService is the Mutagen Compose sidecar service definition. It is
 	// initialized by calling processProject.
 	mutagenService types.ServiceConfig
@@ -60,6 +68,90 @@ type Liaison struct {
 	// synchronization are the synchronization session specifications. This map
 	// is initialized by calling processProject.
 	synchronization map[string]*synchronizationsvc.CreationSpecification
+	// sidecarGroupServices are the Mutagen Compose sidecar service definitions
+	// for sidecar groups, in group order. They are initialized by calling
+	// processProject.
+	sidecarGroupServices types.Services
+	// sidecarFirst indicates whether or not the Mutagen Compose sidecar
+	// services should be placed before user-defined services when injected
+	// into service lists. It is initialized by calling processProject.
+	sidecarFirst bool
+	// forwardingGroups maps forwarding session names to their sidecar groups.
+	// This map is initialized by calling processProject.
+	forwardingGroups map[string]string
+	// synchronizationGroups maps synchronization session names to their
+	// sidecar groups. This map is initialized by calling processProject.
+	synchronizationGroups map[string]string
+	// forwardingHealthGates tracks forwarding sessions whose creation is gated
+	// on the health of their destination service. It is initialized by calling
+	// processProject.
+	forwardingHealthGates healthGates
+	// synchronizationDeferredFlush is the set of synchronization session names
+	// for which the flush after creation is disabled. This map is initialized
+	// by calling processProject.
+	synchronizationDeferredFlush map[string]bool
+	// isolatedDaemon indicates whether or not the project's sessions are
+	// managed by a project-scoped Mutagen daemon. It is initialized by calling
+	// processProject.
+	isolatedDaemon bool
+	// keepOrphanSessions indicates whether or not orphan sessions should be
+	// left untouched during reconciliation. It is initialized by calling
+	// processProject.
+	keepOrphanSessions bool
+	// includeSidecarLogs indicates whether or not the Mutagen Compose sidecar
+	// service should be included in log output when specific services are
+	// requested.
+	includeSidecarLogs bool
+	// sessionStateCallback is the callback to invoke with session states during
+	// reconciliation. It may be nil.
+	sessionStateCallback SessionStateCallback
+	// finalFlushEnabled indicates whether or not synchronization sessions
+	// should be flushed before stopping the sidecar. It's only enabled for
+	// teardown operations (such as down and stop), since other operations (such
+	// as up) may stop the sidecar only to restart it.
+	finalFlushEnabled bool
+	// quiet indicates whether or not Mutagen session status output should be
+	// suppressed. Errors are still reported.
+	quiet bool
+	// daemonArchitecture is the normalized architecture of the Docker daemon.
+	// It is initialized by calling processProject.
+	daemonArchitecture string
+	// sidecarPullPolicy is the command line override for the sidecar image pull
+	// policy. If empty, then the project configuration is used.
+	sidecarPullPolicy string
+	// sidecarRegistryAuthentication is the registry authentication
+	// configuration for the sidecar image, if any. It is initialized by calling
+	// processProject.
+	sidecarRegistryAuthentication *registryAuthConfiguration
+	// skipCachedSidecarPull indicates whether or not pull operations should skip
+	// sidecar images that are already present on the Docker daemon.
+	skipCachedSidecarPull bool
+	// userConfiguration is the user-level Mutagen Compose configuration. It
+	// may be nil if no user configuration has been loaded.
+	userConfiguration *userConfiguration
+	// waitForSessions indicates whether or not reconciliation should wait for
+	// sessions to reach a steady state before completing.
+	waitForSessions bool
+	// initialSynchronizationIncomplete indicates that reconciliation created
+	// sessions but failed to complete their initial synchronization (i.e. that
+	// flushing or waiting for sessions failed without being interrupted).
+	initialSynchronizationIncomplete bool
+	// logger is the logger used by the liaison. If nil, then the standard
+	// logger is used.
+	logger logrus.FieldLogger
+	// reuseDaemonConnections indicates whether or not daemon connections should
+	// be held for reuse across operations.
+	reuseDaemonConnections bool
+	// daemonConnections caches daemon connections held for reuse.
+	daemonConnections daemonConnectionPool
+	// daemonConnector is the registered daemon connector. If nil, then
+	// connectToDaemon is used.
+	daemonConnector DaemonConnector
+	// daemonTimeout is the maximum amount of time to wait for a Mutagen daemon
+	// connection to be established. If zero, then no timeout is applied.
+	daemonTimeout time.Duration
+	// disabled indicates whether or not Mutagen support is disabled.
+	disabled bool
 }
 
 // RegisterDockerCLI registers the associated Docker CLI instance.
@@ -72,6 +164,58 @@ func (l *Liaison) RegisterDockerFlags(flags *pflag.FlagSet) {
 	l.dockerFlags = flags
 }
 
+// RegisterSessionStateCallback registers a callback to be invoked with session
+// states during reconciliation. The callback is invoked with the states of the
+// project's sessions before any reconciliation actions are taken and again once
+// reconciliation (including the flushing of newly created synchronization
+// sessions) is complete. Only one callback may be registered at a time.
+func (l *Liaison) RegisterSessionStateCallback(callback SessionStateCallback) {
+	l.sessionStateCallback = callback
+}
+
+// RegisterProject registers the project for use by operations (such as start)
+// whose Compose API only provides a project name. Without a registered project,
+// these operations can't reconcile Mutagen sessions, so they only resume
+// existing sessions.
+func (l *Liaison) RegisterProject(project *types.Project) {
+	l.registeredProject = project
+}
+
+// SetSidecarLogsIncluded sets whether or not logs from the Mutagen Compose
+// sidecar service should be included when logs are requested for specific
+// services. Sidecar logs are always included if no services are specified.
+func (l *Liaison) SetSidecarLogsIncluded(included bool) {
+	l.includeSidecarLogs = included
+}
+
+// SetSidecarPullPolicy sets the pull policy for the Mutagen Compose sidecar
+// image, overriding any policy specified in the project configuration. If policy
+// is empty, then the project configuration is used. The policy is validated
+// when the project is processed.
+func (l *Liaison) SetSidecarPullPolicy(policy string) {
+	l.sidecarPullPolicy = policy
+}
+
+// SetCachedSidecarPullSkipped sets whether or not pull operations should skip
+// pulling Mutagen Compose sidecar images that are already present on the
+// Docker daemon.
+func (l *Liaison) SetCachedSidecarPullSkipped(skipped bool) {
+	l.skipCachedSidecarPull = skipped
+}
+
+// SetFinalFlushEnabled sets whether or not synchronization sessions should be
+// flushed before the Mutagen Compose sidecar container is stopped. It should
+// only be enabled for teardown operations.
+func (l *Liaison) SetFinalFlushEnabled(enabled bool) {
+	l.finalFlushEnabled = enabled
+}
+
+// Shutdown releases resources held by the liaison, including any daemon
+// connections held for reuse.
+func (l *Liaison) Shutdown() error {
+	return l.daemonConnections.close()
+}
+
 // dockerCLI is a Mutagen-aware Docker CLI implementation.
 type dockerCLI struct {
 	// Cli is the underlying Docker CLI.
@@ -86,8 +230,12 @@ func (c *dockerCLI) Client() client.APIClient {
 }
 
 // DockerCLI returns a Mutagen-aware version of the Docker CLI. This method must
-// only be calld after the underlying CLI is registered via RegisterDockerCLI.
+// only be calld after the underlying CLI is registered via RegisterDockerCLI. If
+// Mutagen support is disabled, then the underlying CLI is returned.
 func (l *Liaison) DockerCLI() command.Cli {
+	if l.disabled {
+		return l.dockerCLI
+	}
 	return &dockerCLI{l.dockerCLI, l}
 }
 
@@ -100,14 +248,19 @@ func (l *Liaison) RegisterComposeService(service api.Service) {
 
 // ComposeService returns a Mutagen-aware version of the Compose Service API.
 // This function must only be called after a Compose service has been registered
-// with RegisterComposeService.
+// with RegisterComposeService. If Mutagen support is disabled, then the
+// underlying service is returned.
 func (l *Liaison) ComposeService() api.Service {
+	if l.disabled {
+		return l.composeService
+	}
 	return &composeService{l, l.composeService}
 }
 
-// processProject loads Mutagen configuration from the specified project, adds
-// the Mutagen Compose sidecar service to the project (as the last service), and
-// sets project dependencies accordingly. If project is nil, this method is a
+// processProject loads Mutagen configuration from the specified project and
+// computes the Mutagen Compose sidecar service definitions and session
+// specifications. It doesn't modify the project; callers that need the sidecar
+// services in the project use copies of it. If project is nil, this method is a
 // no-op and returns nil. This method must only be called after Docker flags
 // have been registered (via RegisterDockerFlags) and the associated Docker CLI
 // (registered via RegisterDockerCLI) can return a valid API client via its
@@ -128,18 +281,6 @@ func (l *Liaison) processProject(project *types.Project) error {
 		return nil
 	}
 
-	// Check for service name conflicts with explicitly-defined services.
-	for _, service := range project.Services {
-		if service.Name == sidecarServiceName {
-			return fmt.Errorf("user-defined service (%s) conflicts with Mutagen Compose sidecar service", sidecarServiceName)
-		}
-	}
-	for _, service := range project.DisabledServices {
-		if service.Name == sidecarServiceName {
-			return fmt.Errorf("disabled user-defined service (%s) conflicts with Mutagen Compose sidecar service", sidecarServiceName)
-		}
-	}
-
 	// Query daemon metadata.
 	daemonMetadata, err := l.dockerCLI.Client().Info(context.Background())
 	if err != nil {
@@ -153,23 +294,28 @@ func (l *Liaison) processProject(project *types.Project) error {
 	// the "down" operation, where, in the event that someone had deleted the
 	// x-mutagen extension section after running "up", the Mutagen sidecar
 	// service would be seen as an orphan container.
-	xMutagen := &configuration{}
-	if x, ok := project.Extensions["x-mutagen"]; ok {
-		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-			DecodeHook: mapstructure.ComposeDecodeHookFunc(
-				mapstructure.TextUnmarshallerHookFunc(),
-				boolToIgnoreVCSModeHookFunc(),
-			),
-			ErrorUnused: true,
-			Result:      xMutagen,
-			MatchName: func(mapKey, fieldName string) bool {
-				return mapKey == fieldName
-			},
-		})
-		if err != nil {
-			return fmt.Errorf("unable to create configuration decoder: %w", err)
-		} else if err = decoder.Decode(x); err != nil {
-			return fmt.Errorf("unable to decode x-mutagen section: %w", err)
+	xMutagen, err := decodeConfiguration(project)
+	if err != nil {
+		return err
+	}
+
+	// Determine the sidecar service name and check for conflicts with
+	// explicitly-defined services.
+	sidecarServiceName := defaultSidecarServiceName
+	if xMutagen.Sidecar.Name != "" {
+		if !isValidServiceName(xMutagen.Sidecar.Name) {
+			return fmt.Errorf("invalid sidecar service name: %s", xMutagen.Sidecar.Name)
+		}
+		sidecarServiceName = xMutagen.Sidecar.Name
+	}
+	for _, service := range project.Services {
+		if service.Name == sidecarServiceName {
+			return fmt.Errorf("user-defined service (%s) conflicts with Mutagen Compose sidecar service", sidecarServiceName)
+		}
+	}
+	for _, service := range project.DisabledServices {
+		if service.Name == sidecarServiceName {
+			return fmt.Errorf("disabled user-defined service (%s) conflicts with Mutagen Compose sidecar service", sidecarServiceName)
 		}
 	}
 
@@ -177,11 +323,16 @@ func (l *Liaison) processProject(project *types.Project) error {
 	defaultConfigurationForwarding := &forwarding.Configuration{}
 	defaultConfigurationSource := &forwarding.Configuration{}
 	defaultConfigurationDestination := &forwarding.Configuration{}
+	var defaultLabelsForwarding map[string]string
 	if defaults, ok := xMutagen.Forwarding["defaults"]; ok {
 		if defaults.Source != "" {
 			return errors.New("source URL not allowed in default forwarding configuration")
 		} else if defaults.Destination != "" {
 			return errors.New("destination URL not allowed in default forwarding configuration")
+		} else if defaults.SidecarGroup != "" {
+			return errors.New("sidecar group not allowed in default forwarding configuration")
+		} else if defaults.WaitForHealthy {
+			return errors.New("health gating not allowed in default forwarding configuration")
 		}
 		defaultConfigurationForwarding = defaults.Configuration.Configuration()
 		if err := defaultConfigurationForwarding.EnsureValid(false); err != nil {
@@ -195,6 +346,10 @@ func (l *Liaison) processProject(project *types.Project) error {
 		if err := defaultConfigurationDestination.EnsureValid(true); err != nil {
 			return fmt.Errorf("invalid default forwarding destination configuration: %w", err)
 		}
+		if err := ensureSessionLabelsValid(defaults.Labels); err != nil {
+			return fmt.Errorf("invalid default forwarding labels: %w", err)
+		}
+		defaultLabelsForwarding = defaults.Labels
 		delete(xMutagen.Forwarding, "defaults")
 	}
 
@@ -202,14 +357,22 @@ func (l *Liaison) processProject(project *types.Project) error {
 	defaultConfigurationSynchronization := &synchronization.Configuration{}
 	defaultConfigurationAlpha := &synchronization.Configuration{}
 	defaultConfigurationBeta := &synchronization.Configuration{}
+	var defaultLabelsSynchronization map[string]string
+	defaultFlushOnCreate := true
 	if defaults, ok := xMutagen.Synchronization["defaults"]; ok {
 		if defaults.Alpha != "" {
 			return errors.New("alpha URL not allowed in default synchronization configuration")
 		} else if defaults.Beta != "" {
 			return errors.New("beta URL not allowed in default synchronization configuration")
+		} else if defaults.SidecarGroup != "" {
+			return errors.New("sidecar group not allowed in default synchronization configuration")
+		} else if defaults.Count != 0 {
+			return errors.New("count not allowed in default synchronization configuration")
 		}
 		defaultConfigurationSynchronization = defaults.Configuration.Configuration()
-		if err := defaultConfigurationSynchronization.EnsureValid(false); err != nil {
+		if err := defaults.ConflictResolution.apply(defaultConfigurationSynchronization); err != nil {
+			return fmt.Errorf("invalid default synchronization configuration: %w", err)
+		} else if err := defaultConfigurationSynchronization.EnsureValid(false); err != nil {
 			return fmt.Errorf("invalid default synchronization configuration: %w", err)
 		}
 		defaultConfigurationAlpha = defaults.ConfigurationAlpha.Configuration()
@@ -220,19 +383,55 @@ func (l *Liaison) processProject(project *types.Project) error {
 		if err := defaultConfigurationBeta.EnsureValid(true); err != nil {
 			return fmt.Errorf("invalid default synchronization beta configuration: %w", err)
 		}
+		if err := ensureSessionLabelsValid(defaults.Labels); err != nil {
+			return fmt.Errorf("invalid default synchronization labels: %w", err)
+		}
+		defaultLabelsSynchronization = defaults.Labels
+		if defaults.FlushOnCreate != nil {
+			defaultFlushOnCreate = *defaults.FlushOnCreate
+		}
 		delete(xMutagen.Synchronization, "defaults")
 	}
 
+	// Expand templated synchronization sessions and sessions that use volume
+	// patterns. Counts are expanded first so that templates can generate
+	// volume patterns.
+	if err := expandSynchronizationCounts(xMutagen.Synchronization); err != nil {
+		return err
+	} else if err := expandSynchronizationVolumeGlobs(xMutagen.Synchronization, project.Volumes); err != nil {
+		return err
+	}
+
+	// Track the dependencies of each sidecar service, keyed by sidecar group.
+	// The primary sidecar service uses the empty group.
+	dependencies := map[string]*sidecarDependencies{"": newSidecarDependencies()}
+	dependenciesForGroup := func(group string) *sidecarDependencies {
+		if d, ok := dependencies[group]; ok {
+			return d
+		}
+		d := newSidecarDependencies()
+		dependencies[group] = d
+		return d
+	}
+
 	// Validate forwarding configurations, convert them to session creation
-	// specifications, and extract network dependencies for the Mutagen service.
+	// specifications, and extract network and volume dependencies for the
+	// Mutagen sidecar services.
 	forwardingSpecifications := make(map[string]*forwardingsvc.CreationSpecification)
-	networkDependencies := make(map[string]*types.ServiceNetworkConfig)
-	for name, session := range xMutagen.Forwarding {
+	forwardingGroups := make(map[string]string)
+	forwardingHealthGates := make(map[string]string)
+	for _, name := range sortedNames(xMutagen.Forwarding) {
+		session := xMutagen.Forwarding[name]
+
 		// Verify that the name is valid.
-		if err := selection.EnsureNameValid(name); err != nil {
+		if err := ensureSessionNameValid(name); err != nil {
 			return fmt.Errorf("invalid forwarding session name (%s): %w", name, err)
 		}
 
+		// Determine the sidecar dependencies for the session's group.
+		forwardingGroups[name] = session.SidecarGroup
+		sessionDependencies := dependenciesForGroup(session.SidecarGroup)
+
 		// Parse and validate the source URL. At the moment, we only allow local
 		// URLs as forwarding sources since this is the primary use case with
 		// Docker Compose. Supporting reverse forwarding is somewhat ill-defined
@@ -241,36 +440,74 @@ func (l *Liaison) processProject(project *types.Project) error {
 		// other protocols (such as SSH and Docker) since they're likely to be
 		// confusing and error-prone (especially raw Docker URLs referencing
 		// containers in this project that won't play nicely with container
-		// startup ordering). Finally, we only support TCP-based endpoints since
-		// they constitute the primary use case with Docker Compose and because
-		// other protocols would likely be error-prone and require
-		// project-relative path resolution.
+		// startup ordering). Finally, we only support TCP-based endpoints and
+		// (when explicitly requested) Unix domain socket endpoints, since they
+		// constitute the primary use cases with Docker Compose. The source
+		// address is recorded verbatim, so a listener bound to a specific
+		// interface (e.g. tcp:127.0.0.1:5432) remains bound to only that
+		// interface. Relative Unix domain socket paths are treated as relative
+		// to the project directory, so we have to override the default URL
+		// parsing behavior in that case.
 		if isNetworkURL(session.Source) {
 			return fmt.Errorf("network URL (%s) not allowed as forwarding source", session.Source)
+		} else if isVolumeURL(session.Source) {
+			return fmt.Errorf("volume URL (%s) not allowed as forwarding source", session.Source)
 		}
 		sourceURL, err := url.Parse(session.Source, url.Kind_Forwarding, true)
 		if err != nil {
 			return fmt.Errorf("unable to parse forwarding source URL (%s): %w", session.Source, err)
 		} else if sourceURL.Protocol != url.Protocol_Local {
 			return errors.New("only local URLs allowed as forwarding sources")
-		} else if protocol, _, err := forwardingurl.Parse(sourceURL.Path); err != nil {
+		} else if protocol, address, err := forwardingurl.Parse(session.Source); err != nil {
 			panic("forwarding URL failed to reparse")
+		} else if isUnixForwardingProtocol(protocol) {
+			if isProjectRelativePath(address) {
+				if address, err = resolveProjectRelativePath(project.WorkingDir, address); err != nil {
+					return fmt.Errorf("unable to resolve relative socket path (%s): %w", session.Source, err)
+				}
+				sourceURL.Path = protocol + ":" + address
+			}
 		} else if !isTCPForwardingProtocol(protocol) {
 			return fmt.Errorf("non-TCP-based forwarding endpoint (%s) unsupported", sourceURL.Path)
+		} else if err = validateTCPForwardingAddress(protocol, address); err != nil {
+			return fmt.Errorf("invalid forwarding source address (%s): %w", address, err)
 		}
 
 		// Parse and validate the destination URL. At the moment, we only allow
-		// network pseudo-URLs (with TCP-based endpoints) as forwarding
-		// destinations for the reasons outlined above. The parseNetworkURL will
-		// enforce that a TCP-based forwarding endpoint is used.
-		if !isNetworkURL(session.Destination) {
-			return fmt.Errorf("forwarding destination (%s) should be a network URL", session.Destination)
-		}
-		destinationURL, network, err := parseNetworkURL(session.Destination)
-		if err != nil {
-			return fmt.Errorf("unable to parse forwarding destination URL (%s): %w", session.Destination, err)
+		// network pseudo-URLs (with TCP-based endpoints) and volume pseudo-URLs
+		// (with Unix domain socket endpoints) as forwarding destinations for
+		// the reasons outlined above. The parseNetworkURL and
+		// parseForwardingVolumeURL functions will enforce that an appropriate
+		// forwarding endpoint is used.
+		var destinationURL *url.URL
+		if isNetworkURL(session.Destination) {
+			d, network, err := parseNetworkURL(session.Destination)
+			if err != nil {
+				return fmt.Errorf("unable to parse forwarding destination URL (%s): %w", session.Destination, err)
+			} else if err = validateNetworkDestinationHost(project, network, d.Path); err != nil {
+				return fmt.Errorf("invalid forwarding destination (%s): %w", session.Destination, err)
+			}
+			if session.WaitForHealthy {
+				service, err := networkDestinationService(project, network, d.Path)
+				if err != nil {
+					return fmt.Errorf("unable to determine destination service for forwarding session %s: %w", name, err)
+				}
+				forwardingHealthGates[name] = service
+			}
+			destinationURL = d
+			sessionDependencies.networks[network] = nil
+		} else if session.WaitForHealthy {
+			return fmt.Errorf("health gating for forwarding session %s requires a network destination", name)
+		} else if isVolumeURL(session.Destination) {
+			d, volume, err := parseForwardingVolumeURL(session.Destination, daemonMetadata.OSType)
+			if err != nil {
+				return fmt.Errorf("unable to parse forwarding destination URL (%s): %w", session.Destination, err)
+			}
+			destinationURL = d
+			sessionDependencies.volumes[volume] = true
+		} else {
+			return fmt.Errorf("forwarding destination (%s) should be a network or volume URL", session.Destination)
 		}
-		networkDependencies[network] = nil
 
 		// Compute the session configuration.
 		configuration := session.Configuration.Configuration()
@@ -293,6 +530,12 @@ func (l *Liaison) processProject(project *types.Project) error {
 		}
 		destinationConfiguration = forwarding.MergeConfigurations(defaultConfigurationDestination, destinationConfiguration)
 
+		// Validate and compute the labels.
+		if err := ensureSessionLabelsValid(session.Labels); err != nil {
+			return fmt.Errorf("invalid forwarding session labels for %s: %w", name, err)
+		}
+		labels := mergeSessionLabels(defaultLabelsForwarding, session.Labels)
+
 		// Record the specification.
 		forwardingSpecifications[name] = &forwardingsvc.CreationSpecification{
 			Source:                   sourceURL,
@@ -301,55 +544,87 @@ func (l *Liaison) processProject(project *types.Project) error {
 			ConfigurationSource:      sourceConfiguration,
 			ConfigurationDestination: destinationConfiguration,
 			Name:                     name,
+			Labels:                   labels,
 		}
 	}
 
 	// Validate synchronization configurations, convert them to session creation
-	// specifications, and extract volume dependencies for the Mutagen service.
+	// specifications, and extract volume and bind dependencies for the Mutagen
+	// sidecar services.
 	synchronizationSpecifications := make(map[string]*synchronizationsvc.CreationSpecification)
-	volumeDependencies := make(map[string]bool)
-	for name, session := range xMutagen.Synchronization {
+	synchronizationGroups := make(map[string]string)
+	synchronizationDeferredFlush := make(map[string]bool)
+	var nativelyWatchingSessions int
+	for _, name := range sortedNames(xMutagen.Synchronization) {
+		session := xMutagen.Synchronization[name]
+
 		// Verify that the name is valid.
-		if err := selection.EnsureNameValid(name); err != nil {
+		if err := ensureSessionNameValid(name); err != nil {
 			return fmt.Errorf("invalid synchronization session name (%s): %v", name, err)
 		}
 
-		// Enforce that exactly one of the session URLs is a volume URL. At the
-		// moment, we only support synchronization sessions where one of the
-		// URLs is local the other is a volume URL. We'll check that the
-		// non-volume URL is local when parsing. We could support other protocol
-		// combinations for synchronization (and we may in the future), but for
-		// now we're focused on supporting the primary Docker Compose use case
-		// and avoiding the confusing and error-prone cases described above.
-		alphaIsVolume := isVolumeURL(session.Alpha)
-		betaIsVolume := isVolumeURL(session.Beta)
-		if !(alphaIsVolume || betaIsVolume) {
-			return fmt.Errorf("neither alpha nor beta references a volume in synchronization session (%s)", name)
-		} else if alphaIsVolume && betaIsVolume {
-			return fmt.Errorf("both alpha and beta reference volumes in synchronization session (%s)", name)
+		// Determine the sidecar dependencies for the session's group.
+		synchronizationGroups[name] = session.SidecarGroup
+		sessionDependencies := dependenciesForGroup(session.SidecarGroup)
+
+		// Enforce that exactly one of the session URLs is a container-side
+		// (i.e. volume, external volume, or bind) URL. At the moment, we only
+		// support synchronization sessions where one of the URLs is local and
+		// the other is a container-side URL. We'll check that the other URL is
+		// local when parsing. We could support other protocol combinations for
+		// synchronization (and we may in the future), but for now we're focused
+		// on supporting the primary Docker Compose use case and avoiding the
+		// confusing and error-prone cases described above.
+		alphaIsVolume, alphaIsBind := isVolumeURL(session.Alpha), isBindURL(session.Alpha)
+		betaIsVolume, betaIsBind := isVolumeURL(session.Beta), isBindURL(session.Beta)
+		alphaIsExternalVolume := isExternalVolumeURL(session.Alpha)
+		betaIsExternalVolume := isExternalVolumeURL(session.Beta)
+		alphaIsContainerSide := alphaIsVolume || alphaIsExternalVolume || alphaIsBind
+		betaIsContainerSide := betaIsVolume || betaIsExternalVolume || betaIsBind
+		if !(alphaIsContainerSide || betaIsContainerSide) {
+			return fmt.Errorf("neither alpha nor beta references a volume or bind mount in synchronization session (%s)", name)
+		} else if alphaIsContainerSide && betaIsContainerSide {
+			return fmt.Errorf("both alpha and beta reference volumes or bind mounts in synchronization session (%s)", name)
 		}
 
 		// Parse and validate the alpha URL. If it isn't a volume URL, then it
 		// must be a local URL. In the case of a local URL, we treat relative
-		// paths as relative to the project directory, so we have to override
-		// the default URL parsing behavior in that case.
+		// paths as relative to the project directory (which reflects the
+		// --project-directory flag, if specified), so we have to override the
+		// default URL parsing behavior in that case. Home-relative paths are
+		// left as expanded by the default URL parsing behavior.
 		var alphaURL *url.URL
+		var alphaVolume, alphaExternalVolume, alphaBind string
 		if alphaIsVolume {
 			if a, volume, err := parseVolumeURL(session.Alpha, daemonMetadata.OSType); err != nil {
 				return fmt.Errorf("unable to parse synchronization alpha URL (%s): %w", session.Alpha, err)
 			} else {
 				alphaURL = a
-				volumeDependencies[volume] = true
+				alphaVolume = volume
+			}
+		} else if alphaIsExternalVolume {
+			if a, volume, err := parseExternalVolumeURL(session.Alpha, daemonMetadata.OSType); err != nil {
+				return fmt.Errorf("unable to parse synchronization alpha URL (%s): %w", session.Alpha, err)
+			} else {
+				alphaURL = a
+				alphaExternalVolume = volume
+			}
+		} else if alphaIsBind {
+			if a, hostPath, err := parseBindURL(session.Alpha, daemonMetadata.OSType); err != nil {
+				return fmt.Errorf("unable to parse synchronization alpha URL (%s): %w", session.Alpha, err)
+			} else {
+				alphaURL = a
+				alphaBind = hostPath
 			}
 		} else {
 			alphaURL, err = url.Parse(session.Alpha, url.Kind_Synchronization, true)
 			if err != nil {
 				return fmt.Errorf("unable to parse synchronization alpha URL (%s): %w", session.Alpha, err)
 			} else if alphaURL.Protocol != url.Protocol_Local {
-				return errors.New("only local and volume URLs allowed as synchronization URLs")
+				return errors.New("only local, volume, external volume, and bind URLs allowed as synchronization URLs")
 			}
-			if !filepath.IsAbs(session.Alpha) {
-				if alphaURL.Path, err = filepath.Abs(filepath.Join(project.WorkingDir, session.Alpha)); err != nil {
+			if isProjectRelativePath(session.Alpha) {
+				if alphaURL.Path, err = resolveProjectRelativePath(project.WorkingDir, session.Alpha); err != nil {
 					return fmt.Errorf("unable to resolve relative alpha URL (%s): %w", session.Alpha, err)
 				}
 			}
@@ -362,25 +637,43 @@ func (l *Liaison) processProject(project *types.Project) error {
 				return fmt.Errorf("unable to parse synchronization beta URL (%s): %w", session.Beta, err)
 			} else {
 				betaURL = b
-				volumeDependencies[volume] = true
+				sessionDependencies.volumes[volume] = true
+			}
+		} else if betaIsExternalVolume {
+			if b, volume, err := parseExternalVolumeURL(session.Beta, daemonMetadata.OSType); err != nil {
+				return fmt.Errorf("unable to parse synchronization beta URL (%s): %w", session.Beta, err)
+			} else {
+				betaURL = b
+				sessionDependencies.externalVolumes[volume] = true
+			}
+		} else if betaIsBind {
+			if b, hostPath, err := parseBindURL(session.Beta, daemonMetadata.OSType); err != nil {
+				return fmt.Errorf("unable to parse synchronization beta URL (%s): %w", session.Beta, err)
+			} else {
+				betaURL = b
+				sessionDependencies.binds[hostPath] = true
 			}
 		} else {
 			betaURL, err = url.Parse(session.Beta, url.Kind_Synchronization, false)
 			if err != nil {
 				return fmt.Errorf("unable to parse synchronization beta URL (%s): %w", session.Beta, err)
 			} else if betaURL.Protocol != url.Protocol_Local {
-				return errors.New("only local and volume URLs allowed as synchronization URLs")
+				return errors.New("only local, volume, external volume, and bind URLs allowed as synchronization URLs")
 			}
-			if !filepath.IsAbs(session.Beta) {
-				if betaURL.Path, err = filepath.Abs(filepath.Join(project.WorkingDir, session.Beta)); err != nil {
+			if isProjectRelativePath(session.Beta) {
+				if betaURL.Path, err = resolveProjectRelativePath(project.WorkingDir, session.Beta); err != nil {
 					return fmt.Errorf("unable to resolve relative beta URL (%s): %w", session.Beta, err)
 				}
 			}
 		}
 
-		// Compute the session configuration.
+		// Compute the session configuration. Any conflict resolution policy is
+		// applied before merging so that it takes precedence over the default
+		// synchronization mode.
 		configuration := session.Configuration.Configuration()
-		if err := configuration.EnsureValid(false); err != nil {
+		if err := session.ConflictResolution.apply(configuration); err != nil {
+			return fmt.Errorf("invalid synchronization session configuration for %s: %v", name, err)
+		} else if err := configuration.EnsureValid(false); err != nil {
 			return fmt.Errorf("invalid synchronization session configuration for %s: %v", name, err)
 		}
 		configuration = synchronization.MergeConfigurations(defaultConfigurationSynchronization, configuration)
@@ -399,6 +692,47 @@ func (l *Liaison) processProject(project *types.Project) error {
 		}
 		betaConfiguration = synchronization.MergeConfigurations(defaultConfigurationBeta, betaConfiguration)
 
+		// If alpha is a volume or bind mount, then determine whether or not the
+		// session requires write access to it. If the synchronization mode
+		// makes alpha a pure source (i.e. a one-way mode), then the session
+		// won't write to the mount. The mount will only be read-only if no
+		// other session requires write access to it.
+		requiresAlphaWrite := !isOneWaySynchronizationMode(configuration.SynchronizationMode)
+		if alphaIsVolume {
+			sessionDependencies.volumes[alphaVolume] = sessionDependencies.volumes[alphaVolume] || requiresAlphaWrite
+		} else if alphaIsExternalVolume {
+			sessionDependencies.externalVolumes[alphaExternalVolume] = sessionDependencies.externalVolumes[alphaExternalVolume] || requiresAlphaWrite
+		} else if alphaIsBind {
+			sessionDependencies.binds[alphaBind] = sessionDependencies.binds[alphaBind] || requiresAlphaWrite
+		}
+
+		// Track whether or not the container-side endpoint uses native
+		// filesystem watching. Endpoint-specific configuration takes
+		// precedence over session configuration.
+		containerSideConfiguration := betaConfiguration
+		if alphaIsContainerSide {
+			containerSideConfiguration = alphaConfiguration
+		}
+		if usesNativeWatching(synchronization.MergeConfigurations(configuration, containerSideConfiguration).WatchMode) {
+			nativelyWatchingSessions++
+		}
+
+		// Validate and compute the labels.
+		if err := ensureSessionLabelsValid(session.Labels); err != nil {
+			return fmt.Errorf("invalid synchronization session labels for %s: %w", name, err)
+		}
+		labels := mergeSessionLabels(defaultLabelsSynchronization, session.Labels)
+
+		// Determine whether or not the session's flush after creation should
+		// be deferred.
+		flushOnCreate := defaultFlushOnCreate
+		if session.FlushOnCreate != nil {
+			flushOnCreate = *session.FlushOnCreate
+		}
+		if !flushOnCreate {
+			synchronizationDeferredFlush[name] = true
+		}
+
 		// Record the specification.
 		synchronizationSpecifications[name] = &synchronizationsvc.CreationSpecification{
 			Alpha:              alphaURL,
@@ -407,35 +741,66 @@ func (l *Liaison) processProject(project *types.Project) error {
 			ConfigurationAlpha: alphaConfiguration,
 			ConfigurationBeta:  betaConfiguration,
 			Name:               name,
+			Labels:             labels,
 		}
 	}
 
+	// Warn if a large number of sessions will rely on native filesystem
+	// watching inside the sidecar container. On Linux, each watched directory
+	// consumes an inotify watch, and the per-user limit on the Docker host is
+	// shared by all containers, so exhausting it results in cryptic failures.
+	// This is only a guardrail, so we don't treat it as an error.
+	if daemonMetadata.OSType == "linux" && nativelyWatchingSessions > nativeWatchingSessionWarningThreshold {
+		l.log().WithField("sessions", nativelyWatchingSessions).Warnf("%d Mutagen synchronization sessions use native filesystem watching inside "+
+			"the Mutagen Compose sidecar container, which may exhaust inotify watches on the Docker host. "+
+			"Consider raising fs.inotify.max_user_watches on the Docker host or using the \"force-poll\" watch mode.",
+			nativelyWatchingSessions,
+		)
+	}
+
 	// Validate network and volume dependencies.
-	for network := range networkDependencies {
-		if _, ok := project.Networks[network]; !ok {
-			return fmt.Errorf("undefined network (%s) referenced by forwarding session", network)
+	for _, d := range dependencies {
+		for network := range d.networks {
+			if _, ok := project.Networks[network]; !ok {
+				return &UndefinedNetworkError{Network: network}
+			}
 		}
-	}
-	for volume := range volumeDependencies {
-		if _, ok := project.Volumes[volume]; !ok {
-			return fmt.Errorf("undefined volume (%s) referenced by synchronization session", volume)
+		for volume := range d.volumes {
+			if _, ok := project.Volumes[volume]; !ok {
+				return &UndefinedVolumeError{Volume: volume}
+			}
 		}
 	}
 
-	// Convert volume dependencies to the Compose format.
-	serviceVolumeDependencies := make([]types.ServiceVolumeConfig, 0, len(volumeDependencies))
-	for volume := range volumeDependencies {
-		serviceVolumeDependencies = append(serviceVolumeDependencies, types.ServiceVolumeConfig{
-			Type:   "volume",
-			Source: volume,
-			Target: mountPathForVolumeInMutagenContainer(daemonMetadata.OSType, volume),
-		})
+	// Validate external volume dependencies. External volumes are referenced by
+	// their Docker volume names and mounted at the same location as project
+	// volumes with the same key would be, so we disallow names that collide
+	// with project volume keys (which would also be remapped by Compose when
+	// mounting). Since Docker would automatically create a missing volume when
+	// mounting it, we also verify that each external volume exists.
+	for _, d := range dependencies {
+		for volume := range d.externalVolumes {
+			if _, ok := project.Volumes[volume]; ok {
+				return fmt.Errorf("external volume (%s) conflicts with project volume of the same name", volume)
+			}
+			if _, err := l.dockerCLI.Client().VolumeInspect(context.Background(), volume); err != nil {
+				if client.IsErrNotFound(err) {
+					return fmt.Errorf("external volume (%s) referenced by Mutagen session does not exist", volume)
+				}
+				return fmt.Errorf("unable to inspect external volume (%s): %w", volume, err)
+			}
+		}
 	}
 
 	// Determine the target sidecar image. At the moment, the only supported
 	// feature specification is "standard", though we may include more granular
-	// feature sets in the future. We default to the enhanced feature set.
+	// feature sets in the future. We default to the enhanced feature set. If
+	// the user has configured an image mirror, then we use it in place of the
+	// default image repository.
 	image := sidecarImage
+	if l.userConfiguration != nil && l.userConfiguration.Sidecar.ImageMirror != "" {
+		image = l.userConfiguration.Sidecar.ImageMirror + ":" + mutagen.Version
+	}
 	var capabilities []string
 	if xMutagen.Sidecar.Features == "" {
 		image += enhancedTagSuffix
@@ -451,20 +816,35 @@ func (l *Liaison) processProject(project *types.Project) error {
 	}
 	composeVersion := strings.TrimPrefix(versions.Compose, "v")
 
+	// Compute sidecar labels. We only add the isolated daemon label if daemon
+	// isolation is enabled in order to avoid modifying the sidecar definition
+	// (and thus triggering its recreation) for existing projects. Since
+	// operations such as stop and down don't process the project, this label
+	// is the authoritative record of which daemon manages the project's
+	// sessions.
+	labels := types.Labels{
+		sidecarRoleLabelKey:    sidecarRoleLabelValue,
+		sidecarVersionLabelKey: mutagen.Version,
+	}
+	isolated := l.userConfiguration != nil && l.userConfiguration.Daemon.Isolated
+	if xMutagen.Daemon.Isolated != nil {
+		isolated = *xMutagen.Daemon.Isolated
+	}
+	if isolated {
+		labels[sidecarIsolatedDaemonLabelKey] = sidecarIsolatedDaemonLabelValue
+	}
+
 	// Create and record the Mutagen sidecar service definition. The service
 	// configuration we generate here needs to match (as closely as possible)
 	// those generated by projectOptions.toProject in Compose. In particular,
 	// the labels need to be correct because many of Compose's commands operate
 	// solely on label filtering (see composeService.getContainers).
 	l.mutagenService = types.ServiceConfig{
-		Name:  sidecarServiceName,
-		Image: image,
-		Labels: types.Labels{
-			sidecarRoleLabelKey:    sidecarRoleLabelValue,
-			sidecarVersionLabelKey: mutagen.Version,
-		},
-		Networks: networkDependencies,
-		Volumes:  serviceVolumeDependencies,
+		Name:     sidecarServiceName,
+		Image:    image,
+		Labels:   labels,
+		Networks: dependencies[""].networks,
+		Volumes:  dependencies[""].serviceVolumes(daemonMetadata.OSType),
 		CapAdd:   capabilities,
 		CustomLabels: types.Labels{
 			api.ProjectLabel:     project.Name,
@@ -476,6 +856,13 @@ func (l *Liaison) processProject(project *types.Project) error {
 		},
 	}
 
+	// Set the sidecar platform to match the daemon so that the correct image
+	// variant is pulled for remote or emulated daemons. If the daemon doesn't
+	// report its platform, then we let Docker choose.
+	if daemonMetadata.OSType != "" && daemonMetadata.Architecture != "" {
+		l.mutagenService.Platform = daemonMetadata.OSType + "/" + normalizeArchitecture(daemonMetadata.Architecture)
+	}
+
 	// HACK: Populate the environment file label if we can pull that information
 	// off of another service. This isn't critical since this isn't used for
 	// filtering, but it's best to maintain consistency.
@@ -495,25 +882,202 @@ func (l *Liaison) processProject(project *types.Project) error {
 	if xMutagen.Sidecar.ContainerName != "" {
 		l.mutagenService.ContainerName = xMutagen.Sidecar.ContainerName
 	}
+	if len(xMutagen.Sidecar.Command) > 0 {
+		l.mutagenService.Command = xMutagen.Sidecar.Command
+	}
+	if len(xMutagen.Sidecar.Entrypoint) > 0 {
+		l.mutagenService.Entrypoint = xMutagen.Sidecar.Entrypoint
+	}
+	if len(xMutagen.Sidecar.Environment) > 0 {
+		environment, err := sidecarEnvironment(xMutagen.Sidecar.Environment)
+		if err != nil {
+			return fmt.Errorf("invalid sidecar environment: %w", err)
+		}
+		l.mutagenService.Environment = environment
+	}
+	if xMutagen.Sidecar.Placement != nil {
+		placement, err := xMutagen.Sidecar.Placement.placement()
+		if err != nil {
+			return fmt.Errorf("invalid sidecar placement: %w", err)
+		}
+		for _, d := range dependencies {
+			if len(d.volumes) > 0 || len(d.externalVolumes) > 0 || len(d.binds) > 0 {
+				if err := ensurePlacementSatisfiedByLocalNode(placement, daemonMetadata); err != nil {
+					return fmt.Errorf("invalid sidecar placement: %w", err)
+				}
+				break
+			}
+		}
+		l.mutagenService.Deploy = &types.DeployConfig{Placement: placement}
+	}
+	if xMutagen.Sidecar.LogLevel != "" {
+		if _, ok := logging.NameToLevel(xMutagen.Sidecar.LogLevel); !ok {
+			return fmt.Errorf("invalid sidecar log level specification: %s", xMutagen.Sidecar.LogLevel)
+		}
+		if l.mutagenService.Environment == nil {
+			l.mutagenService.Environment = make(types.MappingWithEquals, 1)
+		}
+		logLevel := xMutagen.Sidecar.LogLevel
+		l.mutagenService.Environment[logLevelEnvironmentVariable] = &logLevel
+	}
+	stopGracePeriod := sidecarDefaultStopGracePeriod
+	if xMutagen.Sidecar.StopGracePeriod != "" {
+		stopGracePeriod, err = time.ParseDuration(xMutagen.Sidecar.StopGracePeriod)
+		if err != nil {
+			return fmt.Errorf("invalid stop grace period specification: %w", err)
+		} else if stopGracePeriod < 0 {
+			return errors.New("negative stop grace period specified")
+		}
+	}
+	l.mutagenService.StopGracePeriod = (*types.Duration)(&stopGracePeriod)
+	pullPolicy := sidecarDefaultPullPolicy
+	if l.sidecarPullPolicy != "" {
+		pullPolicy = l.sidecarPullPolicy
+	} else if xMutagen.Sidecar.PullPolicy != "" {
+		pullPolicy = xMutagen.Sidecar.PullPolicy
+	}
+	if !isValidSidecarPullPolicy(pullPolicy) {
+		return fmt.Errorf("invalid sidecar pull policy specification: %s", pullPolicy)
+	}
+	l.mutagenService.PullPolicy = pullPolicy
+	position := sidecarPositionLast
+	if xMutagen.Sidecar.Position != "" {
+		position = xMutagen.Sidecar.Position
+	}
+	if !isValidSidecarPosition(position) {
+		return fmt.Errorf("invalid sidecar position specification: %s", position)
+	}
+	l.sidecarFirst = position == sidecarPositionFirst
+	if auth := xMutagen.Sidecar.RegistryAuth; auth != nil {
+		if err := auth.ensureValid(); err != nil {
+			return fmt.Errorf("invalid sidecar registry authentication: %w", err)
+		}
+	}
+	l.sidecarRegistryAuthentication = xMutagen.Sidecar.RegistryAuth
+
+	// Create and record sidecar group service definitions. These are derived
+	// from the primary sidecar service definition, but they use their own
+	// dependencies and carry a label identifying their group. Container names
+	// must be unique, so any container name override only applies to the
+	// primary sidecar service.
+	groups := make([]string, 0, len(dependencies))
+	for group := range dependencies {
+		if group != "" {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	l.sidecarGroupServices = nil
+	for _, group := range groups {
+		name := sidecarServiceName + "-" + group
+		if !isValidServiceName(group) {
+			return fmt.Errorf("invalid sidecar group name: %s", group)
+		}
+		for _, service := range project.AllServices() {
+			if service.Name == name {
+				return fmt.Errorf("user-defined service (%s) conflicts with Mutagen Compose sidecar service for group (%s)", name, group)
+			}
+		}
+		service := l.mutagenService
+		service.Name = name
+		service.ContainerName = ""
+		service.Networks = dependencies[group].networks
+		service.Volumes = dependencies[group].serviceVolumes(daemonMetadata.OSType)
+		service.Labels = make(types.Labels, len(l.mutagenService.Labels)+1)
+		for key, value := range l.mutagenService.Labels {
+			service.Labels[key] = value
+		}
+		service.Labels[sidecarGroupLabelKey] = group
+		service.CustomLabels = make(types.Labels, len(l.mutagenService.CustomLabels))
+		for key, value := range l.mutagenService.CustomLabels {
+			service.CustomLabels[key] = value
+		}
+		service.CustomLabels[api.ServiceLabel] = name
+		l.sidecarGroupServices = append(l.sidecarGroupServices, service)
+	}
+
+	// Attach the staging volume to the sidecar services, if specified. The
+	// volume can't be a synchronization target, since staging files into a
+	// synchronized volume would cause them to be picked up by synchronization.
+	// The Mutagen agent (which is launched via docker exec and thus inherits
+	// the container environment) will use the volume as its data directory.
+	if volume := xMutagen.Sidecar.StagingVolume; volume != "" {
+		if _, ok := project.Volumes[volume]; !ok {
+			return fmt.Errorf("undefined staging volume (%s)", volume)
+		}
+		for _, d := range dependencies {
+			if _, ok := d.volumes[volume]; ok {
+				return fmt.Errorf("staging volume (%s) can't be used by Mutagen sessions", volume)
+			}
+		}
+		attachStagingVolume(&l.mutagenService, volume, daemonMetadata.OSType)
+		for i := range l.sidecarGroupServices {
+			attachStagingVolume(&l.sidecarGroupServices[i], volume, daemonMetadata.OSType)
+		}
+	}
 
-	// Store session specifications.
+	// Store session specifications and daemon settings.
 	l.forwarding = forwardingSpecifications
+	l.forwardingGroups = forwardingGroups
 	l.synchronization = synchronizationSpecifications
+	l.synchronizationGroups = synchronizationGroups
+	l.forwardingHealthGates.services = forwardingHealthGates
+	l.synchronizationDeferredFlush = synchronizationDeferredFlush
+	l.isolatedDaemon = isolated
+	l.daemonArchitecture = normalizeArchitecture(daemonMetadata.Architecture)
+	l.keepOrphanSessions = xMutagen.Reconciliation.KeepOrphans
 
 	// Success.
 	return nil
 }
 
+// sortedNames returns the keys of a map keyed by session name in sorted order.
+// It's used to ensure that sessions are processed (and reported) in a stable
+// order.
+func sortedNames[T any](sessions map[string]T) []string {
+	names := make([]string, 0, len(sessions))
+	for name := range sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// specificationsForGroup returns the forwarding and synchronization session
+// specifications for the specified sidecar group, keyed by session name. The
+// primary sidecar is represented by the empty group.
+func (l *Liaison) specificationsForGroup(group string) (map[string]*forwardingsvc.CreationSpecification, map[string]*synchronizationsvc.CreationSpecification) {
+	forwardingSpecifications := make(map[string]*forwardingsvc.CreationSpecification)
+	for name, specification := range l.forwarding {
+		if l.forwardingGroups[name] == group {
+			forwardingSpecifications[name] = specification
+		}
+	}
+	synchronizationSpecifications := make(map[string]*synchronizationsvc.CreationSpecification)
+	for name, specification := range l.synchronization {
+		if l.synchronizationGroups[name] == group {
+			synchronizationSpecifications[name] = specification
+		}
+	}
+	return forwardingSpecifications, synchronizationSpecifications
+}
+
 // reconcileSessions performs Mutagen session reconciliation for the project
 // using the specified sidecar container ID as the target identifier. It also
-// ensures that all sessions are unpaused.
-func (l *Liaison) reconcileSessions(ctx context.Context, sidecarID string) error {
+// ensures that all sessions are unpaused. If force is true, then all existing
+// sessions are treated as stale and recreated from their specifications.
+// Sessions created by a different version of Mutagen are always treated as
+// stale. It returns a summary of the actions performed.
+func (l *Liaison) reconcileSessions(ctx context.Context, sidecarID string, force bool) (*ReconciliationResult, error) {
 	// Create a Mutagen status updater, start the Mutagen status update, and
 	// defer its finalization.
-	status := newStatusUpdater(ctx, "Mutagen")
+	status := newStatusUpdater(ctx, "Mutagen", l.quiet)
 	status.working("Reconciling Mutagen sessions")
+	logger := l.sidecarLogger(sidecarID, "reconcile")
+	start := time.Now()
 	var statusErr error
 	defer func() {
+		logCompletion(logger, start, statusErr)
 		if statusErr != nil {
 			status.error(statusErr)
 		} else {
@@ -521,30 +1085,60 @@ func (l *Liaison) reconcileSessions(ctx context.Context, sidecarID string) error
 		}
 	}()
 
-	// Convert sidecar URLs to concrete Docker URLs and add sidecar ID labels.
-	for _, specification := range l.forwarding {
+	// Inspect the sidecar container. Note that the sidecar container may have
+	// been created by a different version of Mutagen Compose if the project is
+	// started (rather than brought up) after an upgrade, in which case Compose
+	// won't have recreated it. We don't force session recreation in that case
+	// since the container's labels never change; instead, sessions record the
+	// Mutagen version that created them, and those created by a different
+	// version are recreated (once) as stale sessions.
+	status.working("Inspecting Mutagen Compose sidecar container")
+	sidecarMetadata, err := l.dockerCLI.Client().ContainerInspect(ctx, sidecarID)
+	if err != nil {
+		statusErr = fmt.Errorf("unable to inspect Mutagen Compose sidecar container: %w", err)
+		return nil, statusErr
+	} else if sidecarMetadata.Config == nil {
+		statusErr = errors.New("Mutagen Compose sidecar container has no configuration")
+		return nil, statusErr
+	}
+
+	// Select the session specifications for the sidecar group hosted by the
+	// sidecar container. Sessions belonging to other groups are treated as
+	// undefined for this sidecar container.
+	forwardingSpecifications, synchronizationSpecifications := l.specificationsForGroup(
+		sidecarMetadata.Config.Labels[sidecarGroupLabelKey],
+	)
+
+	// Convert sidecar URLs to concrete Docker URLs and add sidecar ID and
+	// project labels alongside any user-defined labels.
+	projectName := sidecarMetadata.Config.Labels[api.ProjectLabel]
+	for _, specification := range forwardingSpecifications {
 		reifySidecarURLIfNecessary(specification.Source, l.dockerFlags, l.dockerCLI, sidecarID)
 		reifySidecarURLIfNecessary(specification.Destination, l.dockerFlags, l.dockerCLI, sidecarID)
-		specification.Labels = map[string]string{
-			sessionSidecarLabelKey: chopSidecarIdentifier(sidecarID),
-		}
+		specification.Labels = sidecarSessionLabels(specification.Labels, sidecarID, projectName)
 	}
-	for _, specification := range l.synchronization {
+	for _, specification := range synchronizationSpecifications {
 		reifySidecarURLIfNecessary(specification.Alpha, l.dockerFlags, l.dockerCLI, sidecarID)
 		reifySidecarURLIfNecessary(specification.Beta, l.dockerFlags, l.dockerCLI, sidecarID)
-		specification.Labels = map[string]string{
-			sessionSidecarLabelKey: chopSidecarIdentifier(sidecarID),
-		}
+		specification.Labels = sidecarSessionLabels(specification.Labels, sidecarID, projectName)
 	}
 
+	// Determine which forwarding sessions need to have their creation deferred
+	// because they're gated on the health of a service that isn't yet healthy.
+	// Those sessions will be created in the background once the service
+	// becomes healthy (see scheduleHealthGatedSessions).
+	deferredForwardingSessions := l.deferredForwardingSessions(
+		ctx, projectName, sortedNames(forwardingSpecifications),
+	)
+
 	// Connect to the Mutagen daemon and defer closure of the connection.
 	status.working("Connecting to Mutagen daemon")
-	daemonConnection, err := daemon.Connect(true, true)
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
 	if err != nil {
 		statusErr = fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
-		return statusErr
+		return nil, statusErr
 	}
-	defer daemonConnection.Close()
+	defer l.releaseDaemonConnection(daemonConnection)
 
 	// Initiate message-only prompting via the status updater and defer its
 	// termination.
@@ -559,82 +1153,166 @@ func (l *Liaison) reconcileSessions(ctx context.Context, sidecarID string) error
 	}()
 	if err != nil {
 		statusErr = fmt.Errorf("unable to initiate Mutagen prompting: %w", err)
-		return statusErr
+		return nil, statusErr
 	}
 
-	// Create service clients.
-	forwardingService := forwardingsvc.NewForwardingClient(daemonConnection)
-	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
-
 	// Create the session selection criteria.
-	projectSelection := &selection.Selection{
-		LabelSelector: fmt.Sprintf("%s == %s", sessionSidecarLabelKey, chopSidecarIdentifier(sidecarID)),
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		statusErr = err
+		return nil, statusErr
+	}
+
+	// Perform reconciliation using the Mutagen daemon's session services. If
+	// reconciliation is interrupted, then any sessions that it attempted to
+	// create are rolled back.
+	rollback := func(forwardingNames, synchronizationNames []string) {
+		rollbackSessions(
+			logger, daemonConnection, status, projectSelection,
+			forwardingNames, synchronizationNames,
+		)
+	}
+	result, err := l.reconcileSessionsWithServices(
+		ctx, status, logger,
+		newDaemonForwardingSessionService(daemonConnection),
+		newDaemonSynchronizationSessionService(daemonConnection),
+		prompter, projectSelection,
+		forwardingSpecifications, synchronizationSpecifications,
+		deferredForwardingSessions, force, rollback,
+	)
+	if err != nil {
+		statusErr = err
+		return nil, statusErr
+	}
+
+	// Success.
+	return result, nil
+}
+
+// reconcileSessionsWithServices performs the session reconciliation logic of
+// reconcileSessions using the specified session services and prompter. Sessions
+// matching projectSelection are reconciled against the specified session
+// specifications, with orphaned, duplicate, and stale sessions pruned and
+// missing sessions created. The creation of forwarding sessions named in
+// deferredForwardingSessions is skipped. If reconciliation is interrupted after
+// session creation begins, then rollback (if non-nil) is invoked with the names
+// of the sessions whose creation was attempted. Progress is reported via
+// status.
+func (l *Liaison) reconcileSessionsWithServices(
+	ctx context.Context,
+	status *statusUpdater,
+	logger logrus.FieldLogger,
+	forwardingService forwardingSessionService,
+	synchronizationService synchronizationSessionService,
+	prompter string,
+	projectSelection *selection.Selection,
+	forwardingSpecifications map[string]*forwardingsvc.CreationSpecification,
+	synchronizationSpecifications map[string]*synchronizationsvc.CreationSpecification,
+	deferredForwardingSessions map[string]bool,
+	force bool,
+	rollback func(forwardingNames, synchronizationNames []string),
+) (result *ReconciliationResult, err error) {
+	// Create the reconciliation result.
+	result = &ReconciliationResult{
+		CreatedForwardingSessions:      make(map[string]string),
+		CreatedSynchronizationSessions: make(map[string]string),
 	}
 
 	// Query existing forwarding sessions.
 	status.working("Querying existing forwarding sessions")
-	forwardingListRequest := &forwardingsvc.ListRequest{Selection: projectSelection}
-	forwardingListResponse, err := forwardingService.List(context.Background(), forwardingListRequest)
+	forwardingStates, err := forwardingService.List(context.Background(), projectSelection)
 	if err != nil {
-		statusErr = fmt.Errorf("forwarding session listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
-		return statusErr
-	} else if err = forwardingListResponse.EnsureValid(); err != nil {
-		statusErr = fmt.Errorf("invalid forwarding session listing response received: %w", err)
-		return statusErr
+		return nil, fmt.Errorf("forwarding session listing failed: %w", err)
 	}
 
 	// Query existing synchronization sessions.
 	status.working("Querying existing synchronization sessions")
-	synchronizationListRequest := &synchronizationsvc.ListRequest{Selection: projectSelection}
-	synchronizationListResponse, err := synchronizationService.List(context.Background(), synchronizationListRequest)
+	synchronizationStates, err := synchronizationService.List(context.Background(), projectSelection)
 	if err != nil {
-		statusErr = fmt.Errorf("synchronization session listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
-		return statusErr
-	} else if err = synchronizationListResponse.EnsureValid(); err != nil {
-		statusErr = fmt.Errorf("invalid synchronization session listing response received: %w", err)
-		return statusErr
+		return nil, fmt.Errorf("synchronization session listing failed: %w", err)
+	}
+
+	// Provide initial session states to the session state callback, if any.
+	if l.sessionStateCallback != nil {
+		l.sessionStateCallback(&SessionStateUpdate{
+			Phase:                 ReconciliationPhaseInitial,
+			ForwardingStates:      forwardingStates,
+			SynchronizationStates: synchronizationStates,
+		})
 	}
 
 	// Identify orphan forwarding sessions with no corresponding definition, as
 	// well as any duplicate forwarding sessions. At the same time, construct a
-	// map from session name to existing session.
+	// map from session name to existing session. If orphan sessions are to be
+	// kept, then we simply ignore them, but we still prune duplicates since
+	// they would otherwise make reconciliation ambiguous. When duplicates are
+	// found, we retain the most recently created session (see
+	// sessionSupersedes) and prune the others.
 	status.working("Identifying orphan forwarding sessions")
 	var forwardingPruneList []string
 	forwardingNameToSession := make(map[string]*forwarding.Session)
-	for _, state := range forwardingListResponse.SessionStates {
-		if _, defined := l.forwarding[state.Session.Name]; !defined {
-			forwardingPruneList = append(forwardingPruneList, state.Session.Identifier)
-		} else if _, duplicated := forwardingNameToSession[state.Session.Name]; duplicated {
-			forwardingPruneList = append(forwardingPruneList, state.Session.Identifier)
-		} else {
+	for _, state := range forwardingStates {
+		if _, defined := forwardingSpecifications[state.Session.Name]; !defined {
+			if !l.keepOrphanSessions {
+				forwardingPruneList = append(forwardingPruneList, state.Session.Identifier)
+			}
+		} else if existing, duplicated := forwardingNameToSession[state.Session.Name]; !duplicated {
+			forwardingNameToSession[state.Session.Name] = state.Session
+		} else if sessionSupersedes(
+			state.Session.GetCreationTime().AsTime(), state.Session.Identifier,
+			existing.GetCreationTime().AsTime(), existing.Identifier,
+		) {
+			forwardingPruneList = append(forwardingPruneList, existing.Identifier)
 			forwardingNameToSession[state.Session.Name] = state.Session
+		} else {
+			forwardingPruneList = append(forwardingPruneList, state.Session.Identifier)
 		}
 	}
 
 	// Identify orphan synchronization sessions with no corresponding
 	// definition, as well as any duplicate synchronization sessions. At the
-	// same time, construct a map from session name to existing session.
+	// same time, construct a map from session name to existing session. Orphan
+	// sessions are handled using the same strategy as above.
 	status.working("Identifying orphan synchronization sessions")
 	var synchronizationPruneList []string
 	synchronizationNameToSession := make(map[string]*synchronization.Session)
-	for _, state := range synchronizationListResponse.SessionStates {
-		if _, defined := l.synchronization[state.Session.Name]; !defined {
-			synchronizationPruneList = append(synchronizationPruneList, state.Session.Identifier)
-		} else if _, duplicated := synchronizationNameToSession[state.Session.Name]; duplicated {
-			synchronizationPruneList = append(synchronizationPruneList, state.Session.Identifier)
-		} else {
+	for _, state := range synchronizationStates {
+		if _, defined := synchronizationSpecifications[state.Session.Name]; !defined {
+			if !l.keepOrphanSessions {
+				synchronizationPruneList = append(synchronizationPruneList, state.Session.Identifier)
+			}
+		} else if existing, duplicated := synchronizationNameToSession[state.Session.Name]; !duplicated {
+			synchronizationNameToSession[state.Session.Name] = state.Session
+		} else if sessionSupersedes(
+			state.Session.GetCreationTime().AsTime(), state.Session.Identifier,
+			existing.GetCreationTime().AsTime(), existing.Identifier,
+		) {
+			synchronizationPruneList = append(synchronizationPruneList, existing.Identifier)
 			synchronizationNameToSession[state.Session.Name] = state.Session
+		} else {
+			synchronizationPruneList = append(synchronizationPruneList, state.Session.Identifier)
 		}
 	}
 
-	// Identify forwarding sessions that need to be created or recreated.
+	// Identify forwarding sessions that need to be created or recreated. If
+	// recreation is being forced, then all existing sessions are considered
+	// stale. We process sessions in name order so that creation order (and
+	// the associated output) is reproducible. Stale sessions whose creation is
+	// deferred are still pruned.
 	status.working("Identifying missing and stale forwarding sessions")
 	var forwardingCreateSpecifications []*forwardingsvc.CreationSpecification
-	for name, specification := range l.forwarding {
-		if existing, ok := forwardingNameToSession[name]; !ok {
-			forwardingCreateSpecifications = append(forwardingCreateSpecifications, specification)
-		} else if !forwardingSessionCurrent(existing, specification) {
+	for _, name := range sortedNames(forwardingSpecifications) {
+		specification := forwardingSpecifications[name]
+		existing, ok := forwardingNameToSession[name]
+		if ok && !force && forwardingSessionCurrent(existing, specification) {
+			result.ResumedForwardingSessions = append(result.ResumedForwardingSessions, existing.Identifier)
+			continue
+		} else if ok {
 			forwardingPruneList = append(forwardingPruneList, existing.Identifier)
+		}
+		if deferredForwardingSessions[name] {
+			result.DeferredForwardingSessions = append(result.DeferredForwardingSessions, name)
+		} else {
 			forwardingCreateSpecifications = append(forwardingCreateSpecifications, specification)
 		}
 	}
@@ -642,12 +1320,15 @@ func (l *Liaison) reconcileSessions(ctx context.Context, sidecarID string) error
 	// Identify synchronization sessions that need to be created or recreated.
 	status.working("Identifying missing and stale synchronization sessions")
 	var synchronizationCreateSpecifications []*synchronizationsvc.CreationSpecification
-	for name, specification := range l.synchronization {
+	for _, name := range sortedNames(synchronizationSpecifications) {
+		specification := synchronizationSpecifications[name]
 		if existing, ok := synchronizationNameToSession[name]; !ok {
 			synchronizationCreateSpecifications = append(synchronizationCreateSpecifications, specification)
-		} else if !synchronizationSessionCurrent(existing, specification) {
+		} else if force || !synchronizationSessionCurrent(existing, specification) {
 			synchronizationPruneList = append(synchronizationPruneList, existing.Identifier)
 			synchronizationCreateSpecifications = append(synchronizationCreateSpecifications, specification)
+		} else {
+			result.ResumedSynchronizationSessions = append(result.ResumedSynchronizationSessions, existing.Identifier)
 		}
 	}
 
@@ -655,20 +1336,20 @@ func (l *Liaison) reconcileSessions(ctx context.Context, sidecarID string) error
 	if len(forwardingPruneList) > 0 {
 		status.working("Pruning stale Mutagen forwarding sessions")
 		pruneSelection := &selection.Selection{Specifications: forwardingPruneList}
-		if err := forwardingTerminateWithSelection(ctx, forwardingService, prompter, pruneSelection); err != nil {
-			statusErr = fmt.Errorf("unable to prune orphaned/duplicate/stale forwarding sessions: %w", err)
-			return statusErr
+		if err := forwardingService.Terminate(ctx, prompter, pruneSelection); err != nil {
+			return nil, fmt.Errorf("unable to prune orphaned/duplicate/stale forwarding sessions: %w", err)
 		}
+		result.PrunedForwardingSessions = forwardingPruneList
 	}
 
 	// Prune orphaned and stale synchronization sessions.
 	if len(synchronizationPruneList) > 0 {
 		status.working("Pruning stale Mutagen synchronization sessions")
 		pruneSelection := &selection.Selection{Specifications: synchronizationPruneList}
-		if err := synchronizationTerminateWithSelection(ctx, synchronizationService, prompter, pruneSelection); err != nil {
-			statusErr = fmt.Errorf("unable to prune orphaned/duplicate/stale synchronization sessions: %w", err)
-			return statusErr
+		if err := synchronizationService.Terminate(ctx, prompter, pruneSelection); err != nil {
+			return nil, fmt.Errorf("unable to prune orphaned/duplicate/stale synchronization sessions: %w", err)
 		}
+		result.PrunedSynchronizationSessions = synchronizationPruneList
 	}
 
 	// Ensure that all existing sessions are unpaused and connected. This is a
@@ -677,67 +1358,234 @@ func (l *Liaison) reconcileSessions(ctx context.Context, sidecarID string) error
 	// shutdown or stop operation, in which case sessions may be waiting to
 	// reconnect or paused, respectively.
 	status.working("Resuming Mutagen forwarding sessions")
-	if err := forwardingResumeWithSelection(ctx, forwardingService, prompter, projectSelection); err != nil {
-		statusErr = fmt.Errorf("forwarding resumption failed: %w", err)
-		return statusErr
+	if err := forwardingService.Resume(ctx, prompter, projectSelection); err != nil {
+		return nil, fmt.Errorf("forwarding resumption failed: %w", err)
 	}
 	status.working("Resuming Mutagen synchronization sessions")
-	if err := synchronizationResumeWithSelection(ctx, synchronizationService, prompter, projectSelection); err != nil {
-		statusErr = fmt.Errorf("synchronization resumption failed: %w", err)
-		return statusErr
+	if err := synchronizationService.Resume(ctx, prompter, projectSelection); err != nil {
+		return nil, fmt.Errorf("synchronization resumption failed: %w", err)
 	}
 
+	// If reconciliation is interrupted after session creation begins, then
+	// roll back the sessions that we've attempted to create so that the
+	// project isn't left with partially initialized sessions. They'll be
+	// recreated by the next reconciliation. We track attempted (rather than
+	// successful) creations since an interrupted creation may still complete
+	// on the daemon side.
+	var attemptedForwardingSessions, attemptedSynchronizationSessions []string
+	defer func() {
+		if err != nil && ctx.Err() != nil && rollback != nil {
+			rollback(attemptedForwardingSessions, attemptedSynchronizationSessions)
+		}
+	}()
+
 	// Create forwarding sessions.
 	for _, specification := range forwardingCreateSpecifications {
+		attemptedForwardingSessions = append(attemptedForwardingSessions, specification.Name)
 		status.working(fmt.Sprintf("Creating Mutagen forwarding session \"%s\"", specification.Name))
-		if _, err := forwardingCreateWithSpecification(ctx, forwardingService, prompter, specification); err != nil {
-			statusErr = fmt.Errorf("unable to create forwarding session (%s): %w", specification.Name, err)
-			return statusErr
+		if f, err := forwardingService.Create(ctx, prompter, specification); err != nil {
+			return nil, fmt.Errorf("unable to create forwarding session (%s): %w", specification.Name, err)
+		} else {
+			logger.WithField("session", specification.Name).Debugf("Created forwarding session (%s)", f)
+			result.CreatedForwardingSessions[specification.Name] = f
+			status.working(fmt.Sprintf("Created Mutagen forwarding session \"%s\" (%s)", specification.Name, f))
 		}
 	}
 
 	// Create synchronization sessions.
-	var newSynchronizationSessions []string
+	var flushedSynchronizationSessions []string
 	for _, specification := range synchronizationCreateSpecifications {
+		attemptedSynchronizationSessions = append(attemptedSynchronizationSessions, specification.Name)
 		status.working(fmt.Sprintf("Creating Mutagen synchronization session \"%s\"", specification.Name))
-		if s, err := synchronizationCreateWithSpecification(ctx, synchronizationService, prompter, specification); err != nil {
-			statusErr = fmt.Errorf("unable to create synchronization session (%s): %w", specification.Name, err)
-			return statusErr
+		if s, err := synchronizationService.Create(ctx, prompter, specification); err != nil {
+			return nil, fmt.Errorf("unable to create synchronization session (%s): %w", specification.Name, err)
 		} else {
-			newSynchronizationSessions = append(newSynchronizationSessions, s)
+			logger.WithField("session", specification.Name).Debugf("Created synchronization session (%s)", s)
+			if !l.synchronizationDeferredFlush[specification.Name] {
+				flushedSynchronizationSessions = append(flushedSynchronizationSessions, s)
+			}
+			result.CreatedSynchronizationSessions[specification.Name] = s
+			status.working(fmt.Sprintf("Created Mutagen synchronization session \"%s\" (%s)", specification.Name, s))
 		}
 	}
 
-	// Flush newly created synchronization sessions.
-	if len(newSynchronizationSessions) > 0 {
+	// Flush newly created synchronization sessions, except for those that have
+	// opted out of flushing on creation (which synchronize in the background).
+	if len(flushedSynchronizationSessions) > 0 {
 		status.working("Flushing Mutagen synchronization sessions")
-		flushSelection := &selection.Selection{Specifications: newSynchronizationSessions}
-		if err := synchronizationFlushWithSelection(ctx, synchronizationService, prompter, flushSelection); err != nil {
-			statusErr = fmt.Errorf("unable to flush synchronization sessions: %w", err)
-			return statusErr
+		flushSelection := &selection.Selection{Specifications: flushedSynchronizationSessions}
+		reportProgress := func(progress string) {
+			status.working("Flushing Mutagen synchronization sessions: " + progress)
+		}
+		if err := synchronizationFlushWithProgress(ctx, synchronizationService, prompter, flushSelection, reportProgress); err != nil {
+			l.initialSynchronizationIncomplete = ctx.Err() == nil
+			return nil, fmt.Errorf("unable to flush synchronization sessions: %w", err)
+		}
+		result.FlushedSynchronizationSessions = flushedSynchronizationSessions
+	}
+
+	// If requested, wait for sessions to reach a steady state.
+	if l.waitForSessions {
+		status.working("Waiting for Mutagen sessions to reach a steady state")
+		if err := waitForSteadySessions(ctx, forwardingService, synchronizationService, projectSelection); err != nil {
+			l.initialSynchronizationIncomplete = ctx.Err() == nil
+			return nil, fmt.Errorf("unable to wait for sessions: %w", err)
+		}
+	}
+
+	// Provide final session states to the session state callback, if any.
+	if l.sessionStateCallback != nil {
+		status.working("Querying final session states")
+		forwardingStates, err := forwardingService.List(ctx, projectSelection)
+		if err != nil {
+			return nil, fmt.Errorf("forwarding session listing failed: %w", err)
+		}
+		synchronizationStates, err := synchronizationService.List(ctx, projectSelection)
+		if err != nil {
+			return nil, fmt.Errorf("synchronization session listing failed: %w", err)
 		}
+		l.sessionStateCallback(&SessionStateUpdate{
+			Phase:                 ReconciliationPhaseComplete,
+			ForwardingStates:      forwardingStates,
+			SynchronizationStates: synchronizationStates,
+		})
+	}
+
+	// Success.
+	return result, nil
+}
+
+// startSessions reconciles Mutagen sessions for the project using the
+// specified sidecar container ID as the target identifier. If no project has
+// been processed, then the session specifications needed for reconciliation
+// aren't available, so existing sessions are only resumed (rather than being
+// pruned as orphans). The attempt is recorded so that ensureSessionsStarted
+// doesn't repeat it.
+func (l *Liaison) startSessions(ctx context.Context, sidecarID string) error {
+	// Record the attempt.
+	if l.reconciledSidecars == nil {
+		l.reconciledSidecars = make(map[string]bool)
+	}
+	l.reconciledSidecars[sidecarID] = true
+
+	// If no project has been processed, then resume existing sessions.
+	if !l.processedProject {
+		l.log().Warn("Mutagen Compose project unavailable, resuming existing Mutagen sessions without reconciliation")
+		if err := l.resumeSessions(ctx, sidecarID, nil); err != nil {
+			return fmt.Errorf("unable to resume Mutagen sessions: %w", err)
+		}
+		return nil
+	}
+
+	// Otherwise, perform reconciliation.
+	if _, err := l.reconcileSessions(ctx, sidecarID, false); err != nil {
+		return fmt.Errorf("unable to reconcile Mutagen sessions: %w", err)
 	}
 
 	// Success.
 	return nil
 }
 
+// ensureSessionsStarted invokes startSessions for any running sidecar
+// containers in the specified project for which it hasn't already been
+// invoked. Sessions are normally started when Compose starts a sidecar
+// container (see dockerAPIClient.ContainerStart), but that doesn't occur if the
+// sidecar container was already running or if the underlying Compose service
+// doesn't use the liaison's Docker client, so operations that bring up sidecar
+// containers call this method afterward to ensure that sessions are started.
+func (l *Liaison) ensureSessionsStarted(ctx context.Context, projectName string) error {
+	// Identify running (and unpaused) sidecar containers whose sessions haven't
+	// been started.
+	sidecarIDs, err := l.resolveSidecarIDs(ctx, projectName, false)
+	var notFound *SidecarNotFoundError
+	if errors.As(err, &notFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var pending []string
+	for _, sidecarID := range sidecarIDs {
+		if l.reconciledSidecars[sidecarID] {
+			continue
+		}
+		metadata, err := l.dockerCLI.Client().ContainerInspect(ctx, sidecarID)
+		if err != nil {
+			return fmt.Errorf("unable to inspect Mutagen Compose sidecar container: %w", err)
+		} else if metadata.State != nil && metadata.State.Running && !metadata.State.Paused {
+			pending = append(pending, sidecarID)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Start sessions with progress reporting.
+	return progress.Run(ctx, func(ctx context.Context) error {
+		for _, sidecarID := range pending {
+			if err := l.startSessions(ctx, sidecarID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reconcile performs Mutagen session reconciliation for the specified project
+// using its running sidecar containers. If force is true, then all existing
+// sessions are treated as stale and recreated from their specifications, which
+// can be useful after upgrading Mutagen. It returns a summary of the actions
+// performed.
+func (l *Liaison) Reconcile(ctx context.Context, project *types.Project, force bool) (*ReconciliationResult, error) {
+	// Process Mutagen extensions for the project.
+	if err := l.processProject(project); err != nil {
+		return nil, fmt.Errorf("unable to process project: %w", err)
+	}
+
+	// Identify the sidecar containers and ensure that they're running.
+	sidecarIDs, err := l.resolveSidecarIDs(ctx, project.Name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform reconciliation for each sidecar container with progress
+	// reporting.
+	result := &ReconciliationResult{}
+	err = progress.Run(ctx, func(ctx context.Context) error {
+		for _, sidecarID := range sidecarIDs {
+			sidecarResult, err := l.reconcileSessions(ctx, sidecarID, force)
+			if err != nil {
+				return err
+			}
+			result.merge(sidecarResult)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // listSessions lists Mutagen sessions for the project using the specified
-// sidecar container ID as the target identifier.
+// sidecar container ID as the target identifier. The listing includes each
+// session's Mutagen identifier, which can be used with the Mutagen CLI.
 func (l *Liaison) listSessions(ctx context.Context, sidecarID string) error {
 	// Connect to the Mutagen daemon and defer closure of the connection.
-	daemonConnection, err := daemon.Connect(true, true)
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
 	if err != nil {
 		return fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
 	}
-	defer daemonConnection.Close()
+	defer l.releaseDaemonConnection(daemonConnection)
 
 	// Create the session selection criteria.
-	projectSelection := &selection.Selection{
-		LabelSelector: fmt.Sprintf("%s == %s", sessionSidecarLabelKey, chopSidecarIdentifier(sidecarID)),
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		return err
 	}
 
-	// Perform forwarding session listing.
+	// Perform forwarding session listing. Listings are ordered by the daemon
+	// according to session creation time, which corresponds to name order for
+	// sessions created during the same reconciliation.
 	fmt.Println("Forwarding sessions")
 	if err := forward.ListWithSelection(daemonConnection, projectSelection, false); err != nil {
 		return fmt.Errorf("forwarding listing failed: %w", err)
@@ -753,15 +1601,307 @@ func (l *Liaison) listSessions(ctx context.Context, sidecarID string) error {
 	return nil
 }
 
+// SessionStates encodes the states of Mutagen sessions.
+type SessionStates struct {
+	// Forwarding are the forwarding session states.
+	Forwarding []*forwarding.State
+	// Synchronization are the synchronization session states.
+	Synchronization []*synchronization.State
+}
+
+// ListSessions lists Mutagen sessions for the project using the specified
+// sidecar container ID as the target identifier and returns their states. It
+// is the structured analog of the listing performed by Status.
+func (l *Liaison) ListSessions(ctx context.Context, sidecarID string) (*SessionStates, error) {
+	// Connect to the Mutagen daemon and defer release of the connection.
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
+	}
+	defer l.releaseDaemonConnection(daemonConnection)
+
+	// Create the session selection criteria.
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query forwarding sessions.
+	forwardingService := forwardingsvc.NewForwardingClient(daemonConnection)
+	forwardingListResponse, err := forwardingService.List(ctx, &forwardingsvc.ListRequest{Selection: projectSelection})
+	if err != nil {
+		return nil, fmt.Errorf("forwarding session listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	} else if err = forwardingListResponse.EnsureValid(); err != nil {
+		return nil, fmt.Errorf("invalid forwarding session listing response received: %w", err)
+	}
+
+	// Query synchronization sessions.
+	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
+	synchronizationListResponse, err := synchronizationService.List(ctx, &synchronizationsvc.ListRequest{Selection: projectSelection})
+	if err != nil {
+		return nil, fmt.Errorf("synchronization session listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	} else if err = synchronizationListResponse.EnsureValid(); err != nil {
+		return nil, fmt.Errorf("invalid synchronization session listing response received: %w", err)
+	}
+
+	// Success.
+	return &SessionStates{
+		Forwarding:      forwardingListResponse.SessionStates,
+		Synchronization: synchronizationListResponse.SessionStates,
+	}, nil
+}
+
+// AffectedSessions identifies the Mutagen sessions affected by an operation.
+// Sessions are identified by name (or by identifier if unnamed).
+type AffectedSessions struct {
+	// Forwarding are the affected forwarding sessions.
+	Forwarding []string
+	// Synchronization are the affected synchronization sessions.
+	Synchronization []string
+}
+
+// merge merges the sessions identified by another set of affected sessions into
+// the receiver.
+func (a *AffectedSessions) merge(other *AffectedSessions) {
+	a.Forwarding = append(a.Forwarding, other.Forwarding...)
+	a.Synchronization = append(a.Synchronization, other.Synchronization...)
+}
+
+// contains returns whether or not the specified session name is identified by
+// the affected sessions.
+func (a *AffectedSessions) contains(name string) bool {
+	for _, n := range a.Forwarding {
+		if n == name {
+			return true
+		}
+	}
+	for _, n := range a.Synchronization {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionSelection encodes the selection criteria for an operation targeting a
+// subset of a project's Mutagen sessions. A nil selection for a session type
+// indicates that no sessions of that type are targeted.
+type sessionSelection struct {
+	// forwarding is the forwarding session selection.
+	forwarding *selection.Selection
+	// synchronization is the synchronization session selection.
+	synchronization *selection.Selection
+}
+
+// affectedSessions lists Mutagen sessions for the project using the specified
+// sidecar container ID as the target identifier and returns their names. If
+// names is non-empty, then only sessions with the specified names are included.
+// It also returns selection criteria targeting the included sessions.
+func (l *Liaison) affectedSessions(ctx context.Context, sidecarID string, names []string) (*AffectedSessions, *sessionSelection, error) {
+	// Connect to the Mutagen daemon and defer closure of the connection.
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
+	}
+	defer l.releaseDaemonConnection(daemonConnection)
+
+	// Create service clients.
+	forwardingService := forwardingsvc.NewForwardingClient(daemonConnection)
+	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
+
+	// Create the session selection criteria.
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// If no names have been specified, then all of the project's sessions
+	// will be selected.
+	if len(names) == 0 {
+		result, err := listAffectedSessions(ctx, forwardingService, synchronizationService, projectSelection, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result, &sessionSelection{forwarding: projectSelection, synchronization: projectSelection}, nil
+	}
+
+	// Otherwise, identify the named sessions and select them by identifier.
+	// We can't select them by name directly since session names needn't be
+	// unique across projects.
+	included := make(map[string]bool, len(names))
+	for _, name := range names {
+		included[name] = true
+	}
+	identifiers := &AffectedSessions{}
+	result, err := listAffectedSessions(ctx, forwardingService, synchronizationService, projectSelection, func(name, identifier string, synchronization bool) bool {
+		if !included[name] {
+			return false
+		}
+		if synchronization {
+			identifiers.Synchronization = append(identifiers.Synchronization, identifier)
+		} else {
+			identifiers.Forwarding = append(identifiers.Forwarding, identifier)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	selected := &sessionSelection{}
+	if len(identifiers.Forwarding) > 0 {
+		selected.forwarding = &selection.Selection{Specifications: identifiers.Forwarding}
+	}
+	if len(identifiers.Synchronization) > 0 {
+		selected.synchronization = &selection.Selection{Specifications: identifiers.Synchronization}
+	}
+
+	// Success.
+	return result, selected, nil
+}
+
+// listAffectedSessions lists the sessions matching the specified selection and
+// returns their names (or identifiers if unnamed). If filter is non-nil, then
+// only sessions for which it returns true are included.
+func listAffectedSessions(
+	ctx context.Context,
+	forwardingService forwardingsvc.ForwardingClient,
+	synchronizationService synchronizationsvc.SynchronizationClient,
+	criteria *selection.Selection,
+	filter func(name, identifier string, synchronization bool) bool,
+) (*AffectedSessions, error) {
+	// Create a function to compute the display name for a session.
+	displayName := func(name, identifier string) string {
+		if name != "" {
+			return name
+		}
+		return identifier
+	}
+
+	// List sessions and extract their names.
+	result := &AffectedSessions{}
+	forwardingListResponse, err := forwardingService.List(ctx, &forwardingsvc.ListRequest{Selection: criteria})
+	if err != nil {
+		return nil, fmt.Errorf("forwarding session listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	} else if err = forwardingListResponse.EnsureValid(); err != nil {
+		return nil, fmt.Errorf("invalid forwarding session listing response received: %w", err)
+	}
+	for _, state := range forwardingListResponse.SessionStates {
+		if filter == nil || filter(state.Session.Name, state.Session.Identifier, false) {
+			result.Forwarding = append(result.Forwarding, displayName(state.Session.Name, state.Session.Identifier))
+		}
+	}
+	synchronizationListResponse, err := synchronizationService.List(ctx, &synchronizationsvc.ListRequest{Selection: criteria})
+	if err != nil {
+		return nil, fmt.Errorf("synchronization session listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	} else if err = synchronizationListResponse.EnsureValid(); err != nil {
+		return nil, fmt.Errorf("invalid synchronization session listing response received: %w", err)
+	}
+	for _, state := range synchronizationListResponse.SessionStates {
+		if filter == nil || filter(state.Session.Name, state.Session.Identifier, true) {
+			result.Synchronization = append(result.Synchronization, displayName(state.Session.Name, state.Session.Identifier))
+		}
+	}
+
+	// Success.
+	return result, nil
+}
+
+// flushSessions flushes Mutagen synchronization sessions for the project using
+// the specified sidecar container ID as the target identifier. Sessions that are
+// paused or disconnected can't be flushed and are skipped.
+func (l *Liaison) flushSessions(ctx context.Context, sidecarID string) error {
+	// Create a Mutagen status updater, start the Mutagen status update, and
+	// defer its finalization.
+	status := newStatusUpdater(ctx, "Mutagen", l.quiet)
+	status.working("Flushing Mutagen sessions")
+	logger := l.sidecarLogger(sidecarID, "flush")
+	start := time.Now()
+	var statusErr error
+	defer func() {
+		logCompletion(logger, start, statusErr)
+		if statusErr != nil {
+			status.done("Flush incomplete")
+		} else {
+			status.done("Flushed")
+		}
+	}()
+
+	// Connect to the Mutagen daemon and defer closure of the connection.
+	status.working("Connecting to Mutagen daemon")
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
+	if err != nil {
+		statusErr = fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
+		return statusErr
+	}
+	defer l.releaseDaemonConnection(daemonConnection)
+
+	// Initiate message-only prompting via the status updater and defer its
+	// termination.
+	promptingCtx, promptingCancel := context.WithCancel(ctx)
+	prompter, promptingErrors, err := promptingsvc.Host(
+		promptingCtx, promptingsvc.NewPromptingClient(daemonConnection),
+		status, false,
+	)
+	defer func() {
+		promptingCancel()
+		<-promptingErrors
+	}()
+	if err != nil {
+		statusErr = fmt.Errorf("unable to initiate Mutagen prompting: %w", err)
+		return statusErr
+	}
+
+	// Create the session service.
+	synchronizationService := newDaemonSynchronizationSessionService(daemonConnection)
+
+	// Create the session selection criteria.
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		statusErr = err
+		return statusErr
+	}
+
+	// Identify sessions that can be flushed. Mutagen can't flush sessions that
+	// are paused or disconnected, so we skip those.
+	states, err := synchronizationService.List(ctx, projectSelection)
+	if err != nil {
+		statusErr = fmt.Errorf("unable to list synchronization sessions: %w", err)
+		return statusErr
+	}
+	flushable := &selection.Selection{}
+	for _, state := range states {
+		if !state.Session.Paused && state.AlphaConnected && state.BetaConnected {
+			flushable.Specifications = append(flushable.Specifications, state.Session.Identifier)
+		}
+	}
+	if len(flushable.Specifications) == 0 {
+		return nil
+	}
+
+	// Perform synchronization session flushing.
+	status.working("Flushing synchronization sessions")
+	if err := synchronizationService.Flush(ctx, prompter, flushable); err != nil {
+		statusErr = fmt.Errorf("synchronization flush failed: %w", err)
+		return statusErr
+	}
+
+	// Success.
+	return nil
+}
+
 // pauseSessions pauses Mutagen sessions for the project using the specified
-// sidecar container ID as the target identifier.
-func (l *Liaison) pauseSessions(ctx context.Context, sidecarID string) error {
+// sidecar container ID as the target identifier. If selected is nil, then all
+// of the project's sessions are paused.
+func (l *Liaison) pauseSessions(ctx context.Context, sidecarID string, selected *sessionSelection) error {
 	// Create a Mutagen status updater, start the Mutagen status update, and
 	// defer its finalization.
-	status := newStatusUpdater(ctx, "Mutagen")
+	status := newStatusUpdater(ctx, "Mutagen", l.quiet)
 	status.working("Pausing Mutagen sessions")
+	logger := l.sidecarLogger(sidecarID, "pause")
+	start := time.Now()
 	var statusErr error
 	defer func() {
+		logCompletion(logger, start, statusErr)
 		if statusErr != nil {
 			status.error(statusErr)
 		} else {
@@ -771,12 +1911,12 @@ func (l *Liaison) pauseSessions(ctx context.Context, sidecarID string) error {
 
 	// Connect to the Mutagen daemon and defer closure of the connection.
 	status.working("Connecting to Mutagen daemon")
-	daemonConnection, err := daemon.Connect(true, true)
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
 	if err != nil {
 		statusErr = fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
 		return statusErr
 	}
-	defer daemonConnection.Close()
+	defer l.releaseDaemonConnection(daemonConnection)
 
 	// Initiate message-only prompting via the status updater and defer its
 	// termination.
@@ -798,23 +1938,32 @@ func (l *Liaison) pauseSessions(ctx context.Context, sidecarID string) error {
 	forwardingService := forwardingsvc.NewForwardingClient(daemonConnection)
 	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
 
-	// Create the session selection criteria.
-	projectSelection := &selection.Selection{
-		LabelSelector: fmt.Sprintf("%s == %s", sessionSidecarLabelKey, chopSidecarIdentifier(sidecarID)),
+	// Create the session selection criteria, if necessary.
+	if selected == nil {
+		projectSelection, err := sidecarSessionSelection(sidecarID)
+		if err != nil {
+			statusErr = err
+			return statusErr
+		}
+		selected = &sessionSelection{forwarding: projectSelection, synchronization: projectSelection}
 	}
 
 	// Perform forwarding session pausing.
-	status.working("Pausing forwarding sessions")
-	if err := forwardingPauseWithSelection(ctx, forwardingService, prompter, projectSelection); err != nil {
-		statusErr = fmt.Errorf("forwarding pausing failed: %w", err)
-		return statusErr
+	if selected.forwarding != nil {
+		status.working("Pausing forwarding sessions")
+		if err := forwardingPauseWithSelection(ctx, forwardingService, prompter, selected.forwarding); err != nil {
+			statusErr = fmt.Errorf("forwarding pausing failed: %w", err)
+			return statusErr
+		}
 	}
 
 	// Perform synchronization session pausing.
-	status.working("Pausing synchronization sessions")
-	if err := synchronizationPauseWithSelection(ctx, synchronizationService, prompter, projectSelection); err != nil {
-		statusErr = fmt.Errorf("synchronization pausing failed: %w", err)
-		return statusErr
+	if selected.synchronization != nil {
+		status.working("Pausing synchronization sessions")
+		if err := synchronizationPauseWithSelection(ctx, synchronizationService, prompter, selected.synchronization); err != nil {
+			statusErr = fmt.Errorf("synchronization pausing failed: %w", err)
+			return statusErr
+		}
 	}
 
 	// Success.
@@ -822,14 +1971,18 @@ func (l *Liaison) pauseSessions(ctx context.Context, sidecarID string) error {
 }
 
 // resumeSessions resumes Mutagen sessions for the project using the specified
-// sidecar container ID as the target identifier.
-func (l *Liaison) resumeSessions(ctx context.Context, sidecarID string) error {
+// sidecar container ID as the target identifier. If selected is nil, then all
+// of the project's sessions are resumed.
+func (l *Liaison) resumeSessions(ctx context.Context, sidecarID string, selected *sessionSelection) error {
 	// Create a Mutagen status updater, start the Mutagen status update, and
 	// defer its finalization.
-	status := newStatusUpdater(ctx, "Mutagen")
+	status := newStatusUpdater(ctx, "Mutagen", l.quiet)
 	status.working("Resuming Mutagen sessions")
+	logger := l.sidecarLogger(sidecarID, "resume")
+	start := time.Now()
 	var statusErr error
 	defer func() {
+		logCompletion(logger, start, statusErr)
 		if statusErr != nil {
 			status.error(statusErr)
 		} else {
@@ -839,12 +1992,12 @@ func (l *Liaison) resumeSessions(ctx context.Context, sidecarID string) error {
 
 	// Connect to the Mutagen daemon and defer closure of the connection.
 	status.working("Connecting to Mutagen daemon")
-	daemonConnection, err := daemon.Connect(true, true)
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
 	if err != nil {
 		statusErr = fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
 		return statusErr
 	}
-	defer daemonConnection.Close()
+	defer l.releaseDaemonConnection(daemonConnection)
 
 	// Initiate message-only prompting via the status updater and defer its
 	// termination.
@@ -866,38 +2019,142 @@ func (l *Liaison) resumeSessions(ctx context.Context, sidecarID string) error {
 	forwardingService := forwardingsvc.NewForwardingClient(daemonConnection)
 	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
 
-	// Create the session selection criteria.
-	projectSelection := &selection.Selection{
-		LabelSelector: fmt.Sprintf("%s == %s", sessionSidecarLabelKey, chopSidecarIdentifier(sidecarID)),
+	// Create the session selection criteria, if necessary.
+	if selected == nil {
+		projectSelection, err := sidecarSessionSelection(sidecarID)
+		if err != nil {
+			statusErr = err
+			return statusErr
+		}
+		selected = &sessionSelection{forwarding: projectSelection, synchronization: projectSelection}
 	}
 
 	// Perform forwarding session resumption.
-	status.working("Resuming forwarding sessions")
-	if err := forwardingResumeWithSelection(ctx, forwardingService, prompter, projectSelection); err != nil {
-		statusErr = fmt.Errorf("forwarding resumption failed: %w", err)
-		return statusErr
+	if selected.forwarding != nil {
+		status.working("Resuming forwarding sessions")
+		if err := forwardingResumeWithSelection(ctx, forwardingService, prompter, selected.forwarding); err != nil {
+			statusErr = fmt.Errorf("forwarding resumption failed: %w", err)
+			return statusErr
+		}
 	}
 
 	// Perform synchronization session resumption.
-	status.working("Resuming synchronization sessions")
-	if err := synchronizationResumeWithSelection(ctx, synchronizationService, prompter, projectSelection); err != nil {
-		statusErr = fmt.Errorf("synchronization resumption failed: %w", err)
-		return statusErr
+	if selected.synchronization != nil {
+		status.working("Resuming synchronization sessions")
+		if err := synchronizationResumeWithSelection(ctx, synchronizationService, prompter, selected.synchronization); err != nil {
+			statusErr = fmt.Errorf("synchronization resumption failed: %w", err)
+			return statusErr
+		}
 	}
 
 	// Success.
 	return nil
 }
 
+// selectSessions identifies the Mutagen sessions targeted by an operation on
+// the specified sidecar containers. If names is non-empty, then only sessions
+// with the specified names are targeted and each name must correspond to at
+// least one session. It returns the targeted sessions along with per-sidecar
+// selection criteria.
+func (l *Liaison) selectSessions(ctx context.Context, sidecarIDs, names []string) (*AffectedSessions, []*sessionSelection, error) {
+	// Identify the targeted sessions for each sidecar container.
+	result := &AffectedSessions{}
+	selections := make([]*sessionSelection, len(sidecarIDs))
+	for s, sidecarID := range sidecarIDs {
+		affected, selected, err := l.affectedSessions(ctx, sidecarID, names)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.merge(affected)
+		selections[s] = selected
+	}
+
+	// Verify that each named session exists.
+	for _, name := range names {
+		if !result.contains(name) {
+			return nil, nil, fmt.Errorf("unknown session: %s", name)
+		}
+	}
+
+	// Success.
+	return result, selections, nil
+}
+
+// Pause pauses Mutagen sessions for the specified project without affecting
+// any of the project's containers. If names is non-empty, then only the named
+// sessions are paused, otherwise all sessions are paused. It returns the
+// sessions that were paused.
+func (l *Liaison) Pause(ctx context.Context, projectName string, names []string) (*AffectedSessions, error) {
+	// Identify the sidecar containers.
+	sidecarIDs, err := l.resolveSidecarIDs(ctx, projectName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify the sessions to be paused.
+	result, selections, err := l.selectSessions(ctx, sidecarIDs, names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform pausing.
+	for s, sidecarID := range sidecarIDs {
+		if selections[s].forwarding == nil && selections[s].synchronization == nil {
+			continue
+		} else if err := l.pauseSessions(ctx, sidecarID, selections[s]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Success.
+	return result, nil
+}
+
+// Resume resumes Mutagen sessions for the specified project without affecting
+// any of the project's containers. If names is non-empty, then only the named
+// sessions are resumed, otherwise all sessions are resumed. The project's
+// sidecar containers must be running. It returns the sessions that were
+// resumed.
+func (l *Liaison) Resume(ctx context.Context, projectName string, names []string) (*AffectedSessions, error) {
+	// Identify the sidecar containers and ensure that they're running, since
+	// sessions can't connect to them otherwise.
+	sidecarIDs, err := l.resolveSidecarIDs(ctx, projectName, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify the sessions to be resumed.
+	result, selections, err := l.selectSessions(ctx, sidecarIDs, names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform resumption.
+	for s, sidecarID := range sidecarIDs {
+		if selections[s].forwarding == nil && selections[s].synchronization == nil {
+			continue
+		} else if err := l.resumeSessions(ctx, sidecarID, selections[s]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Success.
+	return result, nil
+}
+
 // terminateSessions terminates Mutagen sessions for the project using the
-// specified sidecar container ID as the target identifier.
-func (l *Liaison) terminateSessions(ctx context.Context, sidecarID string) error {
+// specified sidecar container ID as the target identifier. It returns the
+// number of sessions terminated.
+func (l *Liaison) terminateSessions(ctx context.Context, sidecarID string) (int, error) {
 	// Create a Mutagen status updater, start the Mutagen status update, and
 	// defer its finalization.
-	status := newStatusUpdater(ctx, "Mutagen")
+	status := newStatusUpdater(ctx, "Mutagen", l.quiet)
 	status.working("Terminating Mutagen sessions")
+	logger := l.sidecarLogger(sidecarID, "terminate")
+	start := time.Now()
 	var statusErr error
 	defer func() {
+		logCompletion(logger, start, statusErr)
 		if statusErr != nil {
 			status.error(statusErr)
 		} else {
@@ -907,12 +2164,12 @@ func (l *Liaison) terminateSessions(ctx context.Context, sidecarID string) error
 
 	// Connect to the Mutagen daemon and defer closure of the connection.
 	status.working("Connecting to Mutagen daemon")
-	daemonConnection, err := daemon.Connect(true, true)
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
 	if err != nil {
 		statusErr = fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
-		return statusErr
+		return 0, statusErr
 	}
-	defer daemonConnection.Close()
+	defer l.releaseDaemonConnection(daemonConnection)
 
 	// Initiate message-only prompting via the status updater and defer its
 	// termination.
@@ -927,7 +2184,7 @@ func (l *Liaison) terminateSessions(ctx context.Context, sidecarID string) error
 	}()
 	if err != nil {
 		statusErr = fmt.Errorf("unable to initiate Mutagen prompting: %w", err)
-		return statusErr
+		return 0, statusErr
 	}
 
 	// Create service clients.
@@ -935,24 +2192,71 @@ func (l *Liaison) terminateSessions(ctx context.Context, sidecarID string) error
 	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
 
 	// Create the session selection criteria.
-	projectSelection := &selection.Selection{
-		LabelSelector: fmt.Sprintf("%s == %s", sessionSidecarLabelKey, chopSidecarIdentifier(sidecarID)),
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		statusErr = err
+		return 0, statusErr
 	}
 
+	// Count the sessions to be terminated.
+	status.working("Listing Mutagen sessions")
+	forwardingListRequest := &forwardingsvc.ListRequest{Selection: projectSelection}
+	forwardingListResponse, err := forwardingService.List(ctx, forwardingListRequest)
+	if err != nil {
+		statusErr = fmt.Errorf("forwarding session listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+		return 0, statusErr
+	} else if err = forwardingListResponse.EnsureValid(); err != nil {
+		statusErr = fmt.Errorf("invalid forwarding session listing response received: %w", err)
+		return 0, statusErr
+	}
+	synchronizationListRequest := &synchronizationsvc.ListRequest{Selection: projectSelection}
+	synchronizationListResponse, err := synchronizationService.List(ctx, synchronizationListRequest)
+	if err != nil {
+		statusErr = fmt.Errorf("synchronization session listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+		return 0, statusErr
+	} else if err = synchronizationListResponse.EnsureValid(); err != nil {
+		statusErr = fmt.Errorf("invalid synchronization session listing response received: %w", err)
+		return 0, statusErr
+	}
+	count := len(forwardingListResponse.SessionStates) + len(synchronizationListResponse.SessionStates)
+
 	// Perform forwarding session termination.
 	status.working("Terminating forwarding sessions")
 	if err := forwardingTerminateWithSelection(ctx, forwardingService, prompter, projectSelection); err != nil {
 		statusErr = fmt.Errorf("forwarding termination failed: %w", err)
-		return statusErr
+		return 0, statusErr
 	}
 
 	// Perform synchronization session termination.
 	status.working("Terminating synchronization sessions")
 	if err := synchronizationTerminateWithSelection(ctx, synchronizationService, prompter, projectSelection); err != nil {
 		statusErr = fmt.Errorf("synchronization termination failed: %w", err)
-		return statusErr
+		return 0, statusErr
 	}
 
 	// Success.
-	return nil
+	return count, nil
+}
+
+// Terminate terminates all Mutagen sessions for the specified project without
+// affecting any of the project's containers. The sessions will be recreated the
+// next time that the project's sidecar container is started. It returns the
+// number of sessions terminated.
+func (l *Liaison) Terminate(ctx context.Context, projectName string) (int, error) {
+	// Identify the sidecar containers.
+	sidecarIDs, err := l.resolveSidecarIDs(ctx, projectName, false)
+	if err != nil {
+		return 0, err
+	}
+
+	// Perform termination.
+	var count int
+	for _, sidecarID := range sidecarIDs {
+		terminated, err := l.terminateSessions(ctx, sidecarID)
+		if err != nil {
+			return 0, err
+		}
+		count += terminated
+	}
+	return count, nil
 }
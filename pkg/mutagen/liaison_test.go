@@ -0,0 +1,171 @@
+package mutagen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	"github.com/docker/cli/cli/command"
+	moby "github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/mutagen-io/mutagen/pkg/url"
+)
+
+// testDockerAPIClient is a Docker API client for tests. Only the methods
+// required by the code under test are implemented; calling any other method
+// will panic.
+type testDockerAPIClient struct {
+	client.APIClient
+}
+
+// Info implements github.com/docker/docker/client.APIClient.Info.
+func (c *testDockerAPIClient) Info(_ context.Context) (moby.Info, error) {
+	return moby.Info{OSType: "linux", Architecture: "x86_64"}, nil
+}
+
+// testDockerCLI is a Docker CLI for tests that returns a testDockerAPIClient.
+type testDockerCLI struct {
+	command.Cli
+}
+
+// Client implements github.com/docker/cli/cli/command.Cli.Client.
+func (c *testDockerCLI) Client() client.APIClient {
+	return &testDockerAPIClient{}
+}
+
+// newTestLiaison creates a liaison suitable for processing projects in tests.
+func newTestLiaison() *Liaison {
+	liaison := &Liaison{}
+	liaison.RegisterDockerCLI(&testDockerCLI{})
+	liaison.RegisterDockerFlags(pflag.NewFlagSet("docker", pflag.ContinueOnError))
+	return liaison
+}
+
+// newTestProject creates a project with a single database service attached to
+// the default network, a code volume, and the specified Mutagen configuration.
+func newTestProject(xMutagen map[string]interface{}) *types.Project {
+	return &types.Project{
+		Name:       "test",
+		WorkingDir: "/project",
+		Services: types.Services{
+			{
+				Name:     "database",
+				Image:    "postgres",
+				Networks: map[string]*types.ServiceNetworkConfig{"default": nil},
+			},
+		},
+		Networks: types.Networks{"default": types.NetworkConfig{}},
+		Volumes:  types.Volumes{"code": types.VolumeConfig{}},
+		Extensions: map[string]interface{}{
+			"x-mutagen": xMutagen,
+		},
+	}
+}
+
+// TestProcessProjectPreservesLoopbackForwardingSource tests that a forwarding
+// source bound to a loopback interface remains bound to only that interface
+// after project processing and sidecar URL reification.
+func TestProcessProjectPreservesLoopbackForwardingSource(t *testing.T) {
+	testCases := []struct {
+		source   string
+		expected string
+	}{
+		{"tcp:127.0.0.1:5432", "tcp:127.0.0.1:5432"},
+		{"tcp4:127.0.0.1:5432", "tcp4:127.0.0.1:5432"},
+		{"tcp:localhost:5432", "tcp:localhost:5432"},
+		{"tcp6:[::1]:5432", "tcp6:[::1]:5432"},
+	}
+	for _, testCase := range testCases {
+		liaison := newTestLiaison()
+		project := newTestProject(map[string]interface{}{
+			"forward": map[string]interface{}{
+				"database": map[string]interface{}{
+					"source":      testCase.source,
+					"destination": "network://default:tcp:database:5432",
+				},
+			},
+		})
+		if err := liaison.processProject(project); err != nil {
+			t.Fatalf("unable to process project with source %s: %v", testCase.source, err)
+		}
+		specification, ok := liaison.forwarding["database"]
+		if !ok {
+			t.Fatalf("forwarding session missing for source %s", testCase.source)
+		}
+		reifySidecarURLIfNecessary(specification.Source, liaison.dockerFlags, liaison.dockerCLI, "sidecar")
+		if specification.Source.Protocol != url.Protocol_Local {
+			t.Errorf("source %s: protocol changed to %v", testCase.source, specification.Source.Protocol)
+		} else if specification.Source.Path != testCase.expected {
+			t.Errorf("source %s: path mismatch: %s != %s", testCase.source, specification.Source.Path, testCase.expected)
+		}
+	}
+}
+
+// TestProcessProjectResolvesRelativePaths tests that relative synchronization
+// endpoint paths and Unix domain socket forwarding source paths are resolved
+// against the project directory rather than the process working directory.
+func TestProcessProjectResolvesRelativePaths(t *testing.T) {
+	// Create a project directory that differs from the working directory.
+	projectDirectory := t.TempDir()
+	if workingDirectory, err := os.Getwd(); err != nil {
+		t.Fatal("unable to determine working directory:", err)
+	} else if workingDirectory == projectDirectory {
+		t.Fatal("project directory matches working directory")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal("unable to determine home directory:", err)
+	}
+
+	// Create and process the project.
+	liaison := newTestLiaison()
+	project := newTestProject(map[string]interface{}{
+		"forward": map[string]interface{}{
+			"socket": map[string]interface{}{
+				"source":      "unix:./run/database.sock",
+				"destination": "network://default:tcp:database:5432",
+			},
+		},
+		"sync": map[string]interface{}{
+			"code": map[string]interface{}{
+				"alpha": "./src",
+				"beta":  "volume://code",
+			},
+			"output": map[string]interface{}{
+				"alpha": "volume://code/output",
+				"beta":  "../output",
+			},
+			"home": map[string]interface{}{
+				"alpha": "~/configuration",
+				"beta":  "volume://code/configuration",
+			},
+		},
+	})
+	project.WorkingDir = projectDirectory
+	if err := liaison.processProject(project); err != nil {
+		t.Fatal("unable to process project:", err)
+	}
+
+	// Verify path resolution.
+	checks := []struct {
+		description string
+		actual      string
+		expected    string
+	}{
+		{"forwarding source", liaison.forwarding["socket"].Source.Path, "unix:" + filepath.Join(projectDirectory, "run", "database.sock")},
+		{"synchronization alpha", liaison.synchronization["code"].Alpha.Path, filepath.Join(projectDirectory, "src")},
+		{"synchronization beta", liaison.synchronization["output"].Beta.Path, filepath.Join(filepath.Dir(projectDirectory), "output")},
+		{"home-relative synchronization alpha", liaison.synchronization["home"].Alpha.Path, filepath.Join(home, "configuration")},
+	}
+	for _, check := range checks {
+		if check.actual != check.expected {
+			t.Errorf("%s path mismatch: %s != %s", check.description, check.actual, check.expected)
+		}
+	}
+}
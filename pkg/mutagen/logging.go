@@ -0,0 +1,44 @@
+package mutagen
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetLogger sets the logger used by the liaison. Log messages carry structured
+// fields (such as the sidecar container, operation phase, and session name) and
+// are filtered according to the logger's level. If no logger is set, then the
+// standard logrus logger (which is configured by the Docker CLI's --log-level
+// flag) is used.
+func (l *Liaison) SetLogger(logger logrus.FieldLogger) {
+	l.logger = logger
+}
+
+// log returns the logger to use for the liaison.
+func (l *Liaison) log() logrus.FieldLogger {
+	if l.logger == nil {
+		return logrus.StandardLogger()
+	}
+	return l.logger
+}
+
+// sidecarLogger returns a logger annotated with the specified sidecar container
+// and operation phase.
+func (l *Liaison) sidecarLogger(sidecarID, phase string) logrus.FieldLogger {
+	return l.log().WithFields(logrus.Fields{
+		"sidecar": chopSidecarIdentifier(sidecarID),
+		"phase":   phase,
+	})
+}
+
+// logCompletion logs the completion of an operation that started at the
+// specified time, including its duration and, if it failed, its error.
+func logCompletion(logger logrus.FieldLogger, start time.Time, err error) {
+	logger = logger.WithField("duration", time.Since(start).Round(time.Millisecond))
+	if err != nil {
+		logger.WithError(err).Debug("Operation failed")
+		return
+	}
+	logger.Debug("Operation completed")
+}
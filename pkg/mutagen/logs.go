@@ -0,0 +1,45 @@
+package mutagen
+
+import (
+	"context"
+	"fmt"
+
+	moby "github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// SidecarLogs writes the logs of the Mutagen Compose sidecar container for the
+// specified project and sidecar group (with an empty group indicating the
+// primary sidecar container) to the Docker CLI's output and error streams. If
+// follow is
+// true, then logs are streamed until the context is cancelled or the container
+// exits. The tail argument specifies the number of lines to show from the end
+// of the logs, with "all" (or an empty string) indicating all lines.
+func (l *Liaison) SidecarLogs(ctx context.Context, projectName, group string, follow bool, tail string) error {
+	// Identify the sidecar container.
+	sidecarID, err := l.resolveSidecarID(ctx, projectName, group)
+	if err != nil {
+		return err
+	}
+
+	// Request the container logs.
+	logs, err := l.dockerCLI.Client().ContainerLogs(ctx, sidecarID, moby.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+		Tail:       tail,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to request Mutagen Compose sidecar container logs: %w", err)
+	}
+	defer logs.Close()
+
+	// Demultiplex and copy the log streams. The sidecar container doesn't use
+	// a TTY, so its output is always multiplexed.
+	if _, err := stdcopy.StdCopy(l.dockerCLI.Out(), l.dockerCLI.Err(), logs); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("unable to copy Mutagen Compose sidecar container logs: %w", err)
+	}
+
+	// Success.
+	return nil
+}
@@ -0,0 +1,30 @@
+package mutagen
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// isProjectRelativePath determines whether or not a raw local path (as specified
+// in the x-mutagen extension section) should be resolved relative to the
+// project directory. This is the case for any path that isn't absolute and
+// isn't home-directory-relative (i.e. starting with a tilde, which Mutagen's
+// own path normalization will have already expanded).
+func isProjectRelativePath(path string) bool {
+	if filepath.IsAbs(path) {
+		return false
+	} else if path == "~" || strings.HasPrefix(path, "~/") {
+		return false
+	} else if filepath.Separator != '/' && strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return false
+	}
+	return true
+}
+
+// resolveProjectRelativePath resolves a project-relative path against the
+// project directory. The project directory should be taken from the project's
+// WorkingDir field, which Compose sets based on the --project-directory flag
+// (if specified) or the directory containing the first Compose file.
+func resolveProjectRelativePath(projectDirectory, path string) (string, error) {
+	return filepath.Abs(filepath.Join(projectDirectory, path))
+}
@@ -16,16 +16,22 @@ type statusUpdater struct {
 	writer progress.Writer
 	// eventID is the identifier to use for events.
 	eventID string
+	// quiet indicates that only error events should be registered.
+	quiet bool
 }
 
 // newStatusUpdater extracts the Compose progress writer from the specified
-// context and constructs a new statusUpdater.
-func newStatusUpdater(ctx context.Context, eventID string) *statusUpdater {
-	return &statusUpdater{writer: progress.ContextWriter(ctx), eventID: eventID}
+// context and constructs a new statusUpdater. If quiet is true, then only error
+// events will be registered.
+func newStatusUpdater(ctx context.Context, eventID string, quiet bool) *statusUpdater {
+	return &statusUpdater{writer: progress.ContextWriter(ctx), eventID: eventID, quiet: quiet}
 }
 
 // working registers a normal working event.
 func (u *statusUpdater) working(description string) {
+	if u.quiet {
+		return
+	}
 	u.writer.Event(progress.NewEvent(u.eventID, progress.Working, description))
 }
 
@@ -36,6 +42,9 @@ func (u *statusUpdater) error(err error) {
 
 // done registers a done event.
 func (u *statusUpdater) done(description string) {
+	if u.quiet {
+		return
+	}
 	u.writer.Event(progress.NewEvent(u.eventID, progress.Done, description))
 }
 
@@ -0,0 +1,203 @@
+package mutagen
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/compose-spec/compose-go/types"
+
+	moby "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+
+	"google.golang.org/grpc"
+
+	"github.com/mutagen-io/mutagen/pkg/grpcutil"
+	"github.com/mutagen-io/mutagen/pkg/selection"
+	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
+	promptingsvc "github.com/mutagen-io/mutagen/pkg/service/prompting"
+	synchronizationsvc "github.com/mutagen-io/mutagen/pkg/service/synchronization"
+)
+
+// prunableSessionSelection selects all sessions created by Mutagen Compose, as
+// indicated by the presence of the sidecar label.
+var prunableSessionSelection = &selection.Selection{
+	LabelSelector: sessionSidecarLabelKey,
+}
+
+// existingSidecarIdentifiers returns the set of (chopped) identifiers of all
+// existing Mutagen Compose sidecar containers, across all projects, in the form
+// used for session labels.
+func (l *Liaison) existingSidecarIdentifiers(ctx context.Context) (map[string]bool, error) {
+	containers, err := l.dockerCLI.Client().ContainerList(ctx, moby.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", fmt.Sprintf("%s=%s", sidecarRoleLabelKey, sidecarRoleLabelValue)),
+		),
+		All: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query Mutagen sidecar containers: %w", err)
+	}
+	result := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		result[chopSidecarIdentifier(container.ID)] = true
+	}
+	return result, nil
+}
+
+// Prune identifies Mutagen sessions created by Mutagen Compose whose sidecar
+// containers no longer exist (e.g. because a project was removed without its
+// sessions being terminated) and terminates them. If dryRun is true, then the
+// sessions are only identified. Only sessions managed by the shared Mutagen
+// daemon are considered, since project-scoped daemons are only started for
+// their projects (and can be cleaned up using "down --remove-orphans"). It
+// returns the affected sessions, described by name and identifier, since names
+// may not be unique across projects.
+func (l *Liaison) Prune(ctx context.Context, dryRun bool) (*AffectedSessions, error) {
+	// Identify the existing sidecar containers across all projects.
+	sidecars, err := l.existingSidecarIdentifiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to the shared Mutagen daemon and defer release of the connection.
+	daemonConnection, err := l.connectToDaemon(false, "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
+	}
+	defer l.releaseDaemonConnection(daemonConnection)
+
+	// Prune sessions whose sidecar containers no longer exist.
+	return pruneSessions(ctx, daemonConnection, func(labels map[string]string) bool {
+		return !sidecars[labels[sessionSidecarLabelKey]]
+	}, dryRun)
+}
+
+// pruneProjectSessions terminates Mutagen sessions associated with the
+// specified project whose sidecar containers no longer exist. The daemon
+// responsible for the project is determined from the project's configuration
+// if it's been processed (i.e. if project is non-nil), otherwise from the
+// user-level default. Sessions in a project-scoped daemon all belong to the
+// project, but sessions in the shared daemon are attributed using their
+// project label. If the project-scoped daemon has never been used, then it
+// isn't started.
+func (l *Liaison) pruneProjectSessions(ctx context.Context, projectName string, project *types.Project) (*AffectedSessions, error) {
+	// Determine which daemon is responsible for the project's sessions.
+	isolated := l.projectDaemonIsolated(project)
+	if isolated {
+		if dataDirectory, err := isolatedDaemonDataDirectory(projectName); err != nil {
+			return nil, fmt.Errorf("unable to compute project-scoped data directory: %w", err)
+		} else if _, err := os.Stat(dataDirectory); os.IsNotExist(err) {
+			return &AffectedSessions{}, nil
+		}
+	}
+
+	// Identify the existing sidecar containers across all projects.
+	sidecars, err := l.existingSidecarIdentifiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect to the Mutagen daemon and defer release of the connection.
+	daemonConnection, err := l.connectToDaemon(isolated, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
+	}
+	defer l.releaseDaemonConnection(daemonConnection)
+
+	// Prune the project's sessions whose sidecar containers no longer exist.
+	return pruneSessions(ctx, daemonConnection, func(labels map[string]string) bool {
+		if sidecars[labels[sessionSidecarLabelKey]] {
+			return false
+		}
+		return isolated || labels[sessionProjectLabelKey] == projectName
+	}, false)
+}
+
+// pruneSessions terminates the Mutagen Compose sessions managed by the daemon
+// for which orphaned returns true when passed the session's labels. If dryRun
+// is true, then the sessions are only identified. It returns the affected
+// sessions, described by name and identifier.
+func pruneSessions(
+	ctx context.Context,
+	daemonConnection *grpc.ClientConn,
+	orphaned func(labels map[string]string) bool,
+	dryRun bool,
+) (*AffectedSessions, error) {
+	// Create service clients.
+	forwardingService := forwardingsvc.NewForwardingClient(daemonConnection)
+	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
+
+	// Identify orphaned forwarding sessions.
+	result := &AffectedSessions{}
+	forwardingListResponse, err := forwardingService.List(ctx, &forwardingsvc.ListRequest{Selection: prunableSessionSelection})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list forwarding sessions: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	} else if err = forwardingListResponse.EnsureValid(); err != nil {
+		return nil, fmt.Errorf("invalid forwarding session listing received: %w", err)
+	}
+	var forwardingIdentifiers []string
+	for _, state := range forwardingListResponse.SessionStates {
+		if orphaned(state.Session.Labels) {
+			forwardingIdentifiers = append(forwardingIdentifiers, state.Session.Identifier)
+			result.Forwarding = append(result.Forwarding,
+				fmt.Sprintf("%s (%s)", state.Session.Name, state.Session.Identifier),
+			)
+		}
+	}
+
+	// Identify orphaned synchronization sessions.
+	synchronizationListResponse, err := synchronizationService.List(ctx, &synchronizationsvc.ListRequest{Selection: prunableSessionSelection})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list synchronization sessions: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	} else if err = synchronizationListResponse.EnsureValid(); err != nil {
+		return nil, fmt.Errorf("invalid synchronization session listing received: %w", err)
+	}
+	var synchronizationIdentifiers []string
+	for _, state := range synchronizationListResponse.SessionStates {
+		if orphaned(state.Session.Labels) {
+			synchronizationIdentifiers = append(synchronizationIdentifiers, state.Session.Identifier)
+			result.Synchronization = append(result.Synchronization,
+				fmt.Sprintf("%s (%s)", state.Session.Name, state.Session.Identifier),
+			)
+		}
+	}
+
+	// If this is a dry run or there's nothing to prune, then we're done.
+	if dryRun || (len(forwardingIdentifiers) == 0 && len(synchronizationIdentifiers) == 0) {
+		return result, nil
+	}
+
+	// Initiate message-only prompting and defer its termination. Termination
+	// requires a prompter, but it doesn't need to display any messages.
+	status := newStatusUpdater(ctx, "Mutagen", true)
+	promptingCtx, promptingCancel := context.WithCancel(ctx)
+	prompter, promptingErrors, err := promptingsvc.Host(
+		promptingCtx, promptingsvc.NewPromptingClient(daemonConnection),
+		status, false,
+	)
+	defer func() {
+		promptingCancel()
+		<-promptingErrors
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("unable to initiate Mutagen prompting: %w", err)
+	}
+
+	// Terminate the orphaned sessions.
+	if len(forwardingIdentifiers) > 0 {
+		forwardingSelection := &selection.Selection{Specifications: forwardingIdentifiers}
+		if err := forwardingTerminateWithSelection(ctx, forwardingService, prompter, forwardingSelection); err != nil {
+			return nil, fmt.Errorf("unable to terminate forwarding sessions: %w", err)
+		}
+	}
+	if len(synchronizationIdentifiers) > 0 {
+		synchronizationSelection := &selection.Selection{Specifications: synchronizationIdentifiers}
+		if err := synchronizationTerminateWithSelection(ctx, synchronizationService, prompter, synchronizationSelection); err != nil {
+			return nil, fmt.Errorf("unable to terminate synchronization sessions: %w", err)
+		}
+	}
+
+	// Success.
+	return result, nil
+}
@@ -0,0 +1,288 @@
+package mutagen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"google.golang.org/grpc"
+
+	"github.com/mutagen-io/mutagen/pkg/forwarding"
+	"github.com/mutagen-io/mutagen/pkg/selection"
+	promptingsvc "github.com/mutagen-io/mutagen/pkg/service/prompting"
+	"github.com/mutagen-io/mutagen/pkg/synchronization"
+)
+
+const (
+	// sessionWaitTimeout is the maximum amount of time to wait for sessions to
+	// reach a steady state when waiting is requested.
+	sessionWaitTimeout = 2 * time.Minute
+	// sessionWaitPollingInterval is the interval at which session states are
+	// polled when waiting for sessions to reach a steady state.
+	sessionWaitPollingInterval = 500 * time.Millisecond
+	// sessionRollbackTimeout is the maximum amount of time to spend rolling
+	// back sessions created by an interrupted reconciliation.
+	sessionRollbackTimeout = 15 * time.Second
+)
+
+// InitialSynchronizationIncompleteExitCode is the exit code reported by up
+// operations that bring up a project but fail to complete initial
+// synchronization (e.g. because flushing or waiting for sessions failed). It's
+// distinct from Compose's failure exit codes so that automated environments can
+// detect this condition. It corresponds to EX_TEMPFAIL from sysexits.h.
+const InitialSynchronizationIncompleteExitCode = 75
+
+// ReconciliationResult summarizes the actions performed during Mutagen session
+// reconciliation for a project. Sessions are identified by their Mutagen
+// session identifiers.
+type ReconciliationResult struct {
+	// CreatedForwardingSessions maps the names of forwarding sessions created
+	// during reconciliation to their session identifiers.
+	CreatedForwardingSessions map[string]string
+	// CreatedSynchronizationSessions maps the names of synchronization
+	// sessions created during reconciliation to their session identifiers.
+	CreatedSynchronizationSessions map[string]string
+	// PrunedForwardingSessions are the identifiers of orphaned, duplicate, and
+	// stale forwarding sessions that were terminated during reconciliation.
+	PrunedForwardingSessions []string
+	// PrunedSynchronizationSessions are the identifiers of orphaned, duplicate,
+	// and stale synchronization sessions that were terminated during
+	// reconciliation.
+	PrunedSynchronizationSessions []string
+	// ResumedForwardingSessions are the identifiers of pre-existing forwarding
+	// sessions that were retained and resumed during reconciliation.
+	ResumedForwardingSessions []string
+	// ResumedSynchronizationSessions are the identifiers of pre-existing
+	// synchronization sessions that were retained and resumed during
+	// reconciliation.
+	ResumedSynchronizationSessions []string
+	// FlushedSynchronizationSessions are the identifiers of synchronization
+	// sessions that were flushed during reconciliation.
+	FlushedSynchronizationSessions []string
+	// DeferredForwardingSessions are the names of forwarding sessions whose
+	// creation was deferred because their destination service isn't running.
+	DeferredForwardingSessions []string
+}
+
+// merge merges the actions recorded in another reconciliation result into the
+// receiver.
+func (r *ReconciliationResult) merge(other *ReconciliationResult) {
+	if r.CreatedForwardingSessions == nil {
+		r.CreatedForwardingSessions = make(map[string]string)
+	}
+	for name, identifier := range other.CreatedForwardingSessions {
+		r.CreatedForwardingSessions[name] = identifier
+	}
+	if r.CreatedSynchronizationSessions == nil {
+		r.CreatedSynchronizationSessions = make(map[string]string)
+	}
+	for name, identifier := range other.CreatedSynchronizationSessions {
+		r.CreatedSynchronizationSessions[name] = identifier
+	}
+	r.PrunedForwardingSessions = append(r.PrunedForwardingSessions, other.PrunedForwardingSessions...)
+	r.PrunedSynchronizationSessions = append(r.PrunedSynchronizationSessions, other.PrunedSynchronizationSessions...)
+	r.ResumedForwardingSessions = append(r.ResumedForwardingSessions, other.ResumedForwardingSessions...)
+	r.ResumedSynchronizationSessions = append(r.ResumedSynchronizationSessions, other.ResumedSynchronizationSessions...)
+	r.FlushedSynchronizationSessions = append(r.FlushedSynchronizationSessions, other.FlushedSynchronizationSessions...)
+	r.DeferredForwardingSessions = append(r.DeferredForwardingSessions, other.DeferredForwardingSessions...)
+}
+
+// ReconciliationPhase indicates the point during reconciliation at which
+// session states were observed.
+type ReconciliationPhase uint8
+
+const (
+	// ReconciliationPhaseInitial indicates that session states were observed
+	// before any reconciliation actions were performed.
+	ReconciliationPhaseInitial ReconciliationPhase = iota
+	// ReconciliationPhaseComplete indicates that session states were observed
+	// after all reconciliation actions (including the flushing of newly
+	// created synchronization sessions) were performed.
+	ReconciliationPhaseComplete
+)
+
+// SessionStateUpdate encodes the states of a project's Mutagen sessions at a
+// particular point during reconciliation.
+type SessionStateUpdate struct {
+	// Phase is the reconciliation phase at which the states were observed.
+	Phase ReconciliationPhase
+	// ForwardingStates are the states of the project's forwarding sessions.
+	ForwardingStates []*forwarding.State
+	// SynchronizationStates are the states of the project's synchronization
+	// sessions.
+	SynchronizationStates []*synchronization.State
+}
+
+// SessionStateCallback is a callback that receives session state updates. It
+// is invoked synchronously, so it should not block for long periods of time.
+type SessionStateCallback func(update *SessionStateUpdate)
+
+// sessionSupersedes determines whether or not a session (identified by its
+// creation time and identifier) should be retained in preference to a duplicate
+// session with the same name. The most recently created session is preferred,
+// with ties broken by identifier, so that the result doesn't depend on the
+// order in which sessions are listed.
+func sessionSupersedes(creationTime time.Time, identifier string, otherCreationTime time.Time, otherIdentifier string) bool {
+	if !creationTime.Equal(otherCreationTime) {
+		return creationTime.After(otherCreationTime)
+	}
+	return identifier > otherIdentifier
+}
+
+// waitForSteadySessions waits until all selected forwarding sessions are
+// forwarding connections and all selected synchronization sessions are watching
+// for changes. If sessions fail to reach this state before sessionWaitTimeout
+// elapses, then an error identifying those sessions is returned.
+func waitForSteadySessions(
+	ctx context.Context,
+	forwardingService forwardingSessionService,
+	synchronizationService synchronizationSessionService,
+	selection *selection.Selection,
+) error {
+	// Create a timeout context and defer its cancellation.
+	ctx, cancel := context.WithTimeout(ctx, sessionWaitTimeout)
+	defer cancel()
+
+	// Create a ticker to regulate polling.
+	ticker := time.NewTicker(sessionWaitPollingInterval)
+	defer ticker.Stop()
+
+	// Poll until all sessions have reached a steady state.
+	for {
+		// Identify forwarding sessions that haven't reached a steady state.
+		var pending []string
+		forwardingStates, err := forwardingService.List(ctx, selection)
+		if err != nil {
+			return fmt.Errorf("forwarding session listing failed: %w", err)
+		}
+		for _, state := range forwardingStates {
+			if state.Status != forwarding.Status_ForwardingConnections {
+				pending = append(pending, state.Session.Name)
+			}
+		}
+
+		// Identify synchronization sessions that haven't reached a steady
+		// state.
+		synchronizationStates, err := synchronizationService.List(ctx, selection)
+		if err != nil {
+			return fmt.Errorf("synchronization session listing failed: %w", err)
+		}
+		for _, state := range synchronizationStates {
+			if state.Status != synchronization.Status_Watching {
+				pending = append(pending, state.Session.Name)
+			}
+		}
+
+		// If all sessions have reached a steady state, then we're done.
+		if len(pending) == 0 {
+			return nil
+		}
+
+		// Wait for the next polling cycle.
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				sort.Strings(pending)
+				return fmt.Errorf("sessions failed to reach a steady state: %s", strings.Join(pending, ", "))
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollbackSessions terminates the selected sessions with the specified names on
+// a best-effort basis, logging any failures to the specified logger. It's used
+// to roll back sessions created by an interrupted reconciliation, so it uses
+// its own context (since the reconciliation context (and any prompter hosted
+// with it) will have been cancelled). Since any existing sessions with these
+// names are pruned before creation, all matching sessions are assumed to have
+// been created by the interrupted reconciliation.
+func rollbackSessions(
+	logger logrus.FieldLogger,
+	daemonConnection *grpc.ClientConn,
+	status *statusUpdater,
+	projectSelection *selection.Selection,
+	forwardingNames, synchronizationNames []string,
+) {
+	// If there's nothing to roll back, then we're done.
+	if len(forwardingNames) == 0 && len(synchronizationNames) == 0 {
+		return
+	}
+
+	// Create a rollback context and defer its cancellation.
+	ctx, cancel := context.WithTimeout(context.Background(), sessionRollbackTimeout)
+	defer cancel()
+
+	// Initiate message-only prompting and defer its termination.
+	promptingCtx, promptingCancel := context.WithCancel(ctx)
+	prompter, promptingErrors, err := promptingsvc.Host(
+		promptingCtx, promptingsvc.NewPromptingClient(daemonConnection),
+		status, false,
+	)
+	defer func() {
+		promptingCancel()
+		<-promptingErrors
+	}()
+	if err != nil {
+		logger.Warnf("unable to initiate Mutagen prompting for rollback: %v", err)
+		return
+	}
+
+	// Create session services.
+	forwardingService := newDaemonForwardingSessionService(daemonConnection)
+	synchronizationService := newDaemonSynchronizationSessionService(daemonConnection)
+
+	// Roll back forwarding sessions.
+	if len(forwardingNames) > 0 {
+		names := make(map[string]bool, len(forwardingNames))
+		for _, name := range forwardingNames {
+			names[name] = true
+		}
+		var identifiers []string
+		if states, err := forwardingService.List(ctx, projectSelection); err != nil {
+			logger.Warnf("unable to list forwarding sessions for rollback: %v", err)
+		} else {
+			for _, state := range states {
+				if names[state.Session.Name] {
+					identifiers = append(identifiers, state.Session.Identifier)
+				}
+			}
+		}
+		if len(identifiers) > 0 {
+			rollbackSelection := &selection.Selection{Specifications: identifiers}
+			if err := forwardingService.Terminate(ctx, prompter, rollbackSelection); err != nil {
+				logger.Warnf("unable to roll back forwarding sessions: %v", err)
+			}
+		}
+	}
+
+	// Roll back synchronization sessions.
+	if len(synchronizationNames) > 0 {
+		names := make(map[string]bool, len(synchronizationNames))
+		for _, name := range synchronizationNames {
+			names[name] = true
+		}
+		var identifiers []string
+		if states, err := synchronizationService.List(ctx, projectSelection); err != nil {
+			logger.Warnf("unable to list synchronization sessions for rollback: %v", err)
+		} else {
+			for _, state := range states {
+				if names[state.Session.Name] {
+					identifiers = append(identifiers, state.Session.Identifier)
+				}
+			}
+		}
+		if len(identifiers) > 0 {
+			rollbackSelection := &selection.Selection{Specifications: identifiers}
+			if err := synchronizationService.Terminate(ctx, prompter, rollbackSelection); err != nil {
+				logger.Warnf("unable to roll back synchronization sessions: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package mutagen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mutagen-io/mutagen/pkg/forwarding"
+	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
+	synchronizationsvc "github.com/mutagen-io/mutagen/pkg/service/synchronization"
+	"github.com/mutagen-io/mutagen/pkg/synchronization"
+)
+
+// TestSessionSupersedes tests sessionSupersedes.
+func TestSessionSupersedes(t *testing.T) {
+	earlier := testSessionCreationTime
+	later := testSessionCreationTime.Add(time.Second)
+	testCases := []struct {
+		description       string
+		creationTime      time.Time
+		identifier        string
+		otherCreationTime time.Time
+		otherIdentifier   string
+		expected          bool
+	}{
+		{"newer session", later, "a", earlier, "b", true},
+		{"older session", earlier, "b", later, "a", false},
+		{"tie with greater identifier", earlier, "b", earlier, "a", true},
+		{"tie with lesser identifier", earlier, "a", earlier, "b", false},
+		{"identical session", earlier, "a", earlier, "a", false},
+		{"equal instants in different zones", earlier.In(time.FixedZone("test", 3600)), "b", earlier, "a", true},
+	}
+	for _, testCase := range testCases {
+		supersedes := sessionSupersedes(
+			testCase.creationTime, testCase.identifier,
+			testCase.otherCreationTime, testCase.otherIdentifier,
+		)
+		if supersedes != testCase.expected {
+			t.Errorf("%s: result mismatch: %t != %t", testCase.description, supersedes, testCase.expected)
+		}
+	}
+}
+
+// TestReconcileSessionsDiff tests the decisions made by session reconciliation
+// about which existing sessions to prune and retain and which sessions to
+// create.
+func TestReconcileSessionsDiff(t *testing.T) {
+	// Create the desired session specifications.
+	databaseSpecification := testForwardingSpecification("database", "tcp:localhost:5432")
+	codeSpecification := testSynchronizationSpecification("code", "/project")
+
+	// Create specifications for equivalent sessions created by a different
+	// version of Mutagen.
+	staleVersionDatabaseSpecification := testForwardingSpecification("database", "tcp:localhost:5432")
+	staleVersionDatabaseSpecification.Labels[sessionVersionLabelKey] = "0.13.0"
+	staleVersionCodeSpecification := testSynchronizationSpecification("code", "/project")
+	staleVersionCodeSpecification.Labels[sessionVersionLabelKey] = "0.13.0"
+
+	// Create the testing table. Test cases use the desired session
+	// specifications above unless they specify their own.
+	testCases := []struct {
+		description                    string
+		forwardingSpecifications       map[string]*forwardingsvc.CreationSpecification
+		synchronizationSpecifications  map[string]*synchronizationsvc.CreationSpecification
+		forwarding                     []*forwarding.Session
+		synchronization                []*synchronization.Session
+		keepOrphans                    bool
+		force                          bool
+		deferred                       map[string]bool
+		expectedPrunedForwarding       []string
+		expectedResumedForwarding      []string
+		expectedCreatedForwarding      []string
+		expectedDeferredForwarding     []string
+		expectedPrunedSynchronization  []string
+		expectedResumedSynchronization []string
+		expectedCreatedSynchronization []string
+	}{
+		{
+			description:                    "missing sessions",
+			expectedCreatedForwarding:      []string{"database"},
+			expectedCreatedSynchronization: []string{"code"},
+		},
+		{
+			description: "missing sessions created in name order",
+			forwardingSpecifications: map[string]*forwardingsvc.CreationSpecification{
+				"web":      testForwardingSpecification("web", "tcp:localhost:8080"),
+				"cache":    testForwardingSpecification("cache", "tcp:localhost:6379"),
+				"database": databaseSpecification,
+			},
+			synchronizationSpecifications: map[string]*synchronizationsvc.CreationSpecification{
+				"code":    codeSpecification,
+				"assets":  testSynchronizationSpecification("assets", "/assets"),
+				"vendor":  testSynchronizationSpecification("vendor", "/vendor"),
+				"content": testSynchronizationSpecification("content", "/content"),
+			},
+			expectedCreatedForwarding:      []string{"cache", "database", "web"},
+			expectedCreatedSynchronization: []string{"assets", "code", "content", "vendor"},
+		},
+		{
+			description: "current sessions",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, databaseSpecification),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, codeSpecification),
+			},
+			expectedResumedForwarding:      []string{"f1"},
+			expectedResumedSynchronization: []string{"s1"},
+		},
+		{
+			description: "stale configuration",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, testForwardingSpecification("database", "tcp:localhost:6543")),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, testSynchronizationSpecification("code", "/other")),
+			},
+			expectedPrunedForwarding:       []string{"f1"},
+			expectedCreatedForwarding:      []string{"database"},
+			expectedPrunedSynchronization:  []string{"s1"},
+			expectedCreatedSynchronization: []string{"code"},
+		},
+		{
+			description: "stale Mutagen version",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, staleVersionDatabaseSpecification),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, staleVersionCodeSpecification),
+			},
+			expectedPrunedForwarding:       []string{"f1"},
+			expectedCreatedForwarding:      []string{"database"},
+			expectedPrunedSynchronization:  []string{"s1"},
+			expectedCreatedSynchronization: []string{"code"},
+		},
+		{
+			description: "forced recreation",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, databaseSpecification),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, codeSpecification),
+			},
+			force:                          true,
+			expectedPrunedForwarding:       []string{"f1"},
+			expectedCreatedForwarding:      []string{"database"},
+			expectedPrunedSynchronization:  []string{"s1"},
+			expectedCreatedSynchronization: []string{"code"},
+		},
+		{
+			description: "duplicate names",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, databaseSpecification),
+				testForwardingSession("f2", time.Hour, databaseSpecification),
+				testForwardingSession("f3", time.Minute, databaseSpecification),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s2", time.Hour, codeSpecification),
+				testSynchronizationSession("s1", 0, codeSpecification),
+			},
+			expectedPrunedForwarding:       []string{"f1", "f3"},
+			expectedResumedForwarding:      []string{"f2"},
+			expectedPrunedSynchronization:  []string{"s1"},
+			expectedResumedSynchronization: []string{"s2"},
+		},
+		{
+			description: "duplicate names with identical creation times",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f2", 0, databaseSpecification),
+				testForwardingSession("f1", 0, databaseSpecification),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, codeSpecification),
+				testSynchronizationSession("s2", 0, codeSpecification),
+			},
+			expectedPrunedForwarding:       []string{"f1"},
+			expectedResumedForwarding:      []string{"f2"},
+			expectedPrunedSynchronization:  []string{"s1"},
+			expectedResumedSynchronization: []string{"s2"},
+		},
+		{
+			description: "duplicate names with stale newest session",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, databaseSpecification),
+				testForwardingSession("f2", time.Hour, testForwardingSpecification("database", "tcp:localhost:6543")),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, codeSpecification),
+				testSynchronizationSession("s2", time.Hour, testSynchronizationSpecification("code", "/other")),
+			},
+			expectedPrunedForwarding:       []string{"f1", "f2"},
+			expectedCreatedForwarding:      []string{"database"},
+			expectedPrunedSynchronization:  []string{"s1", "s2"},
+			expectedCreatedSynchronization: []string{"code"},
+		},
+		{
+			description: "orphan sessions",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, databaseSpecification),
+				testForwardingSession("f2", 0, testForwardingSpecification("cache", "tcp:localhost:6379")),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, codeSpecification),
+				testSynchronizationSession("s2", 0, testSynchronizationSpecification("assets", "/assets")),
+			},
+			expectedPrunedForwarding:       []string{"f2"},
+			expectedResumedForwarding:      []string{"f1"},
+			expectedPrunedSynchronization:  []string{"s2"},
+			expectedResumedSynchronization: []string{"s1"},
+		},
+		{
+			description: "kept orphan sessions",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, databaseSpecification),
+				testForwardingSession("f2", 0, testForwardingSpecification("cache", "tcp:localhost:6379")),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, codeSpecification),
+				testSynchronizationSession("s2", 0, testSynchronizationSpecification("assets", "/assets")),
+			},
+			keepOrphans:                    true,
+			expectedResumedForwarding:      []string{"f1"},
+			expectedResumedSynchronization: []string{"s1"},
+		},
+		{
+			description:                    "deferred missing session",
+			deferred:                       map[string]bool{"database": true},
+			expectedDeferredForwarding:     []string{"database"},
+			expectedCreatedSynchronization: []string{"code"},
+		},
+		{
+			description: "deferred stale session",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, testForwardingSpecification("database", "tcp:localhost:6543")),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, codeSpecification),
+			},
+			deferred:                       map[string]bool{"database": true},
+			expectedPrunedForwarding:       []string{"f1"},
+			expectedDeferredForwarding:     []string{"database"},
+			expectedResumedSynchronization: []string{"s1"},
+		},
+		{
+			description: "deferred current session",
+			forwarding: []*forwarding.Session{
+				testForwardingSession("f1", 0, databaseSpecification),
+			},
+			synchronization: []*synchronization.Session{
+				testSynchronizationSession("s1", 0, codeSpecification),
+			},
+			deferred:                       map[string]bool{"database": true},
+			expectedResumedForwarding:      []string{"f1"},
+			expectedResumedSynchronization: []string{"s1"},
+		},
+	}
+
+	// Process test cases.
+	for _, testCase := range testCases {
+		forwardingSpecifications := testCase.forwardingSpecifications
+		if forwardingSpecifications == nil {
+			forwardingSpecifications = map[string]*forwardingsvc.CreationSpecification{"database": databaseSpecification}
+		}
+		synchronizationSpecifications := testCase.synchronizationSpecifications
+		if synchronizationSpecifications == nil {
+			synchronizationSpecifications = map[string]*synchronizationsvc.CreationSpecification{"code": codeSpecification}
+		}
+		forwardingService := &fakeForwardingSessionService{sessions: testCase.forwarding}
+		synchronizationService := &fakeSynchronizationSessionService{sessions: testCase.synchronization}
+		result, err := reconcileTestSessions(
+			&Liaison{keepOrphanSessions: testCase.keepOrphans},
+			forwardingService, synchronizationService,
+			forwardingSpecifications, synchronizationSpecifications,
+			testCase.deferred, testCase.force,
+		)
+		if err != nil {
+			t.Errorf("%s: reconciliation failed: %v", testCase.description, err)
+			continue
+		}
+		// Sessions are created in name order, so creation is checked in order.
+		checks := []struct {
+			subject  string
+			actual   []string
+			expected []string
+			ordered  bool
+		}{
+			{"pruned forwarding sessions", result.PrunedForwardingSessions, testCase.expectedPrunedForwarding, false},
+			{"terminated forwarding sessions", forwardingService.terminated, testCase.expectedPrunedForwarding, false},
+			{"resumed forwarding sessions", result.ResumedForwardingSessions, testCase.expectedResumedForwarding, false},
+			{"created forwarding sessions", forwardingService.created, testCase.expectedCreatedForwarding, true},
+			{"deferred forwarding sessions", result.DeferredForwardingSessions, testCase.expectedDeferredForwarding, false},
+			{"pruned synchronization sessions", result.PrunedSynchronizationSessions, testCase.expectedPrunedSynchronization, false},
+			{"terminated synchronization sessions", synchronizationService.terminated, testCase.expectedPrunedSynchronization, false},
+			{"resumed synchronization sessions", result.ResumedSynchronizationSessions, testCase.expectedResumedSynchronization, false},
+			{"created synchronization sessions", synchronizationService.created, testCase.expectedCreatedSynchronization, true},
+		}
+		for _, check := range checks {
+			if check.ordered && !stringsEqualOrdered(check.actual, check.expected) {
+				t.Errorf("%s: %s mismatch (in order): %v != %v", testCase.description, check.subject, check.actual, check.expected)
+			} else if !check.ordered && !stringsEqual(check.actual, check.expected) {
+				t.Errorf("%s: %s mismatch: %v != %v", testCase.description, check.subject, check.actual, check.expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package mutagen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/compose-spec/compose-go/types"
+
+	"github.com/docker/compose/v2/pkg/progress"
+)
+
+// Reload re-processes the specified project and reconciles Mutagen sessions
+// against the project's existing sidecar containers, creating newly defined
+// sessions and pruning removed ones without recreating the sidecar containers
+// or any other services. This is only possible if the running sidecar
+// containers are compatible with the updated configuration, so it first checks
+// that every sidecar service has a running container with the required volume
+// mounts and network attachments. If not (e.g. because a session references a
+// new volume or sidecar group), then an error is returned indicating that the
+// project needs to be brought up again. It may be called multiple times (e.g.
+// when watching for configuration changes), with each call reprocessing the
+// specified project. It returns a summary of the actions performed.
+func (l *Liaison) Reload(ctx context.Context, project *types.Project) (*ReconciliationResult, error) {
+	// Process Mutagen extensions for the project, discarding the results of
+	// any previous processing.
+	l.processedProject = false
+	if err := l.processProject(project); err != nil {
+		return nil, fmt.Errorf("unable to process project: %w", err)
+	}
+
+	// Identify the sidecar containers and index them by group.
+	sidecars, err := l.sidecarContainers(ctx, project.Name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to identify Mutagen Compose sidecar containers: %w", err)
+	}
+	sidecarIDsByGroup := make(map[string]string, len(sidecars))
+	for _, sidecar := range sidecars {
+		sidecarIDsByGroup[sidecar.Labels[sidecarGroupLabelKey]] = sidecar.ID
+	}
+
+	// Ensure that each sidecar service has a compatible running container.
+	var sidecarIDs []string
+	for _, service := range l.sidecarServices() {
+		group := service.Labels[sidecarGroupLabelKey]
+		sidecarID, ok := sidecarIDsByGroup[group]
+		if !ok {
+			notFound := &SidecarNotFoundError{Project: project.Name, Group: group}
+			if group != "" {
+				return nil, fmt.Errorf("%w (run \"up\" to apply changes)", notFound)
+			}
+			return nil, notFound
+		}
+		if err := l.ensureSidecarContainerCompatible(ctx, project, service, sidecarID); err != nil {
+			return nil, err
+		}
+		sidecarIDs = append(sidecarIDs, sidecarID)
+	}
+
+	// Perform reconciliation for each sidecar container with progress
+	// reporting.
+	result := &ReconciliationResult{}
+	err = progress.Run(ctx, func(ctx context.Context) error {
+		for _, sidecarID := range sidecarIDs {
+			sidecarResult, err := l.reconcileSessions(ctx, sidecarID, false)
+			if err != nil {
+				return err
+			}
+			result.merge(sidecarResult)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ensureSidecarContainerCompatible ensures that the specified sidecar container
+// is running and has the volume mounts and network attachments required by the
+// specified sidecar service definition.
+func (l *Liaison) ensureSidecarContainerCompatible(ctx context.Context, project *types.Project, service types.ServiceConfig, sidecarID string) error {
+	// Inspect the container and ensure that it's running.
+	metadata, err := l.dockerCLI.Client().ContainerInspect(ctx, sidecarID)
+	if err != nil {
+		return fmt.Errorf("unable to inspect Mutagen Compose sidecar container: %w", err)
+	} else if metadata.State == nil || !metadata.State.Running {
+		return fmt.Errorf("Mutagen Compose sidecar service (%s) is not running", service.Name)
+	}
+
+	// Ensure that all required mounts are present and writable if necessary.
+	writable := make(map[string]bool, len(metadata.Mounts))
+	for _, mount := range metadata.Mounts {
+		writable[mount.Destination] = mount.RW
+	}
+	for _, volume := range service.Volumes {
+		if rw, ok := writable[volume.Target]; !ok || (!volume.ReadOnly && !rw) {
+			return fmt.Errorf("Mutagen Compose sidecar service (%s) is missing mount for %s (run \"up\" to apply changes)",
+				service.Name, volume.Source,
+			)
+		}
+	}
+
+	// Ensure that all required networks are attached.
+	for key := range service.Networks {
+		name := key
+		if network, ok := project.Networks[key]; ok && network.Name != "" {
+			name = network.Name
+		}
+		if metadata.NetworkSettings == nil || metadata.NetworkSettings.Networks[name] == nil {
+			return fmt.Errorf("Mutagen Compose sidecar service (%s) isn't attached to network %s (run \"up\" to apply changes)",
+				service.Name, key,
+			)
+		}
+	}
+
+	// Success.
+	return nil
+}
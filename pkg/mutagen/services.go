@@ -0,0 +1,133 @@
+package mutagen
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	"github.com/mutagen-io/mutagen/pkg/forwarding"
+	"github.com/mutagen-io/mutagen/pkg/grpcutil"
+	"github.com/mutagen-io/mutagen/pkg/selection"
+	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
+	synchronizationsvc "github.com/mutagen-io/mutagen/pkg/service/synchronization"
+	"github.com/mutagen-io/mutagen/pkg/synchronization"
+)
+
+// forwardingSessionService provides the forwarding session operations used
+// during reconciliation. It allows reconciliation logic to be decoupled from
+// the Mutagen daemon.
+type forwardingSessionService interface {
+	// List returns the states of the selected forwarding sessions.
+	List(ctx context.Context, selection *selection.Selection) ([]*forwarding.State, error)
+	// Create creates a forwarding session and returns its identifier.
+	Create(ctx context.Context, prompter string, specification *forwardingsvc.CreationSpecification) (string, error)
+	// Resume resumes the selected forwarding sessions.
+	Resume(ctx context.Context, prompter string, selection *selection.Selection) error
+	// Terminate terminates the selected forwarding sessions.
+	Terminate(ctx context.Context, prompter string, selection *selection.Selection) error
+}
+
+// synchronizationSessionService provides the synchronization session
+// operations used during reconciliation. It allows reconciliation logic to be
+// decoupled from the Mutagen daemon.
+type synchronizationSessionService interface {
+	// List returns the states of the selected synchronization sessions.
+	List(ctx context.Context, selection *selection.Selection) ([]*synchronization.State, error)
+	// Create creates a synchronization session and returns its identifier.
+	Create(ctx context.Context, prompter string, specification *synchronizationsvc.CreationSpecification) (string, error)
+	// Flush flushes the selected synchronization sessions.
+	Flush(ctx context.Context, prompter string, selection *selection.Selection) error
+	// Resume resumes the selected synchronization sessions.
+	Resume(ctx context.Context, prompter string, selection *selection.Selection) error
+	// Terminate terminates the selected synchronization sessions.
+	Terminate(ctx context.Context, prompter string, selection *selection.Selection) error
+}
+
+// daemonForwardingSessionService implements forwardingSessionService using a
+// Mutagen daemon forwarding service client.
+type daemonForwardingSessionService struct {
+	// client is the underlying forwarding service client.
+	client forwardingsvc.ForwardingClient
+}
+
+// newDaemonForwardingSessionService creates a new forwarding session service
+// backed by the specified Mutagen daemon connection.
+func newDaemonForwardingSessionService(daemonConnection *grpc.ClientConn) *daemonForwardingSessionService {
+	return &daemonForwardingSessionService{
+		client: forwardingsvc.NewForwardingClient(daemonConnection),
+	}
+}
+
+// List implements forwardingSessionService.List.
+func (s *daemonForwardingSessionService) List(ctx context.Context, selection *selection.Selection) ([]*forwarding.State, error) {
+	response, err := s.client.List(ctx, &forwardingsvc.ListRequest{Selection: selection})
+	if err != nil {
+		return nil, grpcutil.PeelAwayRPCErrorLayer(err)
+	} else if err = response.EnsureValid(); err != nil {
+		return nil, fmt.Errorf("invalid list response received: %w", err)
+	}
+	return response.SessionStates, nil
+}
+
+// Create implements forwardingSessionService.Create.
+func (s *daemonForwardingSessionService) Create(ctx context.Context, prompter string, specification *forwardingsvc.CreationSpecification) (string, error) {
+	return forwardingCreateWithSpecification(ctx, s.client, prompter, specification)
+}
+
+// Resume implements forwardingSessionService.Resume.
+func (s *daemonForwardingSessionService) Resume(ctx context.Context, prompter string, selection *selection.Selection) error {
+	return forwardingResumeWithSelection(ctx, s.client, prompter, selection)
+}
+
+// Terminate implements forwardingSessionService.Terminate.
+func (s *daemonForwardingSessionService) Terminate(ctx context.Context, prompter string, selection *selection.Selection) error {
+	return forwardingTerminateWithSelection(ctx, s.client, prompter, selection)
+}
+
+// daemonSynchronizationSessionService implements
+// synchronizationSessionService using a Mutagen daemon synchronization service
+// client.
+type daemonSynchronizationSessionService struct {
+	// client is the underlying synchronization service client.
+	client synchronizationsvc.SynchronizationClient
+}
+
+// newDaemonSynchronizationSessionService creates a new synchronization session
+// service backed by the specified Mutagen daemon connection.
+func newDaemonSynchronizationSessionService(daemonConnection *grpc.ClientConn) *daemonSynchronizationSessionService {
+	return &daemonSynchronizationSessionService{
+		client: synchronizationsvc.NewSynchronizationClient(daemonConnection),
+	}
+}
+
+// List implements synchronizationSessionService.List.
+func (s *daemonSynchronizationSessionService) List(ctx context.Context, selection *selection.Selection) ([]*synchronization.State, error) {
+	response, err := s.client.List(ctx, &synchronizationsvc.ListRequest{Selection: selection})
+	if err != nil {
+		return nil, grpcutil.PeelAwayRPCErrorLayer(err)
+	} else if err = response.EnsureValid(); err != nil {
+		return nil, fmt.Errorf("invalid list response received: %w", err)
+	}
+	return response.SessionStates, nil
+}
+
+// Create implements synchronizationSessionService.Create.
+func (s *daemonSynchronizationSessionService) Create(ctx context.Context, prompter string, specification *synchronizationsvc.CreationSpecification) (string, error) {
+	return synchronizationCreateWithSpecification(ctx, s.client, prompter, specification)
+}
+
+// Flush implements synchronizationSessionService.Flush.
+func (s *daemonSynchronizationSessionService) Flush(ctx context.Context, prompter string, selection *selection.Selection) error {
+	return synchronizationFlushWithSelection(ctx, s.client, prompter, selection)
+}
+
+// Resume implements synchronizationSessionService.Resume.
+func (s *daemonSynchronizationSessionService) Resume(ctx context.Context, prompter string, selection *selection.Selection) error {
+	return synchronizationResumeWithSelection(ctx, s.client, prompter, selection)
+}
+
+// Terminate implements synchronizationSessionService.Terminate.
+func (s *daemonSynchronizationSessionService) Terminate(ctx context.Context, prompter string, selection *selection.Selection) error {
+	return synchronizationTerminateWithSelection(ctx, s.client, prompter, selection)
+}
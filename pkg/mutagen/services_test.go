@@ -0,0 +1,426 @@
+package mutagen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mutagen-io/mutagen/pkg/forwarding"
+	"github.com/mutagen-io/mutagen/pkg/selection"
+	forwardingsvc "github.com/mutagen-io/mutagen/pkg/service/forwarding"
+	synchronizationsvc "github.com/mutagen-io/mutagen/pkg/service/synchronization"
+	"github.com/mutagen-io/mutagen/pkg/synchronization"
+	"github.com/mutagen-io/mutagen/pkg/url"
+)
+
+// testSessionCreationTime is the base creation time used for existing sessions
+// in tests.
+var testSessionCreationTime = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeSessionSelected determines whether or not a fake session is selected by
+// the specified selection. Label selectors are ignored since fake services only
+// contain sessions for the project under test.
+func fakeSessionSelected(selection *selection.Selection, identifier, name string) bool {
+	if len(selection.Specifications) == 0 {
+		return true
+	}
+	for _, specification := range selection.Specifications {
+		if specification == identifier || specification == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeForwardingSessionService is an in-memory forwardingSessionService for
+// reconciliation tests. It records the operations performed on it.
+type fakeForwardingSessionService struct {
+	// lock serializes access to the service.
+	lock sync.Mutex
+	// sessions are the existing sessions.
+	sessions []*forwarding.Session
+	// created are the names of created sessions, in creation order.
+	created []string
+	// terminated are the identifiers of terminated sessions.
+	terminated []string
+	// resumed indicates whether or not a resume operation was performed.
+	resumed bool
+}
+
+// List implements forwardingSessionService.List.
+func (s *fakeForwardingSessionService) List(_ context.Context, selection *selection.Selection) ([]*forwarding.State, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var states []*forwarding.State
+	for _, session := range s.sessions {
+		if fakeSessionSelected(selection, session.Identifier, session.Name) {
+			states = append(states, &forwarding.State{
+				Session: session,
+				Status:  forwarding.Status_ForwardingConnections,
+			})
+		}
+	}
+	return states, nil
+}
+
+// Create implements forwardingSessionService.Create.
+func (s *fakeForwardingSessionService) Create(_ context.Context, _ string, specification *forwardingsvc.CreationSpecification) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	identifier := fmt.Sprintf("forwarding_created_%s", specification.Name)
+	s.sessions = append(s.sessions, &forwarding.Session{
+		Identifier:               identifier,
+		CreationTime:             timestamppb.Now(),
+		Source:                   specification.Source,
+		Destination:              specification.Destination,
+		Configuration:            specification.Configuration,
+		ConfigurationSource:      specification.ConfigurationSource,
+		ConfigurationDestination: specification.ConfigurationDestination,
+		Name:                     specification.Name,
+		Labels:                   specification.Labels,
+		Paused:                   specification.Paused,
+	})
+	s.created = append(s.created, specification.Name)
+	return identifier, nil
+}
+
+// Resume implements forwardingSessionService.Resume.
+func (s *fakeForwardingSessionService) Resume(_ context.Context, _ string, selection *selection.Selection) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, session := range s.sessions {
+		if fakeSessionSelected(selection, session.Identifier, session.Name) {
+			session.Paused = false
+		}
+	}
+	s.resumed = true
+	return nil
+}
+
+// Terminate implements forwardingSessionService.Terminate.
+func (s *fakeForwardingSessionService) Terminate(_ context.Context, _ string, selection *selection.Selection) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var retained []*forwarding.Session
+	for _, session := range s.sessions {
+		if fakeSessionSelected(selection, session.Identifier, session.Name) {
+			s.terminated = append(s.terminated, session.Identifier)
+		} else {
+			retained = append(retained, session)
+		}
+	}
+	s.sessions = retained
+	return nil
+}
+
+// fakeSynchronizationSessionService is an in-memory
+// synchronizationSessionService for reconciliation tests. It records the
+// operations performed on it.
+type fakeSynchronizationSessionService struct {
+	// lock serializes access to the service.
+	lock sync.Mutex
+	// sessions are the existing sessions.
+	sessions []*synchronization.Session
+	// created are the names of created sessions, in creation order.
+	created []string
+	// terminated are the identifiers of terminated sessions.
+	terminated []string
+	// flushed are the identifiers of flushed sessions.
+	flushed []string
+	// resumed indicates whether or not a resume operation was performed.
+	resumed bool
+}
+
+// List implements synchronizationSessionService.List.
+func (s *fakeSynchronizationSessionService) List(_ context.Context, selection *selection.Selection) ([]*synchronization.State, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var states []*synchronization.State
+	for _, session := range s.sessions {
+		if fakeSessionSelected(selection, session.Identifier, session.Name) {
+			states = append(states, &synchronization.State{
+				Session:        session,
+				Status:         synchronization.Status_Watching,
+				AlphaConnected: true,
+				BetaConnected:  true,
+			})
+		}
+	}
+	return states, nil
+}
+
+// Create implements synchronizationSessionService.Create.
+func (s *fakeSynchronizationSessionService) Create(_ context.Context, _ string, specification *synchronizationsvc.CreationSpecification) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	identifier := fmt.Sprintf("synchronization_created_%s", specification.Name)
+	s.sessions = append(s.sessions, &synchronization.Session{
+		Identifier:         identifier,
+		CreationTime:       timestamppb.Now(),
+		Alpha:              specification.Alpha,
+		Beta:               specification.Beta,
+		Configuration:      specification.Configuration,
+		ConfigurationAlpha: specification.ConfigurationAlpha,
+		ConfigurationBeta:  specification.ConfigurationBeta,
+		Name:               specification.Name,
+		Labels:             specification.Labels,
+		Paused:             specification.Paused,
+	})
+	s.created = append(s.created, specification.Name)
+	return identifier, nil
+}
+
+// Flush implements synchronizationSessionService.Flush.
+func (s *fakeSynchronizationSessionService) Flush(_ context.Context, _ string, selection *selection.Selection) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, session := range s.sessions {
+		if fakeSessionSelected(selection, session.Identifier, session.Name) {
+			s.flushed = append(s.flushed, session.Identifier)
+		}
+	}
+	return nil
+}
+
+// Resume implements synchronizationSessionService.Resume.
+func (s *fakeSynchronizationSessionService) Resume(_ context.Context, _ string, selection *selection.Selection) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, session := range s.sessions {
+		if fakeSessionSelected(selection, session.Identifier, session.Name) {
+			session.Paused = false
+		}
+	}
+	s.resumed = true
+	return nil
+}
+
+// Terminate implements synchronizationSessionService.Terminate.
+func (s *fakeSynchronizationSessionService) Terminate(_ context.Context, _ string, selection *selection.Selection) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var retained []*synchronization.Session
+	for _, session := range s.sessions {
+		if fakeSessionSelected(selection, session.Identifier, session.Name) {
+			s.terminated = append(s.terminated, session.Identifier)
+		} else {
+			retained = append(retained, session)
+		}
+	}
+	s.sessions = retained
+	return nil
+}
+
+// testForwardingSpecification creates a forwarding session specification for
+// tests.
+func testForwardingSpecification(name, source string) *forwardingsvc.CreationSpecification {
+	return &forwardingsvc.CreationSpecification{
+		Source: &url.URL{
+			Kind:     url.Kind_Forwarding,
+			Protocol: url.Protocol_Local,
+			Path:     source,
+		},
+		Destination: &url.URL{
+			Kind:     url.Kind_Forwarding,
+			Protocol: url.Protocol_Docker,
+			Host:     "sidecar",
+			Path:     "tcp:database:5432",
+		},
+		Configuration:            &forwarding.Configuration{},
+		ConfigurationSource:      &forwarding.Configuration{},
+		ConfigurationDestination: &forwarding.Configuration{},
+		Name:                     name,
+		Labels:                   sidecarSessionLabels(nil, "sidecar", "test"),
+	}
+}
+
+// testForwardingSession creates an existing forwarding session corresponding
+// to the specified specification for tests.
+func testForwardingSession(identifier string, creationOffset time.Duration, specification *forwardingsvc.CreationSpecification) *forwarding.Session {
+	return &forwarding.Session{
+		Identifier:               identifier,
+		CreationTime:             timestamppb.New(testSessionCreationTime.Add(creationOffset)),
+		Source:                   specification.Source,
+		Destination:              specification.Destination,
+		Configuration:            specification.Configuration,
+		ConfigurationSource:      specification.ConfigurationSource,
+		ConfigurationDestination: specification.ConfigurationDestination,
+		Name:                     specification.Name,
+		Labels:                   specification.Labels,
+	}
+}
+
+// testSynchronizationSpecification creates a synchronization session
+// specification for tests.
+func testSynchronizationSpecification(name, alpha string) *synchronizationsvc.CreationSpecification {
+	return &synchronizationsvc.CreationSpecification{
+		Alpha: &url.URL{
+			Kind:     url.Kind_Synchronization,
+			Protocol: url.Protocol_Local,
+			Path:     alpha,
+		},
+		Beta: &url.URL{
+			Kind:     url.Kind_Synchronization,
+			Protocol: url.Protocol_Docker,
+			Host:     "sidecar",
+			Path:     "/volumes/code",
+		},
+		Configuration:      &synchronization.Configuration{},
+		ConfigurationAlpha: &synchronization.Configuration{},
+		ConfigurationBeta:  &synchronization.Configuration{},
+		Name:               name,
+		Labels:             sidecarSessionLabels(nil, "sidecar", "test"),
+	}
+}
+
+// testSynchronizationSession creates an existing synchronization session
+// corresponding to the specified specification for tests.
+func testSynchronizationSession(identifier string, creationOffset time.Duration, specification *synchronizationsvc.CreationSpecification) *synchronization.Session {
+	return &synchronization.Session{
+		Identifier:         identifier,
+		CreationTime:       timestamppb.New(testSessionCreationTime.Add(creationOffset)),
+		Alpha:              specification.Alpha,
+		Beta:               specification.Beta,
+		Configuration:      specification.Configuration,
+		ConfigurationAlpha: specification.ConfigurationAlpha,
+		ConfigurationBeta:  specification.ConfigurationBeta,
+		Name:               specification.Name,
+		Labels:             specification.Labels,
+	}
+}
+
+// reconcileTestSessions performs session reconciliation against the specified
+// fake services.
+func reconcileTestSessions(
+	liaison *Liaison,
+	forwardingService *fakeForwardingSessionService,
+	synchronizationService *fakeSynchronizationSessionService,
+	forwardingSpecifications map[string]*forwardingsvc.CreationSpecification,
+	synchronizationSpecifications map[string]*synchronizationsvc.CreationSpecification,
+	deferredForwardingSessions map[string]bool,
+	force bool,
+) (*ReconciliationResult, error) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	return liaison.reconcileSessionsWithServices(
+		ctx, newStatusUpdater(ctx, "Mutagen", true), logger,
+		forwardingService, synchronizationService, "",
+		&selection.Selection{LabelSelector: sessionSidecarLabelKey + " == test"},
+		forwardingSpecifications, synchronizationSpecifications,
+		deferredForwardingSessions, force, nil,
+	)
+}
+
+// sortedStrings returns a sorted copy of the specified strings.
+func sortedStrings(values []string) []string {
+	result := append([]string(nil), values...)
+	sort.Strings(result)
+	return result
+}
+
+// stringsEqual determines whether or not two string slices are equal,
+// disregarding order.
+func stringsEqual(first, second []string) bool {
+	return stringsEqualOrdered(sortedStrings(first), sortedStrings(second))
+}
+
+// stringsEqualOrdered determines whether or not two string slices are equal,
+// including order.
+func stringsEqualOrdered(first, second []string) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestReconcileSessionsCreatesMissingSessions tests that reconciliation creates
+// sessions that don't exist and flushes newly created synchronization sessions.
+func TestReconcileSessionsCreatesMissingSessions(t *testing.T) {
+	forwardingService := &fakeForwardingSessionService{}
+	synchronizationService := &fakeSynchronizationSessionService{}
+	result, err := reconcileTestSessions(&Liaison{}, forwardingService, synchronizationService,
+		map[string]*forwardingsvc.CreationSpecification{
+			"database": testForwardingSpecification("database", "tcp:localhost:5432"),
+		},
+		map[string]*synchronizationsvc.CreationSpecification{
+			"code": testSynchronizationSpecification("code", "/project"),
+		},
+		nil, false,
+	)
+	if err != nil {
+		t.Fatal("reconciliation failed:", err)
+	}
+	if !stringsEqual(forwardingService.created, []string{"database"}) {
+		t.Error("unexpected forwarding sessions created:", forwardingService.created)
+	}
+	if !stringsEqual(synchronizationService.created, []string{"code"}) {
+		t.Error("unexpected synchronization sessions created:", synchronizationService.created)
+	}
+	if !stringsEqual(synchronizationService.flushed, []string{"synchronization_created_code"}) {
+		t.Error("unexpected synchronization sessions flushed:", synchronizationService.flushed)
+	}
+	if len(forwardingService.terminated) > 0 || len(synchronizationService.terminated) > 0 {
+		t.Error("sessions terminated unexpectedly")
+	}
+	if result.CreatedForwardingSessions["database"] != "forwarding_created_database" {
+		t.Error("forwarding session creation not reported")
+	}
+	if result.CreatedSynchronizationSessions["code"] != "synchronization_created_code" {
+		t.Error("synchronization session creation not reported")
+	}
+}
+
+// TestReconcileSessionsResumesCurrentSessions tests that reconciliation retains
+// and resumes existing sessions that match their definitions.
+func TestReconcileSessionsResumesCurrentSessions(t *testing.T) {
+	forwardingSpecification := testForwardingSpecification("database", "tcp:localhost:5432")
+	synchronizationSpecification := testSynchronizationSpecification("code", "/project")
+	existingForwarding := testForwardingSession("forwarding_database", 0, forwardingSpecification)
+	existingForwarding.Paused = true
+	existingSynchronization := testSynchronizationSession("synchronization_code", 0, synchronizationSpecification)
+	existingSynchronization.Paused = true
+	forwardingService := &fakeForwardingSessionService{sessions: []*forwarding.Session{existingForwarding}}
+	synchronizationService := &fakeSynchronizationSessionService{sessions: []*synchronization.Session{existingSynchronization}}
+	result, err := reconcileTestSessions(&Liaison{}, forwardingService, synchronizationService,
+		map[string]*forwardingsvc.CreationSpecification{"database": forwardingSpecification},
+		map[string]*synchronizationsvc.CreationSpecification{"code": synchronizationSpecification},
+		nil, false,
+	)
+	if err != nil {
+		t.Fatal("reconciliation failed:", err)
+	}
+	if len(forwardingService.created) > 0 || len(synchronizationService.created) > 0 {
+		t.Error("sessions created unexpectedly")
+	}
+	if len(forwardingService.terminated) > 0 || len(synchronizationService.terminated) > 0 {
+		t.Error("sessions terminated unexpectedly")
+	}
+	if len(synchronizationService.flushed) > 0 {
+		t.Error("existing synchronization sessions flushed unexpectedly")
+	}
+	if !forwardingService.resumed || existingForwarding.Paused {
+		t.Error("forwarding session not resumed")
+	}
+	if !synchronizationService.resumed || existingSynchronization.Paused {
+		t.Error("synchronization session not resumed")
+	}
+	if !stringsEqual(result.ResumedForwardingSessions, []string{"forwarding_database"}) {
+		t.Error("unexpected forwarding sessions reported as resumed:", result.ResumedForwardingSessions)
+	}
+	if !stringsEqual(result.ResumedSynchronizationSessions, []string{"synchronization_code"}) {
+		t.Error("unexpected synchronization sessions reported as resumed:", result.ResumedSynchronizationSessions)
+	}
+}
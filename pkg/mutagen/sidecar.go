@@ -1,22 +1,40 @@
 package mutagen
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 
 	"github.com/docker/cli/cli/command"
+	configtypes "github.com/docker/cli/cli/config/types"
+
+	moby "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
 
 	"github.com/compose-spec/compose-go/types"
 
+	"github.com/docker/compose/v2/pkg/api"
+
 	"github.com/mutagen-io/mutagen/pkg/mutagen"
 	"github.com/mutagen-io/mutagen/pkg/sidecar"
 	"github.com/mutagen-io/mutagen/pkg/url"
 )
 
 const (
-	// sidecarServiceName is the name of the Mutagen sidecar service.
-	sidecarServiceName = "mutagen"
+	// defaultSidecarServiceName is the default name of the Mutagen sidecar
+	// service.
+	defaultSidecarServiceName = "mutagen"
 	// sidecarURLProtocol is a placeholder URL protocol used to indicate that a
 	// URL should point to the Mutagen sidecar. It is used before the sidecar
 	// container ID is known and will be converted to a Docker URL protocol.
@@ -30,6 +48,35 @@ const (
 	// sidecarVersionLabelKey is the name of the label applied to the Mutagen
 	// Compose sidecar container to embed Mutagen Compose version information.
 	sidecarVersionLabelKey = "io.mutagen.compose.version"
+	// sidecarIsolatedDaemonLabelKey is the name of the label applied to the
+	// Mutagen Compose sidecar container to indicate that the project's
+	// sessions are managed by an isolated, project-scoped Mutagen daemon.
+	sidecarIsolatedDaemonLabelKey = "io.mutagen.compose.daemon.isolated"
+	// sidecarIsolatedDaemonLabelValue is the value of the label applied to the
+	// Mutagen Compose sidecar container to indicate daemon isolation.
+	sidecarIsolatedDaemonLabelValue = "true"
+	// sidecarGroupLabelKey is the name of the label applied to Mutagen Compose
+	// sidecar containers that host a specific sidecar group. It's not applied
+	// to the primary sidecar container.
+	sidecarGroupLabelKey = "io.mutagen.compose.sidecar.group"
+	// sidecarDefaultStopGracePeriod is the default stop grace period for the
+	// Mutagen Compose sidecar container. It's longer than the Docker default in
+	// order to give the Mutagen agent time to complete pending transfers.
+	sidecarDefaultStopGracePeriod = 30 * time.Second
+	// sidecarDefaultPullPolicy is the default pull policy for the Mutagen
+	// Compose sidecar image. Since sidecar image tags are pinned to a specific
+	// Mutagen version, there's no need to re-pull an image that's present.
+	sidecarDefaultPullPolicy = types.PullPolicyMissing
+	// sidecarPositionFirst is the sidecar position specification that places
+	// the Mutagen Compose sidecar services before user-defined services.
+	sidecarPositionFirst = "first"
+	// sidecarPositionLast is the sidecar position specification that places
+	// the Mutagen Compose sidecar services after user-defined services. It's
+	// the default position.
+	sidecarPositionLast = "last"
+	// logLevelEnvironmentVariable is the environment variable used by Mutagen
+	// to control its log level.
+	logLevelEnvironmentVariable = "MUTAGEN_LOG_LEVEL"
 )
 
 // sidecarImage is the full Mutagen sidecar image tag.
@@ -40,10 +87,23 @@ func init() {
 	sidecarImage = sidecar.BaseTag + ":" + mutagen.Version
 }
 
+// SidecarImage returns the default Mutagen sidecar image reference, i.e. the
+// image used when no sidecar feature level is specified.
+func SidecarImage() string {
+	return sidecarImage + enhancedTagSuffix
+}
+
+// SidecarVersionLabel returns the key and value of the version label applied to
+// Mutagen Compose sidecar containers.
+func SidecarVersionLabel() (string, string) {
+	return sidecarVersionLabelKey, mutagen.Version
+}
+
 // reifySidecarURLIfNecessary converts a sidecar URL to a reified Docker URL
 // using information from the specified Docker CLI flags, Docker CLI, and
 // sidecar container ID. If the target URL is not a sidecar URL, then this
-// function is a no-op.
+// function is a no-op, which means that local URLs (such as forwarding sources
+// bound to a specific interface) are never modified.
 func reifySidecarURLIfNecessary(target *url.URL, dockerFlags *pflag.FlagSet, dockerCLI command.Cli, sidecarID string) {
 	// If this isn't a sidecar URL, then we're done.
 	if target.Protocol != sidecarURLProtocol {
@@ -141,3 +201,460 @@ func isValidRestartPolicy(restart string) bool {
 		restart == types.RestartPolicyNo ||
 		restart == types.RestartPolicyUnlessStopped
 }
+
+// isValidSidecarPullPolicy returns true if and only if the provided pull policy
+// is non-empty and names a pull policy supported for the sidecar image.
+func isValidSidecarPullPolicy(policy string) bool {
+	return policy == types.PullPolicyNever ||
+		policy == types.PullPolicyMissing ||
+		policy == types.PullPolicyAlways
+}
+
+// sidecarEnvironment validates user-specified sidecar environment variables
+// and resolves those without values from the current environment (omitting
+// them if they're unset), matching the behavior of Compose. Variables that are
+// managed by Mutagen Compose itself (and set as necessary when the sidecar
+// service is defined) are rejected so that they can't be clobbered.
+func sidecarEnvironment(environment types.MappingWithEquals) (types.MappingWithEquals, error) {
+	result := make(types.MappingWithEquals, len(environment))
+	for name, value := range environment {
+		if name == dataDirectoryEnvironmentVariable || name == logLevelEnvironmentVariable {
+			return nil, fmt.Errorf("environment variable (%s) is reserved", name)
+		} else if value != nil {
+			result[name] = value
+		} else if resolved, ok := os.LookupEnv(name); ok {
+			result[name] = &resolved
+		}
+	}
+	return result, nil
+}
+
+// ensurePlacementSatisfiedByLocalNode verifies that the specified sidecar
+// placement can be satisfied by the local Swarm node, if the Docker daemon is
+// part of a Swarm. Compose creates containers (and volumes) using the local
+// Docker daemon, so the volumes and bind mounts used by Mutagen sessions are
+// always local to its node, and a placement that excludes that node could never
+// be satisfied with them. Only constraints on node identifiers, hostnames,
+// roles, and platforms are evaluated, since node labels aren't available
+// without querying the Swarm API.
+func ensurePlacementSatisfiedByLocalNode(placement types.Placement, info moby.Info) error {
+	// If the daemon isn't an active Swarm node, then there's nothing to check.
+	if info.Swarm.LocalNodeState != swarm.LocalNodeStateActive {
+		return nil
+	}
+
+	// Compute the local node's attributes.
+	role := "worker"
+	if info.Swarm.ControlAvailable {
+		role = "manager"
+	}
+	attributes := map[string]string{
+		"node.id":            info.Swarm.NodeID,
+		"node.hostname":      info.Name,
+		"node.role":          role,
+		"node.platform.os":   info.OSType,
+		"node.platform.arch": info.Architecture,
+	}
+
+	// Evaluate the constraints. Swarm compares values case-insensitively.
+	for _, constraint := range placement.Constraints {
+		matches := placementConstraintMatcher.FindStringSubmatch(constraint)
+		if matches == nil {
+			return fmt.Errorf("invalid placement constraint: %s", constraint)
+		}
+		actual, ok := attributes[matches[1]]
+		if !ok {
+			continue
+		}
+		if (matches[2] == "==") != strings.EqualFold(actual, matches[3]) {
+			return fmt.Errorf("placement constraint (%s) excludes the local Swarm node (%s), which hosts the volumes used by Mutagen sessions",
+				constraint, info.Name,
+			)
+		}
+	}
+
+	// Success.
+	return nil
+}
+
+// isValidSidecarPosition returns true if and only if the provided position is
+// a valid sidecar position specification.
+func isValidSidecarPosition(position string) bool {
+	return position == sidecarPositionFirst || position == sidecarPositionLast
+}
+
+// serviceNameMatcher matches valid Compose service names.
+var serviceNameMatcher = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// isValidServiceName checks whether or not a service name is valid.
+func isValidServiceName(name string) bool {
+	return serviceNameMatcher.MatchString(name)
+}
+
+// sidecarDependencies tracks the network, volume, and bind mount dependencies
+// of a Mutagen Compose sidecar service. The volume, external volume, and bind
+// dependency maps track whether or not write access is required for each
+// volume or host path.
+type sidecarDependencies struct {
+	// networks are the network dependencies.
+	networks map[string]*types.ServiceNetworkConfig
+	// volumes are the project volume dependencies.
+	volumes map[string]bool
+	// externalVolumes are the external volume dependencies.
+	externalVolumes map[string]bool
+	// binds are the bind mount dependencies.
+	binds map[string]bool
+}
+
+// newSidecarDependencies creates a new empty set of sidecar dependencies.
+func newSidecarDependencies() *sidecarDependencies {
+	return &sidecarDependencies{
+		networks:        make(map[string]*types.ServiceNetworkConfig),
+		volumes:         make(map[string]bool),
+		externalVolumes: make(map[string]bool),
+		binds:           make(map[string]bool),
+	}
+}
+
+// serviceVolumes converts volume and bind dependencies to the Compose format.
+func (d *sidecarDependencies) serviceVolumes(platform string) []types.ServiceVolumeConfig {
+	result := make([]types.ServiceVolumeConfig, 0, len(d.volumes)+len(d.externalVolumes)+len(d.binds))
+	for volume, write := range d.volumes {
+		result = append(result, types.ServiceVolumeConfig{
+			Type:     types.VolumeTypeVolume,
+			Source:   volume,
+			Target:   mountPathForVolumeInMutagenContainer(platform, volume),
+			ReadOnly: !write,
+		})
+	}
+	for volume, write := range d.externalVolumes {
+		result = append(result, types.ServiceVolumeConfig{
+			Type:     types.VolumeTypeVolume,
+			Source:   volume,
+			Target:   mountPathForVolumeInMutagenContainer(platform, volume),
+			ReadOnly: !write,
+		})
+	}
+	for hostPath, write := range d.binds {
+		result = append(result, types.ServiceVolumeConfig{
+			Type:     types.VolumeTypeBind,
+			Source:   hostPath,
+			Target:   mountPathForBindInMutagenContainer(platform, hostPath),
+			ReadOnly: !write,
+		})
+	}
+	return result
+}
+
+// attachStagingVolume mounts the specified staging volume into a Mutagen
+// Compose sidecar service definition and configures the Mutagen data directory
+// for the service to reside inside it.
+func attachStagingVolume(service *types.ServiceConfig, volume, platform string) {
+	// Add the volume mount. We create a new slice since the existing slice may
+	// be shared with other service definitions.
+	volumes := make([]types.ServiceVolumeConfig, 0, len(service.Volumes)+1)
+	volumes = append(volumes, service.Volumes...)
+	service.Volumes = append(volumes, types.ServiceVolumeConfig{
+		Type:   types.VolumeTypeVolume,
+		Source: volume,
+		Target: mountPathForStagingVolumeInMutagenContainer(platform),
+	})
+
+	// Set the Mutagen data directory. We create a new environment for the same
+	// reason.
+	dataDirectory := dataDirectoryForStagingVolumeInMutagenContainer(platform, service.Name)
+	environment := make(types.MappingWithEquals, len(service.Environment)+1)
+	for key, value := range service.Environment {
+		environment[key] = value
+	}
+	environment[dataDirectoryEnvironmentVariable] = &dataDirectory
+	service.Environment = environment
+}
+
+// normalizeArchitecture normalizes an architecture name to the form used by
+// container image platform specifications. Docker daemons report architectures
+// using kernel naming (e.g. "x86_64"), whereas images use Go naming (e.g.
+// "amd64").
+func normalizeArchitecture(architecture string) string {
+	switch strings.ToLower(architecture) {
+	case "x86_64", "x86-64", "amd64":
+		return "amd64"
+	case "aarch64", "arm64":
+		return "arm64"
+	case "armhf", "armel", "armv6l", "armv7l", "arm":
+		return "arm"
+	case "i386", "i686", "386":
+		return "386"
+	default:
+		return strings.ToLower(architecture)
+	}
+}
+
+// warnOnSidecarPlatformMismatch checks whether or not the specified sidecar
+// image's architecture matches that of the Docker daemon and emits a warning
+// if it doesn't. A mismatched sidecar image can only run via emulation (if
+// available), in which case the sidecar may fail or perform poorly. Failures to
+// perform the check are ignored since they'll surface when the container is
+// created.
+func (l *Liaison) warnOnSidecarPlatformMismatch(ctx context.Context, image string) {
+	// Determine the daemon architecture.
+	daemonArchitecture := l.daemonArchitecture
+	if daemonArchitecture == "" {
+		daemonMetadata, err := l.dockerCLI.Client().Info(ctx)
+		if err != nil {
+			return
+		}
+		daemonArchitecture = normalizeArchitecture(daemonMetadata.Architecture)
+	}
+
+	// Determine the image architecture.
+	metadata, _, err := l.dockerCLI.Client().ImageInspectWithRaw(ctx, image)
+	if err != nil || metadata.Architecture == "" {
+		return
+	}
+	imageArchitecture := normalizeArchitecture(metadata.Architecture)
+
+	// Warn on mismatches.
+	if imageArchitecture != daemonArchitecture {
+		l.log().WithField("image", image).Warnf("Mutagen Compose sidecar image (%s) architecture (%s) doesn't match "+
+			"Docker daemon architecture (%s); the sidecar will require emulation and may fail or perform poorly "+
+			"(remove the image and pull it again to obtain the correct variant)",
+			image, imageArchitecture, daemonArchitecture,
+		)
+	}
+}
+
+// sidecarRegistryAuth returns the encoded registry authentication to use when
+// pulling the specified image, if it's a sidecar image and sidecar registry
+// authentication has been configured. If no sidecar-specific authentication
+// applies, then it returns an empty string.
+func (l *Liaison) sidecarRegistryAuth(image string) (string, error) {
+	// If there's no sidecar registry authentication, then we're done.
+	if l.sidecarRegistryAuthentication == nil {
+		return "", nil
+	}
+
+	// Check if this is a sidecar image. Since all sidecar services use the same
+	// image, checking the primary sidecar service is sufficient.
+	if image != l.mutagenService.Image {
+		return "", nil
+	}
+
+	// Compute the authentication configuration.
+	var auth configtypes.AuthConfig
+	if credential := l.sidecarRegistryAuthentication.Credential; credential != "" {
+		var err error
+		if auth, err = l.dockerCLI.ConfigFile().GetAuthConfig(credential); err != nil {
+			return "", fmt.Errorf("unable to load registry credential (%s): %w", credential, err)
+		}
+	} else {
+		auth.Username = l.sidecarRegistryAuthentication.Username
+		auth.Password = l.sidecarRegistryAuthentication.Password
+	}
+
+	// Encode the authentication configuration in the same manner as Compose.
+	encoded, err := json.Marshal(auth)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode registry authentication: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(encoded), nil
+}
+
+// sidecarServicesToPull returns the Mutagen Compose sidecar service definitions
+// whose images should be pulled by a pull operation. If cached sidecar pulls
+// are being skipped, then services whose images are already present on the
+// Docker daemon are excluded. This method must only be called after
+// processProject.
+func (l *Liaison) sidecarServicesToPull(ctx context.Context) ([]types.ServiceConfig, error) {
+	// If we're not skipping cached images, then all services are pulled.
+	services := l.sidecarServices()
+	if !l.skipCachedSidecarPull {
+		return services, nil
+	}
+
+	// Filter out services whose images are already present.
+	var result []types.ServiceConfig
+	for _, service := range services {
+		if _, _, err := l.dockerCLI.Client().ImageInspectWithRaw(ctx, service.Image); err == nil {
+			continue
+		} else if !client.IsErrNotFound(err) {
+			return nil, fmt.Errorf("unable to inspect Mutagen Compose sidecar image: %w", err)
+		}
+		result = append(result, service)
+	}
+
+	// Success.
+	return result, nil
+}
+
+// sidecarServices returns the Mutagen Compose sidecar service definitions,
+// with the primary sidecar service first, followed by any sidecar group
+// services in group order. This method must only be called after
+// processProject.
+func (l *Liaison) sidecarServices() types.Services {
+	result := make(types.Services, 0, 1+len(l.sidecarGroupServices))
+	result = append(result, l.mutagenService)
+	return append(result, l.sidecarGroupServices...)
+}
+
+// withSidecarServices returns a copy of the specified service list with the
+// Mutagen Compose sidecar service definitions inserted at the configured
+// position. The sidecar services always retain their relative ordering, so the
+// resulting list is the same for every operation that injects them. This
+// method must only be called after processProject.
+func (l *Liaison) withSidecarServices(services types.Services) types.Services {
+	if l.sidecarFirst {
+		return appendServicesByCopy(l.sidecarServices(), services...)
+	}
+	return appendServicesByCopy(services, l.sidecarServices()...)
+}
+
+// sidecarServiceNamesForProject returns the names of the Mutagen Compose
+// sidecar service definitions. This method must only be called after
+// processProject.
+func (l *Liaison) sidecarServiceNamesForProject() []string {
+	var result []string
+	for _, service := range l.sidecarServices() {
+		result = append(result, service.Name)
+	}
+	return result
+}
+
+// sidecarServiceNames returns the names of the Mutagen Compose sidecar
+// services for the specified project. If the project has been processed, then
+// the resolved names are used. Otherwise, the names are read from the sidecar
+// containers' service labels, falling back to the default name if no sidecar
+// containers exist.
+func (l *Liaison) sidecarServiceNames(ctx context.Context, projectName string) ([]string, error) {
+	// If the sidecar services have been defined, then use their names.
+	if l.mutagenService.Name != "" {
+		return l.sidecarServiceNamesForProject(), nil
+	}
+
+	// Otherwise, identify the sidecar containers and extract their service
+	// labels.
+	containers, err := l.sidecarContainers(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, container := range containers {
+		if name := container.Labels[api.ServiceLabel]; name != "" {
+			result = append(result, name)
+		}
+	}
+	if len(result) == 0 {
+		result = append(result, defaultSidecarServiceName)
+	}
+	return result, nil
+}
+
+// staleSidecarContainers returns the names of the specified project's existing
+// Mutagen Compose sidecar containers that were created by a different version
+// of Mutagen (as indicated by their version labels).
+func (l *Liaison) staleSidecarContainers(ctx context.Context, projectName string) ([]string, error) {
+	// Identify the sidecar containers.
+	containers, err := l.sidecarContainers(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identify those with mismatched versions.
+	var stale []string
+	for _, container := range containers {
+		if container.Labels[sidecarVersionLabelKey] == mutagen.Version {
+			continue
+		}
+		name := container.ID
+		if len(container.Names) > 0 {
+			name = strings.TrimPrefix(container.Names[0], "/")
+		}
+		stale = append(stale, name)
+	}
+
+	// Done.
+	return stale, nil
+}
+
+// sidecarContainers performs a query to identify the Mutagen Compose sidecar
+// containers for the specified project. The primary sidecar container (if it
+// exists) is returned first, followed by any sidecar group containers in group
+// order. If multiple containers are identified for the same group, then an
+// error is returned.
+func (l *Liaison) sidecarContainers(ctx context.Context, projectName string) ([]moby.Container, error) {
+	// Query the sidecar containers.
+	containers, err := l.dockerCLI.Client().ContainerList(ctx, moby.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", fmt.Sprintf("%s=%s", api.ProjectLabel, projectName)),
+			filters.Arg("label", fmt.Sprintf("%s=%s", sidecarRoleLabelKey, sidecarRoleLabelValue)),
+		),
+		All: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query Mutagen sidecar containers: %w", err)
+	}
+
+	// Sort the containers by group and check for duplicates.
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].Labels[sidecarGroupLabelKey] < containers[j].Labels[sidecarGroupLabelKey]
+	})
+	for i := 1; i < len(containers); i++ {
+		if containers[i].Labels[sidecarGroupLabelKey] == containers[i-1].Labels[sidecarGroupLabelKey] {
+			return nil, errors.New("multiple Mutagen sidecar containers identified")
+		}
+	}
+
+	// Success.
+	return containers, nil
+}
+
+// resolveSidecarIDs identifies the Mutagen Compose sidecar containers for the
+// specified project, returning their identifiers in the same order as
+// sidecarContainers. If no sidecar containers exist, then a
+// *SidecarNotFoundError is returned. If running is true, then it also ensures
+// that all of the sidecar containers are running.
+func (l *Liaison) resolveSidecarIDs(ctx context.Context, projectName string, running bool) ([]string, error) {
+	// Identify the sidecar containers.
+	containers, err := l.sidecarContainers(ctx, projectName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to identify Mutagen Compose sidecar containers: %w", err)
+	} else if len(containers) == 0 {
+		return nil, &SidecarNotFoundError{Project: projectName}
+	}
+	sidecarIDs := make([]string, 0, len(containers))
+	for _, container := range containers {
+		sidecarIDs = append(sidecarIDs, container.ID)
+	}
+
+	// Ensure that the sidecar containers are running, if required.
+	if running {
+		for _, sidecarID := range sidecarIDs {
+			metadata, err := l.dockerCLI.Client().ContainerInspect(ctx, sidecarID)
+			if err != nil {
+				return nil, fmt.Errorf("unable to inspect Mutagen Compose sidecar container: %w", err)
+			} else if metadata.State == nil || !metadata.State.Running {
+				return nil, errors.New("Mutagen Compose sidecar container is not running")
+			}
+		}
+	}
+
+	// Success.
+	return sidecarIDs, nil
+}
+
+// resolveSidecarID identifies the Mutagen Compose sidecar container hosting
+// the specified sidecar group (with an empty group indicating the primary
+// sidecar container) for the specified project. If no such sidecar container
+// exists, then a *SidecarNotFoundError is returned.
+func (l *Liaison) resolveSidecarID(ctx context.Context, projectName, group string) (string, error) {
+	containers, err := l.sidecarContainers(ctx, projectName)
+	if err != nil {
+		return "", fmt.Errorf("unable to identify Mutagen Compose sidecar containers: %w", err)
+	}
+	for _, container := range containers {
+		if container.Labels[sidecarGroupLabelKey] == group {
+			return container.ID, nil
+		}
+	}
+	return "", &SidecarNotFoundError{Project: projectName, Group: group}
+}
@@ -0,0 +1,124 @@
+package mutagen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mutagen-io/mutagen/cmd"
+
+	"github.com/mutagen-io/mutagen/pkg/grpcutil"
+	synchronizationsvc "github.com/mutagen-io/mutagen/pkg/service/synchronization"
+	"github.com/mutagen-io/mutagen/pkg/synchronization"
+)
+
+// Status prints the status of Mutagen sessions for the specified project. If
+// conflictsOnly is true, then only synchronization sessions with unresolved
+// conflicts are shown, along with the paths involved in those conflicts.
+func (l *Liaison) Status(ctx context.Context, projectName string, conflictsOnly bool) error {
+	// Identify the sidecar containers.
+	sidecarIDs, err := l.resolveSidecarIDs(ctx, projectName, false)
+	if err != nil {
+		return err
+	}
+
+	// Perform the appropriate listing for each sidecar container.
+	for _, sidecarID := range sidecarIDs {
+		if conflictsOnly {
+			err = l.listConflictedSessions(ctx, sidecarID)
+		} else {
+			err = l.listSessions(ctx, sidecarID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Success.
+	return nil
+}
+
+// formatConflictPath formats a conflict path for display.
+func formatConflictPath(path string) string {
+	if path == "" {
+		return "<root>"
+	}
+	return path
+}
+
+// printConflictedSession prints the conflicts for a synchronization session.
+func printConflictedSession(state *synchronization.State) {
+	// Print the session header.
+	fmt.Println("Name:", state.Session.Name)
+	fmt.Println("Identifier:", state.Session.Identifier)
+	fmt.Println("Conflicts:")
+
+	// Print the paths involved in each conflict, along with the endpoint on
+	// which each change occurred.
+	for _, conflict := range state.Conflicts {
+		fmt.Printf("\t%s\n", formatConflictPath(conflict.Root))
+		for _, change := range conflict.AlphaChanges {
+			fmt.Printf("\t\t(alpha) %s\n", formatConflictPath(change.Path))
+		}
+		for _, change := range conflict.BetaChanges {
+			fmt.Printf("\t\t(beta)  %s\n", formatConflictPath(change.Path))
+		}
+	}
+
+	// Print excluded conflicts.
+	if state.ExcludedConflicts > 0 {
+		fmt.Printf("\t...+%d more...\n", state.ExcludedConflicts)
+	}
+}
+
+// listConflictedSessions lists Mutagen synchronization sessions with
+// unresolved conflicts for the project using the specified sidecar container
+// ID as the target identifier.
+func (l *Liaison) listConflictedSessions(ctx context.Context, sidecarID string) error {
+	// Connect to the Mutagen daemon and defer closure of the connection.
+	daemonConnection, err := l.connectToDaemonForSidecar(ctx, sidecarID)
+	if err != nil {
+		return fmt.Errorf("unable to connect to Mutagen daemon: %w", err)
+	}
+	defer l.releaseDaemonConnection(daemonConnection)
+
+	// Create the session selection criteria.
+	projectSelection, err := sidecarSessionSelection(sidecarID)
+	if err != nil {
+		return err
+	}
+
+	// Perform synchronization session listing.
+	synchronizationService := synchronizationsvc.NewSynchronizationClient(daemonConnection)
+	response, err := synchronizationService.List(ctx, &synchronizationsvc.ListRequest{
+		Selection: projectSelection,
+	})
+	if err != nil {
+		return fmt.Errorf("synchronization listing failed: %w", grpcutil.PeelAwayRPCErrorLayer(err))
+	} else if err = response.EnsureValid(); err != nil {
+		return fmt.Errorf("invalid synchronization listing response received: %w", err)
+	}
+
+	// Filter sessions down to those with conflicts.
+	var conflicted []*synchronization.State
+	for _, state := range response.SessionStates {
+		if len(state.Conflicts) > 0 {
+			conflicted = append(conflicted, state)
+		}
+	}
+
+	// Print the conflicted sessions.
+	fmt.Println("Conflicted synchronization sessions")
+	fmt.Println(cmd.DelimiterLine)
+	if len(conflicted) == 0 {
+		fmt.Println("No synchronization sessions with conflicts found")
+		fmt.Println(cmd.DelimiterLine)
+		return nil
+	}
+	for _, state := range conflicted {
+		printConflictedSession(state)
+		fmt.Println(cmd.DelimiterLine)
+	}
+
+	// Success.
+	return nil
+}
@@ -2,14 +2,26 @@ package mutagen
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/types"
 
 	"github.com/mutagen-io/mutagen/pkg/grpcutil"
+	"github.com/mutagen-io/mutagen/pkg/mutagen"
 	"github.com/mutagen-io/mutagen/pkg/selection"
 	synchronizationsvc "github.com/mutagen-io/mutagen/pkg/service/synchronization"
 	"github.com/mutagen-io/mutagen/pkg/synchronization"
+	"github.com/mutagen-io/mutagen/pkg/synchronization/core"
 	"github.com/mutagen-io/mutagen/pkg/url"
 )
 
@@ -21,6 +33,103 @@ func isVolumeURL(raw string) bool {
 	return strings.HasPrefix(strings.ToLower(raw), volumeURLPrefix)
 }
 
+// externalVolumeURLPrefix is the lowercase version of the external volume URL
+// prefix.
+const externalVolumeURLPrefix = "external-volume://"
+
+// isExternalVolumeURL checks if raw URL is an external volume pseudo-URL. These
+// URLs reference Docker volumes by name (rather than by project volume key),
+// allowing synchronization with volumes that aren't managed by the project.
+func isExternalVolumeURL(raw string) bool {
+	return strings.HasPrefix(strings.ToLower(raw), externalVolumeURLPrefix)
+}
+
+// isOneWaySynchronizationMode determines whether or not a synchronization mode
+// is a one-way mode, in which case alpha acts as a pure source and is never
+// modified by synchronization.
+func isOneWaySynchronizationMode(mode core.SynchronizationMode) bool {
+	return mode == core.SynchronizationMode_SynchronizationModeOneWaySafe ||
+		mode == core.SynchronizationMode_SynchronizationModeOneWayReplica
+}
+
+// conflictResolutionPolicy specifies how conflicts in a two-way
+// synchronization session should be resolved. Policies are implemented by
+// mapping them to the corresponding Mutagen synchronization mode.
+type conflictResolutionPolicy uint8
+
+const (
+	// conflictResolutionPolicyDefault indicates that no policy has been
+	// specified and that the synchronization mode should be used as-is.
+	conflictResolutionPolicyDefault conflictResolutionPolicy = iota
+	// conflictResolutionPolicyManual indicates that conflicts should be left
+	// for manual resolution. It maps to the two-way-safe mode.
+	conflictResolutionPolicyManual
+	// conflictResolutionPolicyAlpha indicates that conflicts should be
+	// automatically resolved in favor of alpha. It maps to the
+	// two-way-resolved mode.
+	conflictResolutionPolicyAlpha
+)
+
+// UnmarshalText implements encoding.TextUnmarshaler.UnmarshalText.
+func (p *conflictResolutionPolicy) UnmarshalText(textBytes []byte) error {
+	switch text := string(textBytes); text {
+	case "manual":
+		*p = conflictResolutionPolicyManual
+	case "alpha":
+		*p = conflictResolutionPolicyAlpha
+	case "beta":
+		return errors.New("conflicts can't be resolved in favor of beta (swap the alpha and beta URLs and use \"alpha\" instead)")
+	default:
+		return fmt.Errorf("unknown conflict resolution policy: %s", text)
+	}
+	return nil
+}
+
+// apply applies the conflict resolution policy to a synchronization
+// configuration by setting the corresponding synchronization mode. It returns
+// an error if the configuration explicitly specifies a synchronization mode
+// that's incompatible with the policy.
+func (p conflictResolutionPolicy) apply(configuration *synchronization.Configuration) error {
+	// Determine the synchronization mode corresponding to the policy.
+	var mode core.SynchronizationMode
+	switch p {
+	case conflictResolutionPolicyDefault:
+		return nil
+	case conflictResolutionPolicyManual:
+		mode = core.SynchronizationMode_SynchronizationModeTwoWaySafe
+	case conflictResolutionPolicyAlpha:
+		mode = core.SynchronizationMode_SynchronizationModeTwoWayResolved
+	default:
+		panic("unhandled conflict resolution policy")
+	}
+
+	// Ensure that any explicitly specified mode is compatible.
+	if !configuration.SynchronizationMode.IsDefault() && configuration.SynchronizationMode != mode {
+		return fmt.Errorf("conflict resolution policy incompatible with synchronization mode (%s)",
+			configuration.SynchronizationMode.Description(),
+		)
+	}
+
+	// Set the mode.
+	configuration.SynchronizationMode = mode
+
+	// Success.
+	return nil
+}
+
+// nativeWatchingSessionWarningThreshold is the number of synchronization
+// sessions using native filesystem watching inside the sidecar container above
+// which a resource usage warning is emitted.
+const nativeWatchingSessionWarningThreshold = 10
+
+// usesNativeWatching determines whether or not a watch mode results in native
+// filesystem watching (e.g. via inotify on Linux), as opposed to poll-based
+// watching or no watching at all.
+func usesNativeWatching(mode synchronization.WatchMode) bool {
+	return mode == synchronization.WatchMode_WatchModeDefault ||
+		mode == synchronization.WatchMode_WatchModePortable
+}
+
 // mountPathForVolumeInMutagenContainer returns the mount path that will be used
 // for a volume inside the Mutagen container. The path will be returned without
 // a trailing slash. The volume must be non-empty or this function will panic.
@@ -50,8 +159,21 @@ func mountPathForVolumeInMutagenContainer(platform, volume string) string {
 // on URLs that have been classified as volume URLs by isVolumeURL, otherwise
 // this function may panic.
 func parseVolumeURL(raw, platform string) (*url.URL, string, error) {
-	// Strip off the prefix
-	raw = raw[len(volumeURLPrefix):]
+	return parseVolumeReference(raw[len(volumeURLPrefix):], platform)
+}
+
+// parseExternalVolumeURL parses an external volume pseudo-URL, converting it to
+// a sidecar URL in the same manner as parseVolumeURL. The volume returned is
+// the name of the volume on the Docker daemon. This function must only be
+// called on URLs that have been classified as external volume URLs by
+// isExternalVolumeURL, otherwise this function may panic.
+func parseExternalVolumeURL(raw, platform string) (*url.URL, string, error) {
+	return parseVolumeReference(raw[len(externalVolumeURLPrefix):], platform)
+}
+
+// parseVolumeReference parses the volume and path components of a volume
+// pseudo-URL (with its prefix removed) and converts them to a sidecar URL.
+func parseVolumeReference(raw, platform string) (*url.URL, string, error) {
 
 	// Find the first slash, which will indicate the end of the volume name. If
 	// no slash is found, then we assume that the volume itself is the target
@@ -75,17 +197,278 @@ func parseVolumeURL(raw, platform string) (*url.URL, string, error) {
 	}, volume, nil
 }
 
+// volumeGlobCharacters are the characters that indicate a glob pattern in the
+// volume name portion of a volume pseudo-URL.
+const volumeGlobCharacters = "*?["
+
+// splitVolumeURL splits a volume pseudo-URL into its volume name and the
+// remainder of the URL following the volume name (which will be empty or begin
+// with a slash). This function must only be called on URLs that have been
+// classified as volume URLs by isVolumeURL, otherwise this function may panic.
+func splitVolumeURL(raw string) (string, string) {
+	reference := raw[len(volumeURLPrefix):]
+	if slashIndex := strings.IndexByte(reference, '/'); slashIndex >= 0 {
+		return reference[:slashIndex], reference[slashIndex:]
+	}
+	return reference, ""
+}
+
+// invalidSessionNameCharacterMatcher matches characters that aren't allowed in
+// Mutagen session names.
+var invalidSessionNameCharacterMatcher = regexp.MustCompile(`[^a-zA-Z0-9-]`)
+
+// expandSynchronizationVolumeGlobs expands synchronization sessions whose
+// volume URL contains a glob pattern in its volume name, replacing each such
+// session with one session per matching project volume. Generated sessions are
+// named by appending the (sanitized) volume name to the original session name.
+// It is an error for a pattern not to match any project volume or for a
+// generated name to conflict with another session.
+func expandSynchronizationVolumeGlobs(sessions map[string]synchronizationConfiguration, volumes types.Volumes) error {
+	// Sort project volume names to ensure deterministic expansion.
+	volumeNames := make([]string, 0, len(volumes))
+	for volume := range volumes {
+		volumeNames = append(volumeNames, volume)
+	}
+	sort.Strings(volumeNames)
+
+	// Identify the sessions to expand. We sort them so that conflicts are
+	// reported deterministically.
+	var templates []string
+	for name, session := range sessions {
+		if name == "defaults" {
+			continue
+		}
+		for _, endpoint := range []string{session.Alpha, session.Beta} {
+			if isVolumeURL(endpoint) {
+				if volume, _ := splitVolumeURL(endpoint); strings.ContainsAny(volume, volumeGlobCharacters) {
+					templates = append(templates, name)
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(templates)
+
+	// Expand each session.
+	for _, name := range templates {
+		// Extract the session and remove it from the session map.
+		session := sessions[name]
+		delete(sessions, name)
+
+		// Determine which endpoint contains the pattern. Only one endpoint can
+		// reference a volume, which will be enforced during processing, so we
+		// reject patterns in both endpoints here.
+		alphaIsPattern := isVolumeURL(session.Alpha)
+		betaIsPattern := isVolumeURL(session.Beta)
+		if alphaIsPattern && betaIsPattern {
+			return fmt.Errorf("both alpha and beta reference volumes in synchronization session (%s)", name)
+		}
+		endpoint := session.Beta
+		if alphaIsPattern {
+			endpoint = session.Alpha
+		}
+		pattern, remainder := splitVolumeURL(endpoint)
+
+		// Create a session for each matching volume.
+		var matched bool
+		for _, volume := range volumeNames {
+			if ok, err := path.Match(pattern, volume); err != nil {
+				return fmt.Errorf("invalid volume pattern (%s) in synchronization session (%s): %w", pattern, name, err)
+			} else if !ok {
+				continue
+			}
+			matched = true
+			expanded := session
+			if alphaIsPattern {
+				expanded.Alpha = volumeURLPrefix + volume + remainder
+			} else {
+				expanded.Beta = volumeURLPrefix + volume + remainder
+			}
+			expandedName := name + "-" + invalidSessionNameCharacterMatcher.ReplaceAllString(volume, "-")
+			if _, ok := sessions[expandedName]; ok {
+				return fmt.Errorf("synchronization session (%s) generated from session (%s) conflicts with another session", expandedName, name)
+			}
+			sessions[expandedName] = expanded
+		}
+		if !matched {
+			return fmt.Errorf("volume pattern (%s) in synchronization session (%s) doesn't match any project volumes", pattern, name)
+		}
+	}
+
+	// Success.
+	return nil
+}
+
+// synchronizationIndexPlaceholder is the placeholder that's replaced with the
+// session index in the URLs of templated synchronization sessions.
+const synchronizationIndexPlaceholder = "${index}"
+
+// expandSynchronizationCounts expands synchronization sessions that specify a
+// count, replacing each such session with the specified number of sessions.
+// Generated sessions are named by appending the index to the original session
+// name and have any index placeholders in their URLs replaced with the index.
+// It is an error for a generated name to be invalid or to conflict with another
+// session. The URLs of generated sessions are validated during processing.
+func expandSynchronizationCounts(sessions map[string]synchronizationConfiguration) error {
+	// Identify the sessions to expand. We sort them so that conflicts are
+	// reported deterministically.
+	var templates []string
+	for name, session := range sessions {
+		if name == "defaults" {
+			continue
+		} else if session.Count < 0 {
+			return fmt.Errorf("negative count (%d) in synchronization session (%s)", session.Count, name)
+		} else if session.Count > 0 {
+			templates = append(templates, name)
+		} else if strings.Contains(session.Alpha, synchronizationIndexPlaceholder) ||
+			strings.Contains(session.Beta, synchronizationIndexPlaceholder) {
+			return fmt.Errorf("%s placeholder used without count in synchronization session (%s)", synchronizationIndexPlaceholder, name)
+		}
+	}
+	sort.Strings(templates)
+
+	// Expand each session.
+	for _, name := range templates {
+		// Extract the session and remove it from the session map.
+		session := sessions[name]
+		delete(sessions, name)
+
+		// Generated sessions with identical URLs would target the same
+		// endpoints, so require that at least one URL is indexed.
+		if !strings.Contains(session.Alpha, synchronizationIndexPlaceholder) &&
+			!strings.Contains(session.Beta, synchronizationIndexPlaceholder) {
+			return fmt.Errorf("count specified without %s placeholder in synchronization session (%s) URLs", synchronizationIndexPlaceholder, name)
+		}
+
+		// Create a session for each index.
+		for i := 0; i < session.Count; i++ {
+			index := strconv.Itoa(i)
+			expanded := session
+			expanded.Count = 0
+			expanded.Alpha = strings.ReplaceAll(session.Alpha, synchronizationIndexPlaceholder, index)
+			expanded.Beta = strings.ReplaceAll(session.Beta, synchronizationIndexPlaceholder, index)
+			expandedName := name + "-" + index
+			if err := ensureSessionNameValid(expandedName); err != nil {
+				return fmt.Errorf("invalid synchronization session name (%s) generated from session (%s): %v", expandedName, name, err)
+			} else if _, ok := sessions[expandedName]; ok {
+				return fmt.Errorf("synchronization session (%s) generated from session (%s) conflicts with another session", expandedName, name)
+			}
+			sessions[expandedName] = expanded
+		}
+	}
+
+	// Success.
+	return nil
+}
+
+// bindURLPrefix is the lowercase version of the bind URL prefix.
+const bindURLPrefix = "bind://"
+
+// isBindURL checks if raw URL is a bind mount pseudo-URL.
+func isBindURL(raw string) bool {
+	return strings.HasPrefix(strings.ToLower(raw), bindURLPrefix)
+}
+
+// mountPathForStagingVolumeInMutagenContainer returns the mount path that will
+// be used for the staging volume inside the Mutagen container. The path will be
+// returned without a trailing slash. This function should only be called for
+// supported Docker platforms.
+func mountPathForStagingVolumeInMutagenContainer(platform string) string {
+	switch platform {
+	case "linux":
+		return "/mutagen-data"
+	case "windows":
+		return `c:\mutagen-data`
+	default:
+		panic("unsupported Docker platform")
+	}
+}
+
+// dataDirectoryForStagingVolumeInMutagenContainer returns the Mutagen data
+// directory path that will be used by the specified sidecar service inside the
+// staging volume. Each sidecar service uses its own subdirectory so that
+// multiple sidecar containers can share the same staging volume. This function
+// should only be called for supported Docker platforms.
+func dataDirectoryForStagingVolumeInMutagenContainer(platform, service string) string {
+	switch platform {
+	case "linux":
+		return mountPathForStagingVolumeInMutagenContainer(platform) + "/" + service
+	case "windows":
+		return mountPathForStagingVolumeInMutagenContainer(platform) + `\` + service
+	default:
+		panic("unsupported Docker platform")
+	}
+}
+
+// mountPathForBindInMutagenContainer returns the mount path that will be used
+// for a bind-mounted host path inside the Mutagen container. The mount path is
+// derived from a hash of the host path so that it remains stable across
+// invocations. The host path must be non-empty or this function will panic.
+// This function should only be called for supported Docker platforms.
+func mountPathForBindInMutagenContainer(platform, hostPath string) string {
+	// Verify that the host path is non-empty.
+	if hostPath == "" {
+		panic("empty host path")
+	}
+
+	// Compute a stable name for the mount point.
+	digest := sha256.Sum256([]byte(hostPath))
+	name := hex.EncodeToString(digest[:8])
+
+	// Compute the path based on the daemon OS.
+	switch platform {
+	case "linux":
+		return "/binds/" + name
+	case "windows":
+		return `c:\binds\` + name
+	default:
+		panic("unsupported Docker platform")
+	}
+}
+
+// parseBindURL parses a bind mount pseudo-URL, converting it to a sidecar URL.
+// The pseudo-URL takes the form bind://<host path>, where the host path must be
+// an absolute path. The host path is resolved by the Docker daemon, which may
+// not share the client's filesystem, so its existence isn't verified here; the
+// Docker daemon will report an error if it can't be mounted when the sidecar
+// container is created. As with parseVolumeURL, the resulting URL will only
+// have kind, protocol, and path information set. This function also returns the
+// cleaned host path that needs to be bind mounted into the sidecar container.
+// This function must only be called on URLs that have been classified as bind
+// URLs by isBindURL, otherwise this function may panic.
+func parseBindURL(raw, platform string) (*url.URL, string, error) {
+	// Strip off the prefix and validate the host path.
+	hostPath := raw[len(bindURLPrefix):]
+	if hostPath == "" {
+		return nil, "", errors.New("empty host path")
+	} else if !filepath.IsAbs(hostPath) {
+		return nil, "", errors.New("host path is not absolute")
+	}
+	hostPath = filepath.Clean(hostPath)
+
+	// Create a Docker synchronization URL.
+	return &url.URL{
+		Kind:     url.Kind_Synchronization,
+		Protocol: sidecarURLProtocol,
+		Path:     mountPathForBindInMutagenContainer(platform, hostPath),
+	}, hostPath, nil
+}
+
 // synchronizationSessionCurrent determines whether or not an existing
 // synchronization session is equivalent to the specification for its creation.
+// Sessions created by a different version of Mutagen (as recorded by their
+// version label) are never considered current.
 func synchronizationSessionCurrent(
 	session *synchronization.Session,
 	specification *synchronizationsvc.CreationSpecification,
 ) bool {
-	return session.Alpha.Equal(specification.Alpha) &&
-		session.Beta.Equal(session.Beta) &&
+	return session.Labels[sessionVersionLabelKey] == mutagen.Version &&
+		session.Alpha.Equal(specification.Alpha) &&
+		session.Beta.Equal(specification.Beta) &&
 		session.Configuration.Equal(specification.Configuration) &&
 		session.ConfigurationAlpha.Equal(specification.ConfigurationAlpha) &&
-		session.ConfigurationBeta.Equal(specification.ConfigurationBeta)
+		session.ConfigurationBeta.Equal(specification.ConfigurationBeta) &&
+		sessionLabelsEqual(session.Labels, specification.Labels)
 }
 
 // synchronizationCreateWithSpecification creates a synchronization session
@@ -129,6 +512,76 @@ func synchronizationFlushWithSelection(
 	return nil
 }
 
+// synchronizationFlushProgressPollingInterval is the interval at which session
+// states are polled to report progress while flushing synchronization sessions.
+const synchronizationFlushProgressPollingInterval = time.Second
+
+// synchronizationFlushWithProgress flushes synchronization sessions using the
+// provided synchronization session service, session selection, and prompter,
+// but it also polls the states of the selected sessions while the flush is in
+// progress and reports staging progress via the specified callback. Progress
+// reporting is best-effort, so polling failures are ignored.
+func synchronizationFlushWithProgress(
+	ctx context.Context,
+	synchronizationService synchronizationSessionService,
+	prompter string,
+	selection *selection.Selection,
+	report func(progress string),
+) error {
+	// Start the flush operation in the background.
+	flushErrors := make(chan error, 1)
+	go func() {
+		flushErrors <- synchronizationService.Flush(ctx, prompter, selection)
+	}()
+
+	// Create a ticker to regulate polling.
+	ticker := time.NewTicker(synchronizationFlushProgressPollingInterval)
+	defer ticker.Stop()
+
+	// Poll for progress until the flush operation completes.
+	for {
+		select {
+		case err := <-flushErrors:
+			return err
+		case <-ticker.C:
+			states, err := synchronizationService.List(ctx, selection)
+			if err != nil {
+				continue
+			}
+			if progress := describeSynchronizationProgress(states); progress != "" {
+				report(progress)
+			}
+		}
+	}
+}
+
+// describeSynchronizationProgress describes the aggregate staging progress of
+// the specified synchronization sessions. Mutagen only reports staging progress
+// in terms of paths, so byte counts aren't available. It returns an empty
+// string if no sessions are staging files.
+func describeSynchronizationProgress(states []*synchronization.State) string {
+	// Aggregate staging progress.
+	var staging bool
+	var received, total uint64
+	for _, state := range states {
+		if status := state.StagingStatus; status != nil {
+			staging = true
+			received += status.Received
+			total += status.Total
+		}
+	}
+	if !staging {
+		return ""
+	}
+
+	// Format the description.
+	var percentage uint64
+	if total > 0 {
+		percentage = received * 100 / total
+	}
+	return fmt.Sprintf("staged %d/%d files (%d%%)", received, total, percentage)
+}
+
 // synchronizationPauseWithSelection pauses synchronization sessions using the
 // provided synchronization service client, session selection, and prompter.
 func synchronizationPauseWithSelection(
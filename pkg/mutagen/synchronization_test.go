@@ -0,0 +1,82 @@
+package mutagen
+
+import (
+	"regexp"
+	"testing"
+)
+
+// TestParseBindURL tests parseBindURL.
+func TestParseBindURL(t *testing.T) {
+	testCases := []struct {
+		raw              string
+		platform         string
+		expectedHostPath string
+		expectedSuccess  bool
+	}{
+		{"bind://", "linux", "", false},
+		{"bind://relative/path", "linux", "", false},
+		{"bind://./path", "linux", "", false},
+		{"bind:///project/code", "linux", "/project/code", true},
+		{"bind:///project/code/", "linux", "/project/code", true},
+		{"bind:///project/other/../code", "linux", "/project/code", true},
+		{"BIND:///project/code", "linux", "/project/code", true},
+		{"bind:///nonexistent/path/on/client", "linux", "/nonexistent/path/on/client", true},
+		{"bind:///project/code", "windows", "/project/code", true},
+	}
+	for _, testCase := range testCases {
+		result, hostPath, err := parseBindURL(testCase.raw, testCase.platform)
+		if !testCase.expectedSuccess {
+			if err == nil {
+				t.Errorf("%s: parsing succeeded unexpectedly", testCase.raw)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unable to parse: %v", testCase.raw, err)
+			continue
+		}
+		if hostPath != testCase.expectedHostPath {
+			t.Errorf("%s: host path mismatch: %s != %s", testCase.raw, hostPath, testCase.expectedHostPath)
+		}
+		if result.Protocol != sidecarURLProtocol {
+			t.Errorf("%s: unexpected protocol: %v", testCase.raw, result.Protocol)
+		}
+		expectedPath := mountPathForBindInMutagenContainer(testCase.platform, testCase.expectedHostPath)
+		if result.Path != expectedPath {
+			t.Errorf("%s: path mismatch: %s != %s", testCase.raw, result.Path, expectedPath)
+		}
+	}
+}
+
+// TestMountPathForBindInMutagenContainer tests that bind mount paths inside the
+// sidecar container are stable, platform-specific, and distinct for distinct
+// host paths.
+func TestMountPathForBindInMutagenContainer(t *testing.T) {
+	// Verify the mount path format for each platform.
+	formats := map[string]*regexp.Regexp{
+		"linux":   regexp.MustCompile(`^/binds/[0-9a-f]{16}$`),
+		"windows": regexp.MustCompile(`^c:\\binds\\[0-9a-f]{16}$`),
+	}
+	for platform, format := range formats {
+		path := mountPathForBindInMutagenContainer(platform, "/project/code")
+		if !format.MatchString(path) {
+			t.Errorf("%s: invalid mount path: %s", platform, path)
+		}
+	}
+
+	// Verify that mount paths are stable and distinct.
+	first := mountPathForBindInMutagenContainer("linux", "/project/code")
+	if again := mountPathForBindInMutagenContainer("linux", "/project/code"); again != first {
+		t.Errorf("mount path unstable: %s != %s", again, first)
+	}
+	if other := mountPathForBindInMutagenContainer("linux", "/project/assets"); other == first {
+		t.Errorf("mount path collision: %s", other)
+	}
+
+	// Verify that an empty host path is rejected.
+	defer func() {
+		if recover() == nil {
+			t.Error("empty host path accepted")
+		}
+	}()
+	mountPathForBindInMutagenContainer("linux", "")
+}
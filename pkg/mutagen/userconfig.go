@@ -0,0 +1,98 @@
+package mutagen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// userConfigurationFileName is the name of the user-level Mutagen Compose
+	// configuration file inside the user's home directory.
+	userConfigurationFileName = ".mutagen-compose.yaml"
+	// userConfigurationEnvironmentVariable is the environment variable that
+	// can be used to override the path of the user-level Mutagen Compose
+	// configuration file.
+	userConfigurationEnvironmentVariable = "MUTAGEN_COMPOSE_CONFIG"
+)
+
+// userConfiguration encodes user-level Mutagen Compose configuration. These
+// settings are machine- or user-specific defaults that don't belong in a
+// project's Compose configuration. Where a setting can also be specified in a
+// project's Mutagen configuration, the project setting takes precedence.
+type userConfiguration struct {
+	// Quiet indicates whether or not Mutagen session status output should be
+	// suppressed by default.
+	Quiet bool `yaml:"quiet"`
+	// Daemon encodes Mutagen daemon defaults.
+	Daemon struct {
+		// Isolated indicates whether or not project sessions should be managed
+		// by a project-scoped Mutagen daemon by default.
+		Isolated bool `yaml:"isolated"`
+	} `yaml:"daemon"`
+	// Sidecar encodes sidecar service defaults.
+	Sidecar struct {
+		// ImageMirror is an alternative image repository (e.g. on a registry
+		// mirror) from which to pull the Mutagen sidecar image. The image tag
+		// is still determined by Mutagen Compose.
+		ImageMirror string `yaml:"imageMirror"`
+	} `yaml:"sidecar"`
+}
+
+// userConfigurationPath computes the path to the user-level Mutagen Compose
+// configuration file.
+func userConfigurationPath() (string, error) {
+	if path := os.Getenv(userConfigurationEnvironmentVariable); path != "" {
+		return path, nil
+	}
+	homeDirectory, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to compute path to home directory: %w", err)
+	}
+	return filepath.Join(homeDirectory, userConfigurationFileName), nil
+}
+
+// loadUserConfiguration loads the user-level Mutagen Compose configuration. If
+// the configuration file doesn't exist, then an empty configuration is
+// returned.
+func loadUserConfiguration() (*userConfiguration, error) {
+	// Compute the configuration file path.
+	path, err := userConfigurationPath()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the configuration file.
+	result := &userConfiguration{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return result, nil
+		}
+		return nil, fmt.Errorf("unable to read configuration file: %w", err)
+	}
+
+	// Decode the configuration.
+	if err := yaml.UnmarshalStrict(data, result); err != nil {
+		return nil, fmt.Errorf("unable to decode configuration file (%s): %w", path, err)
+	}
+
+	// Success.
+	return result, nil
+}
+
+// LoadUserConfiguration loads the user-level Mutagen Compose configuration and
+// registers it with the liaison. It must be called before ApplyFlags in order
+// for command line flags to take precedence over user-level defaults.
+func (l *Liaison) LoadUserConfiguration() error {
+	configuration, err := loadUserConfiguration()
+	if err != nil {
+		return fmt.Errorf("unable to load user configuration: %w", err)
+	}
+	l.userConfiguration = configuration
+	l.quiet = configuration.Quiet
+	return nil
+}
@@ -0,0 +1,62 @@
+package mutagen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/client"
+
+	"github.com/compose-spec/compose-go/types"
+)
+
+// ensureExternalVolumesExist verifies that any external volumes mounted into the
+// Mutagen Compose sidecar services exist on the Docker daemon. Compose doesn't
+// verify the existence of external volumes until a container that mounts them
+// is created, at which point the failure is attributed to the sidecar service,
+// so we perform this check up-front to provide a clearer error. This method
+// must only be called after processProject.
+func (l *Liaison) ensureExternalVolumesExist(ctx context.Context, project *types.Project) error {
+	for _, service := range l.sidecarServices() {
+		if err := l.ensureServiceExternalVolumesExist(ctx, project, service); err != nil {
+			return err
+		}
+	}
+
+	// Success.
+	return nil
+}
+
+// ensureServiceExternalVolumesExist verifies that any external volumes mounted
+// into the specified Mutagen Compose sidecar service exist on the Docker daemon.
+func (l *Liaison) ensureServiceExternalVolumesExist(ctx context.Context, project *types.Project, service types.ServiceConfig) error {
+	for _, mount := range service.Volumes {
+		// Ignore non-volume mounts.
+		if mount.Type != types.VolumeTypeVolume {
+			continue
+		}
+
+		// Ignore volumes that aren't external. Those that aren't defined in
+		// the project have already been rejected by processProject.
+		volume, ok := project.Volumes[mount.Source]
+		if !ok || !volume.External.External {
+			continue
+		}
+
+		// Determine the volume name on the Docker daemon.
+		name := volume.Name
+		if name == "" {
+			name = mount.Source
+		}
+
+		// Verify that the volume exists.
+		if _, err := l.dockerCLI.Client().VolumeInspect(ctx, name); err != nil {
+			if client.IsErrNotFound(err) {
+				return fmt.Errorf("external volume (%s) referenced by Mutagen session does not exist (create it with \"docker volume create %s\")", name, name)
+			}
+			return fmt.Errorf("unable to inspect external volume (%s): %w", name, err)
+		}
+	}
+
+	// Success.
+	return nil
+}
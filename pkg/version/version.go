@@ -22,25 +22,49 @@ const (
 	// dockerModuleName is the module name that we'll use to identify the Docker
 	// dependency version.
 	dockerModuleName = "github.com/docker/cli"
+	// Unknown is the version used for dependencies whose versions can't be
+	// identified from build information.
+	Unknown = "unknown"
 )
 
 // Versions encodes the dependency versions for Mutagen Compose. It is designed
-// to be serialized as JSON.
+// to be serialized as JSON or YAML.
 type Versions struct {
 	// Mutagen is the Mutagen version.
-	Mutagen string `json:"mutagen"`
+	Mutagen string `json:"mutagen" yaml:"mutagen"`
 	// Compose is the Compose version.
-	Compose string `json:"compose"`
+	Compose string `json:"compose" yaml:"compose"`
 	// Docker is the Docker version.
-	Docker string `json:"docker"`
+	Docker string `json:"docker" yaml:"docker"`
+}
+
+// BuildInformation encodes build provenance information for Mutagen Compose. It
+// is designed to be serialized as JSON or YAML.
+type BuildInformation struct {
+	// GoVersion is the version of the Go toolchain used for the build.
+	GoVersion string `json:"goVersion" yaml:"goVersion"`
+	// Revision is the VCS revision of the main module, if known.
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// Time is the VCS commit time of the main module, if known.
+	Time string `json:"time,omitempty" yaml:"time,omitempty"`
+	// Modified indicates whether or not the VCS working tree had local
+	// modifications at build time.
+	Modified bool `json:"modified,omitempty" yaml:"modified,omitempty"`
 }
 
 // LoadVersions loads version information.
 func LoadVersions() (*Versions, error) {
+	versions, _, err := LoadVersionsAndBuildInformation()
+	return versions, err
+}
+
+// LoadVersionsAndBuildInformation loads version information along with build
+// provenance information.
+func LoadVersionsAndBuildInformation() (*Versions, *BuildInformation, error) {
 	// Load build information.
 	build, ok := debug.ReadBuildInfo()
 	if !ok {
-		return nil, errors.New("unable to read build information")
+		return nil, nil, errors.New("unable to read build information")
 	}
 
 	// Create the result.
@@ -66,12 +90,28 @@ func LoadVersions() (*Versions, error) {
 
 	// Fill in unknown information.
 	if !composeFound {
-		result.Compose = "unknown"
+		result.Compose = Unknown
 	}
 	if !dockerFound {
-		result.Docker = "unknown"
+		result.Docker = Unknown
+	}
+
+	// Extract build provenance information. VCS information is only stamped
+	// for builds of the main module performed from within a repository.
+	provenance := &BuildInformation{
+		GoVersion: build.GoVersion,
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			provenance.Revision = setting.Value
+		case "vcs.time":
+			provenance.Time = setting.Value
+		case "vcs.modified":
+			provenance.Modified = setting.Value == "true"
+		}
 	}
 
 	// Done.
-	return result, nil
+	return result, provenance, nil
 }